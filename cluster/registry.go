@@ -0,0 +1,166 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+//Package cluster routes sessions to the logic node owning them. The
+//assignments live in a Store shared by the gates, so they survive gate
+//restarts, and sessions of a lost node are migrated to the picked ones.
+//Sessions should be keyed by an id stable across reconnects, e.g. the account.
+package cluster
+
+import (
+	"sync"
+
+	"github.com/lightning-go/lightning/logger"
+)
+
+const maxAssignRetry = 3
+
+type Registry struct {
+	store           Store
+	cache           sync.Map
+	pickCallback    func(sessionId string) (string, bool)
+	aliveCallback   func(node string) bool
+	migrateCallback func(sessionId, from, to string)
+}
+
+func NewRegistry(store Store) *Registry {
+	if store == nil {
+		return nil
+	}
+	return &Registry{
+		store: store,
+	}
+}
+
+//SetPickCallback selects the node of sessions without a live owner
+func (r *Registry) SetPickCallback(cb func(sessionId string) (string, bool)) {
+	r.pickCallback = cb
+}
+
+//SetAliveCallback reports whether a node is up, owners are live without it
+func (r *Registry) SetAliveCallback(cb func(node string) bool) {
+	r.aliveCallback = cb
+}
+
+func (r *Registry) SetMigrateCallback(cb func(sessionId, from, to string)) {
+	r.migrateCallback = cb
+}
+
+func (r *Registry) isAlive(node string) bool {
+	if r.aliveCallback == nil {
+		return true
+	}
+	return r.aliveCallback(node)
+}
+
+func (r *Registry) pick(sessionId string) (string, bool) {
+	if r.pickCallback == nil {
+		return "", false
+	}
+	return r.pickCallback(sessionId)
+}
+
+//Lookup returns the owner of the session, "" when it has none
+func (r *Registry) Lookup(sessionId string) (string, error) {
+	v, ok := r.cache.Load(sessionId)
+	if ok {
+		return v.(string), nil
+	}
+	owner, err := r.store.Lookup(sessionId)
+	if err != nil || owner == "" {
+		return "", err
+	}
+	r.cache.Store(sessionId, owner)
+	return owner, nil
+}
+
+//Route returns the live owner of the session, sessions without one are
+//assigned to the picked node
+func (r *Registry) Route(sessionId string) (string, error) {
+	owner, err := r.Lookup(sessionId)
+	if err != nil {
+		return "", err
+	}
+	if owner != "" && r.isAlive(owner) {
+		return owner, nil
+	}
+	r.cache.Delete(sessionId)
+
+	for i := 0; i < maxAssignRetry; i++ {
+		node, ok := r.pick(sessionId)
+		if !ok {
+			return "", ErrNoNode
+		}
+		cur, err := r.store.Assign(sessionId, owner, node)
+		if err != nil {
+			return "", err
+		}
+		if cur == node {
+			r.cache.Store(sessionId, node)
+			if owner != "" {
+				r.onMigrate(sessionId, owner, node)
+			}
+			return node, nil
+		}
+		//another gate assigned it first
+		if cur != "" && r.isAlive(cur) {
+			r.cache.Store(sessionId, cur)
+			return cur, nil
+		}
+		owner = cur
+	}
+	return "", ErrConflict
+}
+
+//Touch renews the assignment of an active session
+func (r *Registry) Touch(sessionId string) error {
+	return r.store.Touch(sessionId)
+}
+
+func (r *Registry) Release(sessionId string) error {
+	r.cache.Delete(sessionId)
+	return r.store.Release(sessionId)
+}
+
+//Invalidate drops the cached owner, the next lookup reads the store
+func (r *Registry) Invalidate(sessionId string) {
+	r.cache.Delete(sessionId)
+}
+
+//Migrate moves the sessions of a lost node to the picked nodes, sessions
+//moved by another gate are left alone, it returns the number moved
+func (r *Registry) Migrate(node string) (int, error) {
+	sessions, err := r.store.Sessions(node)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, sessionId := range sessions {
+		r.cache.Delete(sessionId)
+		to, ok := r.pick(sessionId)
+		if !ok {
+			return n, ErrNoNode
+		}
+		cur, err := r.store.Assign(sessionId, node, to)
+		if err != nil {
+			logger.Errorf("migrate session %v from %v failed: %v", sessionId, node, err)
+			continue
+		}
+		if cur != to {
+			continue
+		}
+		r.cache.Store(sessionId, to)
+		r.onMigrate(sessionId, node, to)
+		n++
+	}
+	return n, nil
+}
+
+func (r *Registry) onMigrate(sessionId, from, to string) {
+	logger.Debugf("session %v migrated from %v to %v", sessionId, from, to)
+	if r.migrateCallback != nil {
+		r.migrateCallback(sessionId, from, to)
+	}
+}
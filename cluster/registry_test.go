@@ -0,0 +1,73 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package cluster
+
+import (
+	"testing"
+	"time"
+)
+
+func testRegistry(t *testing.T, s Store) {
+	alive := map[string]bool{"a": true, "b": true}
+	next := "a"
+	r := NewRegistry(s)
+	r.SetPickCallback(func(string) (string, bool) { return next, true })
+	r.SetAliveCallback(func(n string) bool { return alive[n] })
+	for _, id := range []string{"s1", "s2", "s3"} {
+		n, err := r.Route(id)
+		if err != nil || n != "a" {
+			t.Fatal(n, err)
+		}
+	}
+	//a second gate sees the assignment
+	r2 := NewRegistry(s)
+	r2.SetPickCallback(func(string) (string, bool) { return "b", true })
+	r2.SetAliveCallback(func(n string) bool { return alive[n] })
+	if n, _ := r2.Route("s1"); n != "a" {
+		t.Fatal("r2", n)
+	}
+	ss, _ := s.Sessions("a")
+	if len(ss) != 3 {
+		t.Fatal("sessions", ss)
+	}
+	alive["a"] = false
+	next = "b"
+	moved := 0
+	r.SetMigrateCallback(func(id, from, to string) { moved++ })
+	n, err := r.Migrate("a")
+	if err != nil || n != 3 || moved != 3 {
+		t.Fatal("migrate", n, err, moved)
+	}
+	n2, _ := r2.Migrate("a")
+	if n2 != 0 {
+		t.Fatal("double migrate")
+	}
+	r2.Invalidate("s1")
+	if o, _ := r2.Route("s1"); o != "b" {
+		t.Fatal("after", o)
+	}
+	r.Release("s2")
+	if o, _ := s.Lookup("s2"); o != "" {
+		t.Fatal("release")
+	}
+	ss, _ = s.Sessions("b")
+	if len(ss) != 2 {
+		t.Fatal("index", ss)
+	}
+	if err := s.Touch("s1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegistryMemStore(t *testing.T) {
+	m := NewMemStore()
+	m.SetTTL(1)
+	testRegistry(t, m)
+	time.Sleep(1100 * time.Millisecond)
+	if o, _ := m.Lookup("s1"); o != "" {
+		t.Fatal("ttl")
+	}
+}
@@ -0,0 +1,130 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package cluster
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoNode   = errors.New("no node available")
+	ErrConflict = errors.New("session assignment conflict")
+)
+
+//Store persists the node owning each session, shared by every node of the cluster
+type Store interface {
+	//Assign sets the owner of the session to node if it is still prev, "" for
+	//none, and returns the owner after the call
+	Assign(sessionId, prev, node string) (string, error)
+	//Lookup returns "" for sessions without owner
+	Lookup(sessionId string) (string, error)
+	//Touch renews the ttl of the assignment
+	Touch(sessionId string) error
+	Release(sessionId string) error
+	//Sessions returns the sessions owned by node
+	Sessions(node string) ([]string, error)
+}
+
+type memEntry struct {
+	node     string
+	deadline time.Time
+}
+
+//MemStore keeps the assignments of a single process, for tests and standalone servers
+type MemStore struct {
+	mux      sync.Mutex
+	ttl      time.Duration
+	sessions map[string]*memEntry
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{
+		sessions: make(map[string]*memEntry),
+	}
+}
+
+//assignments expire after ttl seconds without Touch, zero keeps them
+func (ms *MemStore) SetTTL(ttl int64) {
+	ms.mux.Lock()
+	ms.ttl = time.Duration(ttl) * time.Second
+	ms.mux.Unlock()
+}
+
+func (ms *MemStore) get(sessionId string) *memEntry {
+	e, ok := ms.sessions[sessionId]
+	if !ok {
+		return nil
+	}
+	if !e.deadline.IsZero() && time.Now().After(e.deadline) {
+		delete(ms.sessions, sessionId)
+		return nil
+	}
+	return e
+}
+
+func (ms *MemStore) deadline() time.Time {
+	if ms.ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ms.ttl)
+}
+
+func (ms *MemStore) Assign(sessionId, prev, node string) (string, error) {
+	ms.mux.Lock()
+	defer ms.mux.Unlock()
+	owner := ""
+	e := ms.get(sessionId)
+	if e != nil {
+		owner = e.node
+	}
+	if owner != prev {
+		return owner, nil
+	}
+	ms.sessions[sessionId] = &memEntry{node: node, deadline: ms.deadline()}
+	return node, nil
+}
+
+func (ms *MemStore) Lookup(sessionId string) (string, error) {
+	ms.mux.Lock()
+	defer ms.mux.Unlock()
+	e := ms.get(sessionId)
+	if e == nil {
+		return "", nil
+	}
+	return e.node, nil
+}
+
+func (ms *MemStore) Touch(sessionId string) error {
+	ms.mux.Lock()
+	defer ms.mux.Unlock()
+	e := ms.get(sessionId)
+	if e != nil {
+		e.deadline = ms.deadline()
+	}
+	return nil
+}
+
+func (ms *MemStore) Release(sessionId string) error {
+	ms.mux.Lock()
+	delete(ms.sessions, sessionId)
+	ms.mux.Unlock()
+	return nil
+}
+
+func (ms *MemStore) Sessions(node string) ([]string, error) {
+	ms.mux.Lock()
+	defer ms.mux.Unlock()
+	list := make([]string, 0)
+	for sessionId := range ms.sessions {
+		e := ms.get(sessionId)
+		if e != nil && e.node == node {
+			list = append(list, sessionId)
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,152 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package cluster
+
+import (
+	"context"
+	"strings"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/etcd"
+)
+
+//EtcdStore keeps an assignment in <prefix>/session/<sessionId> and indexes it
+//by node in <prefix>/node/<node>/<sessionId>, both attached to the same lease
+type EtcdStore struct {
+	client *clientv3.Client
+	prefix string
+	ttl    int64
+}
+
+func NewEtcdStore(e *etcd.Etcd, prefix string) *EtcdStore {
+	if e == nil || e.GetClient() == nil {
+		return nil
+	}
+	return &EtcdStore{
+		client: e.GetClient(),
+		prefix: strings.TrimSuffix(prefix, "/"),
+	}
+}
+
+//assignments expire after ttl seconds without Touch, zero keeps them
+func (es *EtcdStore) SetTTL(ttl int64) {
+	es.ttl = ttl
+}
+
+func (es *EtcdStore) sessionKey(sessionId string) string {
+	return es.prefix + "/session/" + sessionId
+}
+
+func (es *EtcdStore) nodeKey(node, sessionId string) string {
+	return es.prefix + "/node/" + node + "/" + sessionId
+}
+
+func (es *EtcdStore) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.TODO(), conf.GetGlobalVal().HttpTimeout)
+}
+
+func (es *EtcdStore) Assign(sessionId, prev, node string) (string, error) {
+	ctx, cancel := es.context()
+	defer cancel()
+
+	var opts []clientv3.OpOption
+	var leaseId clientv3.LeaseID
+	if es.ttl > 0 {
+		lease, err := es.client.Grant(ctx, es.ttl)
+		if err != nil {
+			return "", err
+		}
+		leaseId = lease.ID
+		opts = append(opts, clientv3.WithLease(leaseId))
+	}
+
+	key := es.sessionKey(sessionId)
+	cmp := clientv3.Compare(clientv3.Value(key), "=", prev)
+	if prev == "" {
+		cmp = clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+	}
+	ops := []clientv3.Op{
+		clientv3.OpPut(key, node, opts...),
+		clientv3.OpPut(es.nodeKey(node, sessionId), "", opts...),
+	}
+	if prev != "" && prev != node {
+		ops = append(ops, clientv3.OpDelete(es.nodeKey(prev, sessionId)))
+	}
+
+	resp, err := es.client.Txn(ctx).If(cmp).Then(ops...).Else(clientv3.OpGet(key)).Commit()
+	if err != nil {
+		return "", err
+	}
+	if resp.Succeeded {
+		return node, nil
+	}
+	if leaseId != 0 {
+		es.client.Revoke(ctx, leaseId)
+	}
+	kvs := resp.Responses[0].GetResponseRange().Kvs
+	if len(kvs) == 0 {
+		return "", nil
+	}
+	return string(kvs[0].Value), nil
+}
+
+func (es *EtcdStore) Lookup(sessionId string) (string, error) {
+	ctx, cancel := es.context()
+	defer cancel()
+	resp, err := es.client.Get(ctx, es.sessionKey(sessionId))
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", nil
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+func (es *EtcdStore) Touch(sessionId string) error {
+	ctx, cancel := es.context()
+	defer cancel()
+	resp, err := es.client.Get(ctx, es.sessionKey(sessionId))
+	if err != nil {
+		return err
+	}
+	if len(resp.Kvs) == 0 || resp.Kvs[0].Lease == 0 {
+		return nil
+	}
+	_, err = es.client.KeepAliveOnce(ctx, clientv3.LeaseID(resp.Kvs[0].Lease))
+	return err
+}
+
+func (es *EtcdStore) Release(sessionId string) error {
+	owner, err := es.Lookup(sessionId)
+	if err != nil || owner == "" {
+		return err
+	}
+	ctx, cancel := es.context()
+	defer cancel()
+	key := es.sessionKey(sessionId)
+	_, err = es.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value(key), "=", owner)).
+		Then(clientv3.OpDelete(key), clientv3.OpDelete(es.nodeKey(owner, sessionId))).
+		Commit()
+	return err
+}
+
+func (es *EtcdStore) Sessions(node string) ([]string, error) {
+	ctx, cancel := es.context()
+	defer cancel()
+	prefix := es.nodeKey(node, "")
+	resp, err := es.client.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		list = append(list, strings.TrimPrefix(string(kv.Key), prefix))
+	}
+	return list, nil
+}
@@ -0,0 +1,127 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package cluster
+
+import (
+	"github.com/gomodule/redigo/redis"
+	"github.com/lightning-go/lightning/db"
+	"github.com/mna/redisc"
+)
+
+//KEYS: session, node set, prev node set
+//ARGV: prev, node, ttl, sessionId
+var assignScript = redis.NewScript(3, `
+local cur = redis.call('GET', KEYS[1])
+if not cur then cur = '' end
+if cur ~= ARGV[1] then
+	if ARGV[1] ~= '' then redis.call('SREM', KEYS[3], ARGV[4]) end
+	return cur
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'EX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+redis.call('SADD', KEYS[2], ARGV[4])
+if ARGV[1] ~= '' and ARGV[1] ~= ARGV[2] then redis.call('SREM', KEYS[3], ARGV[4]) end
+return ARGV[2]
+`)
+
+//KEYS: session, node set
+//ARGV: node, sessionId
+var releaseScript = redis.NewScript(2, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then redis.call('DEL', KEYS[1]) end
+redis.call('SREM', KEYS[2], ARGV[2])
+return 1
+`)
+
+//RedisStore keeps an assignment in {prefix}:session:<sessionId> and indexes
+//it by node in the set {prefix}:node:<node>, the hash tag keeps the keys of
+//a script in one slot of a redis cluster
+type RedisStore struct {
+	client *db.RedisClient
+	prefix string
+	ttl    int64
+}
+
+func NewRedisStore(client *db.RedisClient, prefix string) *RedisStore {
+	if client == nil {
+		return nil
+	}
+	return &RedisStore{
+		client: client,
+		prefix: "{" + prefix + "}",
+	}
+}
+
+//assignments expire after ttl seconds without Touch, zero keeps them
+func (rs *RedisStore) SetTTL(ttl int64) {
+	rs.ttl = ttl
+}
+
+func (rs *RedisStore) sessionKey(sessionId string) string {
+	return rs.prefix + ":session:" + sessionId
+}
+
+func (rs *RedisStore) nodeKey(node string) string {
+	return rs.prefix + ":node:" + node
+}
+
+//cluster connections are bound to the slot of the keys, scripts carry no key first
+func (rs *RedisStore) conn(keys ...string) redis.Conn {
+	conn := rs.client.GetConn()
+	redisc.BindConn(conn, keys...)
+	return conn
+}
+
+func (rs *RedisStore) Assign(sessionId, prev, node string) (string, error) {
+	keys := []string{rs.sessionKey(sessionId), rs.nodeKey(node), rs.nodeKey(prev)}
+	conn := rs.conn(keys...)
+	defer conn.Close()
+	return redis.String(assignScript.Do(conn, keys[0], keys[1], keys[2], prev, node, rs.ttl, sessionId))
+}
+
+func (rs *RedisStore) Lookup(sessionId string) (string, error) {
+	key := rs.sessionKey(sessionId)
+	conn := rs.conn(key)
+	defer conn.Close()
+	owner, err := redis.String(conn.Do("GET", key))
+	if err == redis.ErrNil {
+		return "", nil
+	}
+	return owner, err
+}
+
+func (rs *RedisStore) Touch(sessionId string) error {
+	if rs.ttl <= 0 {
+		return nil
+	}
+	key := rs.sessionKey(sessionId)
+	conn := rs.conn(key)
+	defer conn.Close()
+	_, err := conn.Do("EXPIRE", key, rs.ttl)
+	return err
+}
+
+func (rs *RedisStore) Release(sessionId string) error {
+	owner, err := rs.Lookup(sessionId)
+	if err != nil || owner == "" {
+		return err
+	}
+	keys := []string{rs.sessionKey(sessionId), rs.nodeKey(owner)}
+	conn := rs.conn(keys...)
+	defer conn.Close()
+	_, err = releaseScript.Do(conn, keys[0], keys[1], owner, sessionId)
+	return err
+}
+
+//the set may hold expired sessions, they are dropped when Assign meets them
+func (rs *RedisStore) Sessions(node string) ([]string, error) {
+	key := rs.nodeKey(node)
+	conn := rs.conn(key)
+	defer conn.Close()
+	return redis.Strings(conn.Do("SMEMBERS", key))
+}
@@ -0,0 +1,230 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"hash/fnv"
+	"math"
+	"strings"
+	"text/template"
+)
+
+type msgId struct {
+	Type  string
+	Const string
+	Id    uint32
+}
+
+var genTemplate = template.Must(template.New("gen").Parse(`// Code generated by lightning-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .HasReply}}
+	"context"
+
+{{end}}
+	"github.com/lightning-go/lightning/defs"
+{{- if .MsgIds}}
+	"github.com/lightning-go/lightning/module"
+{{- end}}
+	"github.com/lightning-go/lightning/rpc"
+	"github.com/lightning-go/lightning/utils"
+{{- range .Imports}}
+	{{.}}
+{{- end}}
+)
+{{if .MsgIds}}
+//message ids for ProtoCodec
+const (
+{{- range .MsgIds}}
+	{{.Const}} uint32 = {{.Id}}
+{{- end}}
+)
+{{end}}
+{{- range .Services}}{{$svc := .}}
+{{- if .Declare}}
+type {{.Name}} interface {
+{{- range .Methods}}
+	{{.Name}}(session defs.ISession, req *{{.Req}}, resp *{{.Reply}}) int
+{{- end}}
+}
+{{end}}
+//method ids of {{.Name}}
+const (
+{{- range .Methods}}
+	{{$svc.Name}}{{.Name}} = "{{.Name}}"
+{{- end}}
+)
+
+//Register{{.Name}} adds the methods of svc to sf, dispatched without reflection
+func Register{{.Name}}(sf *utils.ServiceFactory, svc {{.Name}}) {
+	sf.RegisterMethods(
+{{- range .Methods}}
+		&utils.MethodDesc{
+			Name: {{$svc.Name}}{{.Name}},
+			NewReq: func() interface{} {
+				return new({{.Req}})
+			},
+{{- if .Reply}}
+			NewReply: func() interface{} {
+				return new({{.Reply}})
+			},
+			Invoke: func(session defs.ISession, req, reply interface{}) int {
+				return svc.{{.Name}}(session, req.(*{{.Req}}), reply.(*{{.Reply}}))
+			},
+{{- else}}
+			Invoke: func(session defs.ISession, req, reply interface{}) int {
+				return svc.{{.Name}}(session, req.(*{{.Req}}))
+			},
+{{- end}}
+		},
+{{- end}}
+	)
+}
+{{- if $.MsgIds}}
+
+//Register{{.Name}}Msgs registers the message ids used by {{.Name}}
+func Register{{.Name}}Msgs() error {
+{{- range $.MsgTypes .}}
+	if err := module.RegisterMsg({{.Const}}, new({{.Type}})); err != nil {
+		return err
+	}
+{{- end}}
+	return nil
+}
+{{- end}}
+
+//{{.Name}}Client calls {{.Name}} through an rpc.Client
+type {{.Name}}Client struct {
+	client *rpc.Client
+}
+
+func New{{.Name}}Client(client *rpc.Client) *{{.Name}}Client {
+	return &{{.Name}}Client{client: client}
+}
+{{range .Methods}}
+{{- if .Reply}}
+func (c *{{$svc.Name}}Client) {{.Name}}(ctx context.Context, req *{{.Req}}) (*{{.Reply}}, error) {
+	resp := new({{.Reply}})
+	err := c.client.CallCtx(ctx, {{$svc.Name}}{{.Name}}, req, resp)
+	return resp, err
+}
+{{else}}
+func (c *{{$svc.Name}}Client) {{.Name}}(req *{{.Req}}) error {
+	return c.client.Notify({{$svc.Name}}{{.Name}}, req)
+}
+{{end}}
+{{- end}}
+{{- end}}
+`))
+
+//ids are the base plus a hash of the full message name within msgIdSpan,
+//so adding or reordering messages keeps the ids of the others
+const msgIdSpan = 1 << 24
+
+type genData struct {
+	*File
+	MsgIds []*msgId
+	byType map[string]*msgId
+}
+
+//context is only imported for the stubs of methods with reply
+func (d *genData) HasReply() bool {
+	for _, s := range d.Services {
+		for _, m := range s.Methods {
+			if m.Reply != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//MsgTypes lists the message ids of the request and reply types of s
+func (d *genData) MsgTypes(s *Service) []*msgId {
+	var ids []*msgId
+	seen := map[string]bool{}
+	for _, m := range s.Methods {
+		for _, typ := range []string{m.Req, m.Reply} {
+			if typ == "" || seen[typ] {
+				continue
+			}
+			seen[typ] = true
+			ids = append(ids, d.byType[typ])
+		}
+	}
+	return ids
+}
+
+func msgIdOf(base uint32, name string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return base + h.Sum32()%msgIdSpan
+}
+
+func (d *genData) assignMsgIds() error {
+	//the ids from math.MaxUint32-1 up are the heartbeat of ProtoCodec
+	if uint64(d.MsgIdBase)+msgIdSpan >= math.MaxUint32-1 {
+		return fmt.Errorf("-msgid %v leaves no room for %v ids", d.MsgIdBase, msgIdSpan)
+	}
+	byId := map[uint32]*msgId{}
+	names := map[*msgId]string{}
+	for _, s := range d.Services {
+		for _, m := range s.Methods {
+			for i, typ := range []string{m.Req, m.Reply} {
+				if typ == "" || d.byType[typ] != nil {
+					continue
+				}
+				name := m.ReqMsg
+				if i == 1 {
+					name = m.ReplyMsg
+				}
+				id := &msgId{
+					Type:  typ,
+					Const: strings.Replace(typ, ".", "", -1) + "MsgId",
+					Id:    msgIdOf(d.MsgIdBase, name),
+				}
+				if other, ok := byId[id.Id]; ok {
+					return fmt.Errorf("msg id %v of %v collides with %v, rename one of them",
+						id.Id, name, names[other])
+				}
+				byId[id.Id] = id
+				names[id] = name
+				d.byType[typ] = id
+				d.MsgIds = append(d.MsgIds, id)
+			}
+		}
+	}
+	return nil
+}
+
+func generate(f *File) ([]byte, error) {
+	d := &genData{
+		File:   f,
+		byType: map[string]*msgId{},
+	}
+	if f.MsgIdBase > 0 {
+		err := d.assignMsgIds()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	err := genTemplate.Execute(&buf, d)
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %v\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
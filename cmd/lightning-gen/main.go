@@ -0,0 +1,105 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+//lightning-gen emits a statically typed ServiceFactory dispatch table, rpc
+//client stubs and message id constants for service definitions.
+//
+//from a go interface, methods shaped like ServiceFactory handlers:
+//
+//	//go:generate lightning-gen -type LoginService
+//	type LoginService interface {
+//		Login(session defs.ISession, req *LoginReq, resp *LoginResp) int
+//		Logout(session defs.ISession, req *LogoutReq) int
+//	}
+//
+//or from the services of a .proto file, the interface is emitted as well:
+//
+//	//go:generate lightning-gen -proto login.proto -msgid 1000
+//
+//-msgid assigns ProtoCodec message ids to the request and reply types, the
+//base plus a hash of the full message name, so the ids stay the same when
+//messages are added or reordered. a collision fails the generation
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma separated service interfaces of the go source file")
+	protoFile = flag.String("proto", "", "proto file whose services are generated")
+	pkgName   = flag.String("package", "", "package of the generated file, $GOPACKAGE by default")
+	output    = flag.String("output", "", "output file, <service>_gen.go by default")
+	msgIdBase = flag.Uint("msgid", 0, "base of the message ids of the message types, 0 disables")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: lightning-gen -type Service[,Service] [file.go]\n")
+	fmt.Fprintf(os.Stderr, "       lightning-gen -proto file.proto [-package name]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	f, err := load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "lightning-gen:", err)
+		os.Exit(1)
+	}
+	if *msgIdBase > math.MaxUint32 {
+		fmt.Fprintln(os.Stderr, "lightning-gen: -msgid out of range")
+		os.Exit(1)
+	}
+	f.MsgIdBase = uint32(*msgIdBase)
+
+	src, err := generate(f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "lightning-gen:", err)
+		os.Exit(1)
+	}
+
+	out := *output
+	if out == "" {
+		out = filepath.Join(f.Dir, strings.ToLower(f.Services[0].Name)+"_gen.go")
+	}
+	err = ioutil.WriteFile(out, src, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "lightning-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func load() (*File, error) {
+	if *protoFile != "" {
+		name := *pkgName
+		if name == "" {
+			name = os.Getenv("GOPACKAGE")
+		}
+		if name == "" {
+			return nil, fmt.Errorf("-package or $GOPACKAGE is required with -proto")
+		}
+		return parseProto(*protoFile, name)
+	}
+
+	if *typeNames == "" {
+		return nil, fmt.Errorf("-type or -proto is required")
+	}
+	source := flag.Arg(0)
+	if source == "" {
+		source = os.Getenv("GOFILE")
+	}
+	if source == "" {
+		return nil, fmt.Errorf("no go source file")
+	}
+	return parseGo(source, strings.Split(*typeNames, ","))
+}
@@ -0,0 +1,191 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type Method struct {
+	Name  string
+	Req   string
+	Reply string
+	//the full names of the messages, their ids are derived from
+	ReqMsg   string
+	ReplyMsg string
+}
+
+type Service struct {
+	Name    string
+	Methods []*Method
+	//the interface is emitted for proto services
+	Declare bool
+}
+
+type File struct {
+	Dir       string
+	Package   string
+	Imports   []string
+	Services  []*Service
+	MsgIdBase uint32
+}
+
+func parseGo(source string, names []string) (*File, error) {
+	fset := token.NewFileSet()
+	astFile, err := parser.ParseFile(fset, source, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{
+		Dir:     filepath.Dir(source),
+		Package: astFile.Name.Name,
+	}
+	imports := map[string]string{}
+	for _, spec := range astFile.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = importPath
+	}
+	used := map[string]bool{}
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		iface := findInterface(astFile, name)
+		if iface == nil {
+			return nil, fmt.Errorf("%v: interface %v not found", source, name)
+		}
+		s := &Service{Name: name}
+		for _, field := range iface.Methods.List {
+			pos := fset.Position(field.Pos())
+			if len(field.Names) == 0 {
+				return nil, fmt.Errorf("%v: %v: embedded interfaces are not supported", pos, name)
+			}
+			m, err := parseMethod(field.Names[0].Name, field.Type.(*ast.FuncType))
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v.%v: %v", pos, name, field.Names[0].Name, err)
+			}
+			m.ReqMsg = goMsgName(f.Package, imports, m.Req)
+			m.ReplyMsg = goMsgName(f.Package, imports, m.Reply)
+			for _, typ := range []string{m.Req, m.Reply} {
+				if i := strings.Index(typ, "."); i > 0 {
+					used[typ[:i]] = true
+				}
+			}
+			s.Methods = append(s.Methods, m)
+		}
+		if len(s.Methods) == 0 {
+			return nil, fmt.Errorf("%v: interface %v has no methods", source, name)
+		}
+		f.Services = append(f.Services, s)
+	}
+
+	for name := range used {
+		importPath, ok := imports[name]
+		if !ok {
+			return nil, fmt.Errorf("%v: package %v is not imported", source, name)
+		}
+		spec := strconv.Quote(importPath)
+		if path.Base(importPath) != name {
+			spec = name + " " + spec
+		}
+		f.Imports = append(f.Imports, spec)
+	}
+	return f, nil
+}
+
+//goMsgName qualifies typ with the import path of its package
+func goMsgName(pkg string, imports map[string]string, typ string) string {
+	if typ == "" {
+		return ""
+	}
+	i := strings.Index(typ, ".")
+	if i < 0 {
+		return pkg + "." + typ
+	}
+	importPath, ok := imports[typ[:i]]
+	if !ok {
+		return typ
+	}
+	return importPath + typ[i:]
+}
+
+func findInterface(astFile *ast.File, name string) *ast.InterfaceType {
+	for _, decl := range astFile.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			if typeSpec.Name.Name != name {
+				continue
+			}
+			iface, _ := typeSpec.Type.(*ast.InterfaceType)
+			return iface
+		}
+	}
+	return nil
+}
+
+//the shape accepted by ServiceFactory: (defs.ISession, *Req[, *Reply]) int
+func parseMethod(name string, fn *ast.FuncType) (*Method, error) {
+	var params []ast.Expr
+	for _, field := range fn.Params.List {
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			params = append(params, field.Type)
+		}
+	}
+	if len(params) != 2 && len(params) != 3 {
+		return nil, fmt.Errorf("want (defs.ISession, *Req[, *Reply]), got %v params", len(params))
+	}
+	sel, ok := params[0].(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "ISession" {
+		return nil, fmt.Errorf("first param must be defs.ISession, got %v", types.ExprString(params[0]))
+	}
+
+	m := &Method{Name: name}
+	var err error
+	m.Req, err = pointerElem(params[1])
+	if err != nil {
+		return nil, err
+	}
+	if len(params) == 3 {
+		m.Reply, err = pointerElem(params[2])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if fn.Results == nil || len(fn.Results.List) != 1 || len(fn.Results.List[0].Names) > 1 ||
+		types.ExprString(fn.Results.List[0].Type) != "int" {
+		return nil, fmt.Errorf("must return a single int status")
+	}
+	return m, nil
+}
+
+func pointerElem(expr ast.Expr) (string, error) {
+	star, ok := expr.(*ast.StarExpr)
+	if !ok {
+		return "", fmt.Errorf("%v is not a pointer", types.ExprString(expr))
+	}
+	return types.ExprString(star.X), nil
+}
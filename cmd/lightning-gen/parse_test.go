@@ -0,0 +1,203 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const loginProto = `
+syntax = "proto3";
+package game.login;
+
+option go_package = "example.com/game/login";
+
+/* service Fake { rpc Nope(A) returns (B); } */
+message LoginReq {
+	string account = 1;
+	message Device { string os = 1; }
+	Device device = 2;
+}
+
+service Login {
+	option (game.auth) = { required: true, scopes: ["a}", "b{"] };
+	// rpc Commented(LoginReq) returns (LoginResp);
+	rpc Login(LoginReq) returns (.game.login.LoginResp) {
+		option (google.api.http) = { post: "/v1/{account}" body: "*" };
+	}
+	rpc Logout (game.login.LogoutReq) returns (google.protobuf.Empty) {}
+	rpc Ping(google.protobuf.StringValue) returns (LoginReq.Device);
+}
+
+service Chat {
+	rpc Say(SayReq) returns (SayResp);
+}
+`
+
+func TestParseProto(t *testing.T) {
+	f, err := parseProtoText("login.proto", loginProto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Services) != 2 || f.Services[0].Name != "Login" || f.Services[1].Name != "Chat" {
+		t.Fatalf("%v services", len(f.Services))
+	}
+	want := []Method{
+		{"Login", "LoginReq", "LoginResp", "game.login.LoginReq", "game.login.LoginResp"},
+		{"Logout", "LogoutReq", "empty.Empty", "game.login.LogoutReq", "google.protobuf.Empty"},
+		{"Ping", "wrappers.StringValue", "LoginReq_Device", "google.protobuf.StringValue", "game.login.LoginReq.Device"},
+	}
+	methods := f.Services[0].Methods
+	if len(methods) != len(want) {
+		t.Fatalf("%v methods", len(methods))
+	}
+	for i, m := range methods {
+		if *m != want[i] {
+			t.Fatalf("method %+v, want %+v", *m, want[i])
+		}
+	}
+	imports := strings.Join(f.Imports, " ")
+	if len(f.Imports) != 2 || !strings.Contains(imports, `"github.com/golang/protobuf/ptypes/empty"`) ||
+		!strings.Contains(imports, `"github.com/golang/protobuf/ptypes/wrappers"`) {
+		t.Fatalf("imports %v", f.Imports)
+	}
+}
+
+func TestParseProtoErrors(t *testing.T) {
+	cases := []struct {
+		text string
+		err  string
+	}{
+		{"message A {}", "no service"},
+		{"service S {}", "has no rpc"},
+		{"service S {\n rpc A(Req) returns (stream Resp);\n}", "streaming"},
+		{"service S {\n rpc A(Req) returns (Resp);\n message M {}\n}", `:3: unexpected "message"`},
+		{"service S {\n rpc A(Req) returns (Resp) {\n", "unexpected end of file"},
+		{"service S {\n rpc A(Req) returns (Resp) option;\n}", "want ; or {"},
+		{"service S {\n rpc A(Req) (Resp);\n}", `want "returns"`},
+		{"service S { rpc A(other.Req) returns (Resp); }", "not a message of package"},
+		{"service S { rpc A(google.protobuf.Api) returns (Resp); }", "not a supported well known type"},
+		{"/* service S {", "unterminated comment"},
+		{`option x = "a;`, "unterminated string"},
+		{"message A { } }", "unbalanced }"},
+	}
+	for _, c := range cases {
+		_, err := parseProtoText("bad.proto", c.text)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("%q: error %v, want %q", c.text, err, c.err)
+		}
+	}
+}
+
+func msgIds(t *testing.T, text string) map[string]uint32 {
+	f, err := parseProtoText("ids.proto", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Package = "ids"
+	f.MsgIdBase = 1000
+	d := &genData{File: f, byType: map[string]*msgId{}}
+	if err := d.assignMsgIds(); err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]uint32{}
+	for _, id := range d.MsgIds {
+		if id.Id < 1000 || id.Id >= 1000+msgIdSpan {
+			t.Fatalf("msg id %v of %v out of range", id.Id, id.Type)
+		}
+		ids[id.Type] = id.Id
+	}
+	return ids
+}
+
+//adding and reordering rpcs keeps the ids of the other messages
+func TestMsgIdsStable(t *testing.T) {
+	before := msgIds(t, `package p; service S {
+		rpc A(AReq) returns (AResp);
+		rpc B(BReq) returns (BResp);
+	}`)
+	after := msgIds(t, `package p; service S {
+		rpc C(CReq) returns (CResp);
+		rpc B(BReq) returns (BResp);
+		rpc A(AReq) returns (AResp);
+	}`)
+	if len(before) != 4 || len(after) != 6 {
+		t.Fatalf("%v and %v ids", len(before), len(after))
+	}
+	for typ, id := range before {
+		if after[typ] != id {
+			t.Fatalf("%v changed from %v to %v", typ, id, after[typ])
+		}
+	}
+
+	d := &genData{File: &File{MsgIdBase: math.MaxUint32 - msgIdSpan}, byType: map[string]*msgId{}}
+	if d.assignMsgIds() == nil {
+		t.Fatal("base past the reserved ids accepted")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	f, err := parseProtoText("login.proto", loginProto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Package = "login"
+	f.MsgIdBase = 1000
+	src, err := generate(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func RegisterLogin(sf *utils.ServiceFactory, svc Login)",
+		"Ping(session defs.ISession, req *wrappers.StringValue, resp *LoginReq_Device) int",
+		`"github.com/golang/protobuf/ptypes/empty"`,
+		"module.RegisterMsg(LoginReq_DeviceMsgId, new(LoginReq_Device))",
+		"func (c *ChatClient) Say(ctx context.Context, req *SayReq) (*SayResp, error)",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Fatalf("generated code misses %q\n%s", want, src)
+		}
+	}
+}
+
+func TestParseGo(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "svc.go")
+	err := ioutil.WriteFile(source, []byte(`package svc
+
+import (
+	"github.com/lightning-go/lightning/defs"
+	pb "example.com/game/proto"
+)
+
+type LoginService interface {
+	Login(session defs.ISession, req *pb.LoginReq, resp *LoginResp) int
+	Logout(session defs.ISession, req *LogoutReq) int
+}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := parseGo(source, []string{"LoginService"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := f.Services[0].Methods
+	if len(m) != 2 || m[0].ReqMsg != "example.com/game/proto.LoginReq" || m[0].ReplyMsg != "svc.LoginResp" ||
+		m[1].Reply != "" {
+		t.Fatalf("methods %+v %+v", *m[0], *m[1])
+	}
+	if len(f.Imports) != 1 || f.Imports[0] != `pb "example.com/game/proto"` {
+		t.Fatalf("imports %v", f.Imports)
+	}
+
+	if _, err := parseGo(source, []string{"Missing"}); err == nil {
+		t.Fatal("missing interface accepted")
+	}
+}
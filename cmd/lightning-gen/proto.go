@@ -0,0 +1,409 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//the go packages of the google.protobuf well known types, by message
+var wellKnownTypes = map[string]string{
+	"Any":         "github.com/golang/protobuf/ptypes/any",
+	"Duration":    "github.com/golang/protobuf/ptypes/duration",
+	"Empty":       "github.com/golang/protobuf/ptypes/empty",
+	"Timestamp":   "github.com/golang/protobuf/ptypes/timestamp",
+	"Struct":      "github.com/golang/protobuf/ptypes/struct",
+	"Value":       "github.com/golang/protobuf/ptypes/struct",
+	"ListValue":   "github.com/golang/protobuf/ptypes/struct",
+	"DoubleValue": "github.com/golang/protobuf/ptypes/wrappers",
+	"FloatValue":  "github.com/golang/protobuf/ptypes/wrappers",
+	"Int64Value":  "github.com/golang/protobuf/ptypes/wrappers",
+	"UInt64Value": "github.com/golang/protobuf/ptypes/wrappers",
+	"Int32Value":  "github.com/golang/protobuf/ptypes/wrappers",
+	"UInt32Value": "github.com/golang/protobuf/ptypes/wrappers",
+	"BoolValue":   "github.com/golang/protobuf/ptypes/wrappers",
+	"StringValue": "github.com/golang/protobuf/ptypes/wrappers",
+	"BytesValue":  "github.com/golang/protobuf/ptypes/wrappers",
+}
+
+//the go package names differing from the last element of the import path
+var goPackageNames = map[string]string{
+	"github.com/golang/protobuf/ptypes/struct": "structpb",
+}
+
+var protoIdent = regexp.MustCompile(`^\.?[A-Za-z_][\w.]*$`)
+
+type protoToken struct {
+	text string
+	line int
+}
+
+type protoParser struct {
+	source  string
+	tokens  []protoToken
+	pos     int
+	pkg     string
+	imports map[string]bool
+}
+
+//message types are expected in the generated package, as protoc-gen-go emits
+//them, or among the well known types
+func parseProto(source, pkg string) (*File, error) {
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parseProtoText(source, string(content))
+	if err != nil {
+		return nil, err
+	}
+	f.Dir = filepath.Dir(source)
+	f.Package = pkg
+	return f, nil
+}
+
+func parseProtoText(source, text string) (*File, error) {
+	tokens, err := tokenizeProto(source, text)
+	if err != nil {
+		return nil, err
+	}
+	p := &protoParser{
+		source:  source,
+		tokens:  tokens,
+		imports: map[string]bool{},
+	}
+
+	f := &File{}
+	for p.pos < len(p.tokens) {
+		switch p.tokens[p.pos].text {
+		case "package":
+			p.pos++
+			tok, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(";"); err != nil {
+				return nil, err
+			}
+			p.pkg = strings.TrimPrefix(tok.text, ".")
+		case "service":
+			s, err := p.service()
+			if err != nil {
+				return nil, err
+			}
+			f.Services = append(f.Services, s)
+		default:
+			if err = p.skipStatement(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(f.Services) == 0 {
+		return nil, fmt.Errorf("%v: no service found", source)
+	}
+
+	//services may precede the package statement
+	for _, s := range f.Services {
+		for _, m := range s.Methods {
+			m.Req, m.ReqMsg, err = p.goName(m.Req)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v.%v: %v", source, s.Name, m.Name, err)
+			}
+			m.Reply, m.ReplyMsg, err = p.goName(m.Reply)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v.%v: %v", source, s.Name, m.Name, err)
+			}
+		}
+	}
+	for importPath := range p.imports {
+		spec := strconv.Quote(importPath)
+		if name, ok := goPackageNames[importPath]; ok {
+			spec = name + " " + spec
+		}
+		f.Imports = append(f.Imports, spec)
+	}
+	return f, nil
+}
+
+//tokenizeProto splits the source into names, numbers, strings and symbols, comments dropped
+func tokenizeProto(source, text string) ([]protoToken, error) {
+	var tokens []protoToken
+	line := 1
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(text[i:], "//"):
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(text[i:], "/*"):
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("%v:%v: unterminated comment", source, line)
+			}
+			line += strings.Count(text[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for ; j < len(text) && text[j] != c; j++ {
+				if text[j] == '\\' {
+					j++
+				} else if text[j] == '\n' {
+					break
+				}
+			}
+			if j >= len(text) || text[j] != c {
+				return nil, fmt.Errorf("%v:%v: unterminated string", source, line)
+			}
+			tokens = append(tokens, protoToken{text[i : j+1], line})
+			i = j + 1
+		case isProtoNameChar(c):
+			j := i
+			for j < len(text) && isProtoNameChar(text[j]) {
+				j++
+			}
+			tokens = append(tokens, protoToken{text[i:j], line})
+			i = j
+		default:
+			tokens = append(tokens, protoToken{string(c), line})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+func isProtoNameChar(c byte) bool {
+	return c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func (p *protoParser) errorf(tok protoToken, format string, args ...interface{}) error {
+	return fmt.Errorf("%v:%v: %v", p.source, tok.line, fmt.Sprintf(format, args...))
+}
+
+func (p *protoParser) next() (protoToken, error) {
+	if p.pos >= len(p.tokens) {
+		line := 1
+		if len(p.tokens) > 0 {
+			line = p.tokens[len(p.tokens)-1].line
+		}
+		return protoToken{}, fmt.Errorf("%v:%v: unexpected end of file", p.source, line)
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	return tok, nil
+}
+
+func (p *protoParser) expect(text string) error {
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	if tok.text != text {
+		return p.errorf(tok, "want %q, got %q", text, tok.text)
+	}
+	return nil
+}
+
+func (p *protoParser) ident() (protoToken, error) {
+	tok, err := p.next()
+	if err != nil {
+		return tok, err
+	}
+	if !protoIdent.MatchString(tok.text) {
+		return tok, p.errorf(tok, "want a name, got %q", tok.text)
+	}
+	return tok, nil
+}
+
+//skipStatement skips a statement ending with ; or a block, braces balanced
+func (p *protoParser) skipStatement() error {
+	depth := 0
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch tok.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth < 0 {
+				return p.errorf(tok, "unbalanced }")
+			}
+			if depth == 0 {
+				return nil
+			}
+		case ";":
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+//skipBlock skips to the } closing the block whose { was read
+func (p *protoParser) skipBlock() error {
+	depth := 1
+	for depth > 0 {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch tok.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		}
+	}
+	return nil
+}
+
+func (p *protoParser) service() (*Service, error) {
+	p.pos++
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	s := &Service{Name: name.text, Declare: true}
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch tok.text {
+		case "}":
+			if len(s.Methods) == 0 {
+				return nil, p.errorf(name, "service %v has no rpc", s.Name)
+			}
+			return s, nil
+		case ";":
+		case "option":
+			if err = p.skipStatement(); err != nil {
+				return nil, err
+			}
+		case "rpc":
+			m, err := p.rpc(s)
+			if err != nil {
+				return nil, err
+			}
+			s.Methods = append(s.Methods, m)
+		default:
+			return nil, p.errorf(tok, "unexpected %q in service %v", tok.text, s.Name)
+		}
+	}
+}
+
+func (p *protoParser) rpc(s *Service) (*Method, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	req, err := p.rpcType()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect("returns"); err != nil {
+		return nil, err
+	}
+	reply, err := p.rpcType()
+	if err != nil {
+		return nil, err
+	}
+
+	//the options of the rpc are not used
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch tok.text {
+	case ";":
+	case "{":
+		if err = p.skipBlock(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, p.errorf(tok, "want ; or { after rpc %v, got %q", name.text, tok.text)
+	}
+
+	if req.text == "" || reply.text == "" {
+		return nil, p.errorf(name, "%v.%v: streaming is not supported", s.Name, name.text)
+	}
+	return &Method{Name: name.text, Req: req.text, Reply: reply.text}, nil
+}
+
+//rpcType reads ( [stream] Type ), the text is empty for a stream
+func (p *protoParser) rpcType() (protoToken, error) {
+	if err := p.expect("("); err != nil {
+		return protoToken{}, err
+	}
+	tok, err := p.ident()
+	if err != nil {
+		return tok, err
+	}
+	stream := false
+	if tok.text == "stream" && p.pos < len(p.tokens) && p.tokens[p.pos].text != ")" {
+		stream = true
+		if tok, err = p.ident(); err != nil {
+			return tok, err
+		}
+	}
+	if err = p.expect(")"); err != nil {
+		return tok, err
+	}
+	if stream {
+		tok.text = ""
+	}
+	return tok, nil
+}
+
+//goName returns the go type protoc-gen-go emits for the message and its full
+//proto name. the messages of the package keep their nesting joined by _,
+//the ones of other packages must be well known types
+func (p *protoParser) goName(name string) (string, string, error) {
+	name = strings.TrimPrefix(name, ".")
+	if p.pkg != "" && strings.HasPrefix(name, p.pkg+".") {
+		name = name[len(p.pkg)+1:]
+	} else if strings.HasPrefix(name, "google.protobuf.") {
+		msg := name[len("google.protobuf."):]
+		importPath, ok := wellKnownTypes[msg]
+		if !ok {
+			return "", "", fmt.Errorf("%v is not a supported well known type", name)
+		}
+		p.imports[importPath] = true
+		pkgName, ok := goPackageNames[importPath]
+		if !ok {
+			pkgName = path.Base(importPath)
+		}
+		return pkgName + "." + msg, name, nil
+	}
+
+	//package names are lower case, messages are not
+	if first := name[0]; first < 'A' || first > 'Z' {
+		return "", "", fmt.Errorf("%v is not a message of package %q", name, p.pkg)
+	}
+	full := name
+	if p.pkg != "" {
+		full = p.pkg + "." + name
+	}
+	return strings.Replace(name, ".", "_", -1), full, nil
+}
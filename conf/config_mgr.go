@@ -97,18 +97,29 @@ type LogConfig struct {
 }
 
 type ServerConfig struct {
-	Name          string   `json:"name"`
-	Host          string   `json:"host"`
-	Port          int      `json:"port"`
-	WebHost       string   `json:"webHost"`
-	WebPort       int      `json:"webPort"`
-	MaxConn       int      `json:"maxConn"`
-	MaxPacketSize int      `json:"maxPacketSize"`
-	Remotes       []string `json:"remotes"`
-	HostList      []string `json:"hostList"`
-	Timeout       int64    `json:"timeout"`
-	Group         string   `json:"group"`
-	WatchGroups   []string `json:"watchGroups"`
+	Name          string     `json:"name"`
+	Host          string     `json:"host"`
+	Port          int        `json:"port"`
+	WebHost       string     `json:"webHost"`
+	WebPort       int        `json:"webPort"`
+	MaxConn       int        `json:"maxConn"`
+	MaxConnPerIp  int        `json:"maxConnPerIp"`
+	MaxPacketSize int        `json:"maxPacketSize"`
+	Remotes       []string   `json:"remotes"`
+	HostList      []string   `json:"hostList"`
+	Timeout       int64      `json:"timeout"`
+	Heartbeat     int64      `json:"heartbeat"`   //second, 0 disabled
+	IdleTimeout   int64      `json:"idleTimeout"` //second, 0 disabled
+	Group         string     `json:"group"`
+	WatchGroups   []string   `json:"watchGroups"`
+	TLS           *TLSConfig `json:"tls"`
+}
+
+type TLSConfig struct {
+	CertFile   string `json:"certFile"`
+	KeyFile    string `json:"keyFile"`
+	CAFile     string `json:"caFile"` //server: client CA, enables mTLS; client: root CA
+	ServerName string `json:"serverName"`
 }
 
 type DBConfig struct {
@@ -31,6 +31,11 @@ type GlobalVal struct {
 	PongWait         time.Duration
 	WriteWait        time.Duration
 	RedisIdleTimeout time.Duration
+	DrainTimeout     time.Duration
+	RpcTimeout       time.Duration
+	StreamWindow     int32
+	MaxWriteBatch    int32
+	WriteDelay       time.Duration
 }
 
 func newGlobalVal() *GlobalVal {
@@ -42,5 +47,10 @@ func newGlobalVal() *GlobalVal {
 		PongWait:         time.Second * 120,
 		WriteWait:        time.Second * 60,
 		RedisIdleTimeout: time.Second * 60,
+		DrainTimeout:     time.Second * 10,
+		RpcTimeout:       time.Second * 5,
+		StreamWindow:     64,
+		MaxWriteBatch:    128,
+		WriteDelay:       0,
 	}
 }
@@ -31,6 +31,24 @@ const (
 
 var ConvertTypeError = errors.New("convert type error")
 
+//the MemMgr not closed yet, for FlushAll
+var memMgrs sync.Map
+
+//FlushAll waits up to timeout for the db queues of all the open MemMgr to be synced,
+//the application registers it to run once the servers are stopped, e.g.
+//network.GetSrvMgr().AddExitHook(func() { db.FlushAll(timeout) })
+func FlushAll(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	ok := true
+	memMgrs.Range(func(key, value interface{}) bool {
+		if !key.(*MemMgr).Flush(time.Until(deadline)) {
+			ok = false
+		}
+		return true
+	})
+	return ok
+}
+
 type IkCallback func(obj interface{})(ikField string, ikVal interface{})
 type PkCallback func(obj interface{}) (pkVal interface{})
 
@@ -71,6 +89,7 @@ type MemMgr struct {
 	queue        *utils.SafeQueue
 	queueWorking int32
 	queueWait    sync.WaitGroup
+	queueLen     int64
 	expire		 int64
 	ikCallbackList	[]IkCallback
 	pkCallback		PkCallback
@@ -119,6 +138,7 @@ func NewMemMgr(rc *RedisClient, dbName, tableName, pk string,
 	}
 
 	mm.initPKValue()
+	memMgrs.Store(mm, true)
 	mm.log.Infof("table %v cache init ok", mm.tableName)
 	return mm
 }
@@ -650,9 +670,35 @@ func (mm *MemMgr) putQueue(state int, d interface{}) {
 	memMode := NewMemMode()
 	memMode.State = state
 	memMode.Data = d
+	atomic.AddInt64(&mm.queueLen, 1)
 	mm.queue.Put(memMode)
 }
 
+//QueueLen returns the number of records waiting to be synced to db
+func (mm *MemMgr) QueueLen() int64 {
+	return atomic.LoadInt64(&mm.queueLen)
+}
+
+//Flush waits up to timeout for the db queue to be synced
+func (mm *MemMgr) Flush(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for mm.QueueLen() > 0 {
+		if time.Now().After(deadline) {
+			mm.log.Warnf("table %v flush timeout, %v records left", mm.tableName, mm.QueueLen())
+			return false
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return true
+}
+
+//Close waits up to timeout for the db queue to be synced and leaves FlushAll
+func (mm *MemMgr) Close(timeout time.Duration) bool {
+	ok := mm.Flush(timeout)
+	memMgrs.Delete(mm)
+	return ok
+}
+
 func (mm *MemMgr) enableQueue() {
 	go func() {
 		mm.log.Tracef("enable memMode queue")
@@ -684,10 +730,10 @@ func (mm *MemMgr) enableQueue() {
 				if !ok || d == nil {
 					continue
 				}
-				if d.Data == nil {
-					continue
+				if d.Data != nil {
+					mm.syncMemMode(d.State, d.Data)
 				}
-				mm.syncMemMode(d.State, d.Data)
+				atomic.AddInt64(&mm.queueLen, -1)
 				FreeMemMode(d)
 			}
 		}
@@ -5,6 +5,11 @@
 
 package defs
 
+import (
+	"context"
+	"time"
+)
+
 type IPacket interface {
 	SetData([]byte)
 	GetData() []byte
@@ -18,17 +23,87 @@ type IPacket interface {
 	SetSequence(uint64)
 }
 
+type IMsgPacket interface {
+	IPacket
+	SetMsg(interface{})
+	GetMsg() interface{}
+}
+
 type ICodec interface {
 	Init(IConnection) bool
 	Write(IPacket) error
 	Read() (IPacket, error)
 }
 
+//codecs holding settings implement it so every connection gets a configured copy
+type ICodecCloner interface {
+	Clone() ICodec
+}
+
+//decorators switched in by UpdateCodec wrap the running codec, Decode finishes
+//a frame the running codec read before the switch and returns nil when consumed
+type ICodecDecorator interface {
+	Decorate(ICodec)
+	Decode(IPacket) (IPacket, error)
+}
+
+//codecs exchanging keys in band, Hello is sent once the codec is in use
+type IHandshakeCodec interface {
+	Hello() error
+}
+
+//codecs whose frames do not depend on connection state, a frame encoded once
+//is written to every connection using the same codec type
+type IFrameCodec interface {
+	EncodeFrame(IPacket) ([]byte, error)
+	WriteFrame([]byte) error
+}
+
+//codecs framing the packet id, the heartbeat pings by id so it only runs over them
+type IPacketIdCodec interface {
+	CarriesId() bool
+}
+
+//codecs with a write buffer, the io module encodes the queued packets with
+//WriteBuffered and flushes them with a single write
+type IBufferedCodec interface {
+	WriteBuffered(IPacket) error
+	Flush() error
+}
+
+//frame codecs with a write buffer, see IBufferedCodec
+type IBufferedFrameCodec interface {
+	WriteFrameBuffered([]byte) error
+}
+
+//IStream carries packets under the sequence of the request opening it
+type IStream interface {
+	Sequence() uint64
+	Send(IPacket) error
+	SendCtx(context.Context, IPacket) error
+	Recv() (IPacket, error)
+	RecvCtx(context.Context) (IPacket, error)
+	Close() error
+	Done() <-chan struct{}
+	Err() error
+}
+
 type IIOModule interface {
 	Codec(ICodec) bool
 	Close()
 	OnConnectionLost()
 	Write(IPacket)
 	WriteAwait(IPacket) (IPacket, error)
+	WriteAwaitCtx(context.Context, IPacket) (IPacket, error)
 	UpdateCodec(ICodec)
+	WriteQueueLen() int
+	EnableHeartbeat(time.Duration, time.Duration)
+	OpenStream(IPacket) (IStream, error)
+	AcceptStream(IPacket) (IStream, error)
+}
+
+//IWriteLimit is implemented by io modules supporting write policies and watermarks
+type IWriteLimit interface {
+	SetWritePolicy(WritePolicy, time.Duration)
+	SetWatermark(high, low int)
 }
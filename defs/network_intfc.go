@@ -6,6 +6,7 @@
 package defs
 
 import (
+	"context"
 	"net"
 	"github.com/gorilla/websocket"
 )
@@ -13,10 +14,13 @@ import (
 type ExitCallback func()
 type CloseCallback func(IConnection)
 type WriteCompleteCallback func(IConnection)
+type WatermarkCallback func(IConnection, int)
 type ConnCallback func(IConnection)
 type MsgCallback func(IConnection, IPacket)
 type AuthorizedCallback func(IConnection, IPacket) bool
 type ClientConnCallback func(net.Conn)
+type RejectCallback func(string, error)
+type CodecErrorCallback func(IConnection, error)
 type NewIOModuleCallback func(IConnection) IIOModule
 type ParseMethodNameCallback func(string) (string, error)
 type ParseDataCallback func([]byte, interface{}) bool
@@ -56,6 +60,10 @@ type IClient interface {
 	SendDataAwait([]byte) (IPacket, error)
 	SendDataByIdAwait(string, []byte) (IPacket, error)
 	SendPacketAwait(IPacket) (IPacket, error)
+	SendDataAwaitCtx(context.Context, []byte) (IPacket, error)
+	SendDataByIdAwaitCtx(context.Context, string, []byte) (IPacket, error)
+	SendPacketAwaitCtx(context.Context, IPacket) (IPacket, error)
+	OpenStream(IPacket) (IStream, error)
 	GetConn() IConnection
 }
 
@@ -73,13 +81,47 @@ type IConnection interface {
 	WriteDataAwait([]byte) (IPacket, error)
 	WriteDataByIdAwait(string, []byte) (IPacket, error)
 	WritePacketAwait(IPacket) (IPacket, error)
+	WriteDataAwaitCtx(context.Context, []byte) (IPacket, error)
+	WriteDataByIdAwaitCtx(context.Context, string, []byte) (IPacket, error)
+	WritePacketAwaitCtx(context.Context, IPacket) (IPacket, error)
+	OpenStream(IPacket) (IStream, error)
+	AcceptStream(IPacket) (IStream, error)
 	WriteComplete()
+	WriteQueueLen() int
 	SetContext(interface{}, interface{})
 	GetContext(interface{}) interface{}
 	DelContext(interface{})
 	UpdateCodec(ICodec)
 }
 
+//WritePolicy decides what a write does when the write queue is full
+type WritePolicy int
+
+const (
+	//waits for room, up to the timeout when one is set, then drops the packet
+	WriteBlock WritePolicy = iota
+	WriteDropNewest
+	WriteDropOldest
+	//closes the slow consumer
+	WriteDisconnect
+)
+
+//IWatermarkHandler is told by the io module when the write queue length
+//reaches the high watermark and when it falls back to the low one, on the
+//goroutine writing or draining the queue, so it must not block
+type IWatermarkHandler interface {
+	OnHighWatermark(int)
+	OnLowWatermark(int)
+}
+
+type IPacketLimit interface {
+	GetMaxPacketSize() int
+}
+
+type ICodecErrorHandler interface {
+	OnCodecError(error)
+}
+
 type ITcpConnection interface {
 	GetConn() net.Conn
 }
@@ -105,7 +147,13 @@ type ISession interface {
 	WritePacketAwait(IPacket) (IPacket, error)
 	WriteDataAwait([]byte) (IPacket, error)
 	WriteDataByIdAwait(string, []byte) (IPacket, error)
+	WritePacketAwaitCtx(context.Context, IPacket) (IPacket, error)
+	OpenStream(IPacket) (IStream, error)
+	AcceptStream(IPacket) (IStream, error)
+	WriteDataAwaitCtx(context.Context, []byte) (IPacket, error)
+	WriteDataByIdAwaitCtx(context.Context, string, []byte) (IPacket, error)
 	OnService(ISession, IPacket) bool
+	QueueLen() int
 	SetContext(key, value interface{})
 	GetContext(key interface{}) interface{}
 	SetPacket(IPacket)
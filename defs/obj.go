@@ -69,6 +69,20 @@ func (p *Packet) SetSequence(sequence uint64) {
 	p.sequence = sequence
 }
 
+//
+type MsgPacket struct {
+	Packet
+	msg interface{}
+}
+
+func (p *MsgPacket) GetMsg() interface{} {
+	return p.msg
+}
+
+func (p *MsgPacket) SetMsg(msg interface{}) {
+	p.msg = msg
+}
+
 //
 type MethodType struct {
 	sync.Mutex
@@ -0,0 +1,320 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/etcd"
+	"github.com/lightning-go/lightning/logger"
+)
+
+type registration struct {
+	ins    *Instance
+	ttl    int64
+	lease  clientv3.LeaseID
+	cancel context.CancelFunc
+}
+
+//EtcdRegistry keeps an instance in <prefix>/<group>/<name> as json, attached
+//to a lease kept alive until Deregister
+type EtcdRegistry struct {
+	client     *clientv3.Client
+	prefix     string
+	mux        sync.Mutex
+	registered map[string]*registration
+}
+
+func NewEtcdRegistry(e *etcd.Etcd, prefix string) *EtcdRegistry {
+	if e == nil || e.GetClient() == nil {
+		return nil
+	}
+	return &EtcdRegistry{
+		client:     e.GetClient(),
+		prefix:     strings.TrimSuffix(prefix, "/"),
+		registered: make(map[string]*registration),
+	}
+}
+
+func (er *EtcdRegistry) key(group, name string) string {
+	return er.prefix + "/" + group + "/" + name
+}
+
+func (er *EtcdRegistry) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.TODO(), conf.GetGlobalVal().HttpTimeout)
+}
+
+func (er *EtcdRegistry) put(key string, reg *registration) error {
+	data, err := json.Marshal(reg.ins)
+	if err != nil {
+		return err
+	}
+	var opts []clientv3.OpOption
+	if reg.lease != 0 {
+		opts = append(opts, clientv3.WithLease(reg.lease))
+	}
+	ctx, cancel := er.context()
+	defer cancel()
+	_, err = er.client.Put(ctx, key, string(data), opts...)
+	return err
+}
+
+func (er *EtcdRegistry) grant(key string, reg *registration) error {
+	if reg.ttl > 0 {
+		ctx, cancel := er.context()
+		lease, err := er.client.Grant(ctx, reg.ttl)
+		cancel()
+		if err != nil {
+			return err
+		}
+		reg.lease = lease.ID
+	}
+	return er.put(key, reg)
+}
+
+//the lease is granted again when it is lost, e.g. after a network partition
+func (er *EtcdRegistry) keepAlive(ctx context.Context, key string, reg *registration) {
+	for {
+		er.mux.Lock()
+		lease := reg.lease
+		er.mux.Unlock()
+
+		ch, err := er.client.KeepAlive(ctx, lease)
+		if err == nil {
+			for range ch {
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Warnf("lease of %v lost, registering again", key)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+		er.mux.Lock()
+		if ctx.Err() == nil {
+			err = er.grant(key, reg)
+		}
+		er.mux.Unlock()
+		if err != nil {
+			logger.Error(err)
+		}
+	}
+}
+
+func (er *EtcdRegistry) stop(key string, reg *registration) error {
+	reg.cancel()
+	ctx, cancel := er.context()
+	defer cancel()
+	if reg.lease != 0 {
+		_, err := er.client.Revoke(ctx, reg.lease)
+		return err
+	}
+	_, err := er.client.Delete(ctx, key)
+	return err
+}
+
+func (er *EtcdRegistry) Register(ins *Instance, ttl int64) error {
+	if !ins.valid() {
+		return ErrInvalidInstance
+	}
+	cp := *ins
+	key := er.key(ins.Group, ins.Name)
+
+	er.mux.Lock()
+	defer er.mux.Unlock()
+	reg, ok := er.registered[key]
+	if ok && reg.ttl == ttl {
+		reg.ins = &cp
+		return er.put(key, reg)
+	}
+	if ok {
+		delete(er.registered, key)
+		er.stop(key, reg)
+	}
+
+	reg = &registration{ins: &cp, ttl: ttl}
+	err := er.grant(key, reg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	reg.cancel = cancel
+	er.registered[key] = reg
+	if ttl > 0 {
+		go er.keepAlive(ctx, key, reg)
+	}
+	return nil
+}
+
+func (er *EtcdRegistry) Deregister(ins *Instance) error {
+	if ins == nil {
+		return ErrNotRegistered
+	}
+	key := er.key(ins.Group, ins.Name)
+
+	er.mux.Lock()
+	defer er.mux.Unlock()
+	reg, ok := er.registered[key]
+	if !ok {
+		return ErrNotRegistered
+	}
+	delete(er.registered, key)
+	return er.stop(key, reg)
+}
+
+func (er *EtcdRegistry) parse(key, value []byte) *Instance {
+	ins := &Instance{}
+	err := json.Unmarshal(value, ins)
+	if err != nil {
+		logger.Errorf("discovery %s: %v", key, err)
+		return nil
+	}
+	if len(ins.Name) == 0 {
+		k := string(key)
+		ins.Name = k[strings.LastIndex(k, "/")+1:]
+	}
+	return ins
+}
+
+func (er *EtcdRegistry) Watch(ctx context.Context, group string) (<-chan *Event, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	prefix := er.key(group, "")
+	resp, err := er.client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan *Event)
+	go func() {
+		defer close(events)
+		w := &etcdWatch{
+			registry: er,
+			ctx:      ctx,
+			events:   events,
+			known:    make(map[string]*Instance),
+		}
+		if !w.sync(resp) {
+			return
+		}
+		for {
+			rev := resp.Header.Revision + 1
+			if !w.watch(prefix, rev) {
+				return
+			}
+			//the watch was cut or compacted, the changes are taken from a new list
+			for {
+				resp, err = er.client.Get(ctx, prefix, clientv3.WithPrefix())
+				if err == nil {
+					break
+				}
+				logger.Error(err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Second):
+				}
+			}
+			if !w.sync(resp) {
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+type etcdWatch struct {
+	registry *EtcdRegistry
+	ctx      context.Context
+	events   chan *Event
+	known    map[string]*Instance
+}
+
+func (w *etcdWatch) send(t EventType, ins *Instance) bool {
+	select {
+	case w.events <- &Event{Type: t, Instance: ins}:
+		return true
+	case <-w.ctx.Done():
+		return false
+	}
+}
+
+func (w *etcdWatch) put(key, value []byte) bool {
+	ins := w.registry.parse(key, value)
+	if ins == nil {
+		return true
+	}
+	old, ok := w.known[string(key)]
+	if ok && old.equal(ins) {
+		return true
+	}
+	w.known[string(key)] = ins
+	if ok {
+		return w.send(EventUpdate, ins)
+	}
+	return w.send(EventAdd, ins)
+}
+
+func (w *etcdWatch) del(key []byte) bool {
+	old, ok := w.known[string(key)]
+	if !ok {
+		return true
+	}
+	delete(w.known, string(key))
+	return w.send(EventRemove, old)
+}
+
+//sync sends the difference between the known instances and the listed ones
+func (w *etcdWatch) sync(resp *clientv3.GetResponse) bool {
+	listed := make(map[string]bool, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		listed[string(kv.Key)] = true
+		if !w.put(kv.Key, kv.Value) {
+			return false
+		}
+	}
+	for key := range w.known {
+		if !listed[key] && !w.del([]byte(key)) {
+			return false
+		}
+	}
+	return true
+}
+
+//returns false once ctx is done
+func (w *etcdWatch) watch(prefix string, rev int64) bool {
+	wch := w.registry.client.Watch(w.ctx, prefix, clientv3.WithPrefix(), clientv3.WithRev(rev))
+	for wresp := range wch {
+		if wresp.Err() != nil {
+			logger.Warnf("discovery watch %v: %v", prefix, wresp.Err())
+			break
+		}
+		for _, ev := range wresp.Events {
+			ok := true
+			switch ev.Type {
+			case clientv3.EventTypePut:
+				ok = w.put(ev.Kv.Key, ev.Kv.Value)
+			case clientv3.EventTypeDelete:
+				ok = w.del(ev.Kv.Key)
+			}
+			if !ok {
+				return false
+			}
+		}
+	}
+	return w.ctx.Err() == nil
+}
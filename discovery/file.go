@@ -0,0 +1,207 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lightning-go/lightning/logger"
+)
+
+const DefaultFileInterval = time.Second
+
+//FileRegistry keeps the instances as a json array in a local file shared by
+//the processes of one host, changes are picked up by polling, ttl is ignored
+type FileRegistry struct {
+	*listRegistry
+	path      string
+	interval  time.Duration
+	fileMux   sync.Mutex
+	modTime   time.Time
+	size      int64
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func NewFileRegistry(path string) *FileRegistry {
+	fr := &FileRegistry{
+		listRegistry: newListRegistry(),
+		path:         path,
+		interval:     DefaultFileInterval,
+		closed:       make(chan struct{}),
+	}
+	fr.reload()
+	go fr.poll()
+	return fr
+}
+
+//SetInterval sets how often the file is checked for changes
+func (fr *FileRegistry) SetInterval(interval time.Duration) {
+	if interval > 0 {
+		fr.fileMux.Lock()
+		fr.interval = interval
+		fr.fileMux.Unlock()
+	}
+}
+
+//Close stops polling, watchers end with their context
+func (fr *FileRegistry) Close() {
+	fr.closeOnce.Do(func() {
+		close(fr.closed)
+	})
+}
+
+func (fr *FileRegistry) poll() {
+	for {
+		fr.fileMux.Lock()
+		interval := fr.interval
+		fr.fileMux.Unlock()
+
+		select {
+		case <-fr.closed:
+			return
+		case <-time.After(interval):
+			fr.reload()
+		}
+	}
+}
+
+func (fr *FileRegistry) reload() {
+	fr.fileMux.Lock()
+	defer fr.fileMux.Unlock()
+
+	info, err := os.Stat(fr.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error(err)
+			return
+		}
+		fr.modTime, fr.size = time.Time{}, 0
+		fr.set(nil)
+		return
+	}
+	if info.ModTime().Equal(fr.modTime) && info.Size() == fr.size {
+		return
+	}
+	list, err := fr.read()
+	if err != nil {
+		logger.Errorf("load %v failed: %v", fr.path, err)
+		return
+	}
+	fr.modTime, fr.size = info.ModTime(), info.Size()
+	fr.set(list)
+}
+
+func (fr *FileRegistry) read() ([]*Instance, error) {
+	data, err := ioutil.ReadFile(fr.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	list := make([]*Instance, 0)
+	if len(data) == 0 {
+		return list, nil
+	}
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, err
+	}
+	valid := list[:0]
+	for _, ins := range list {
+		if ins.valid() {
+			valid = append(valid, ins)
+		}
+	}
+	return valid, nil
+}
+
+//the file is replaced by rename so readers never see a partial write
+func (fr *FileRegistry) write(list []*Instance) error {
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(fr.path), filepath.Base(fr.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	err = os.Rename(tmp.Name(), fr.path)
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (fr *FileRegistry) update(f func([]*Instance) ([]*Instance, error)) error {
+	fr.fileMux.Lock()
+	list, err := fr.read()
+	if err == nil {
+		list, err = f(list)
+	}
+	if err == nil {
+		err = fr.write(list)
+	}
+	//a rewrite of the same size may keep the modification time
+	fr.modTime = time.Time{}
+	fr.fileMux.Unlock()
+	if err != nil {
+		return err
+	}
+	fr.reload()
+	return nil
+}
+
+func (fr *FileRegistry) Register(ins *Instance, ttl int64) error {
+	if !ins.valid() {
+		return ErrInvalidInstance
+	}
+	return fr.update(func(list []*Instance) ([]*Instance, error) {
+		cp := *ins
+		for i, old := range list {
+			if old.key() == ins.key() {
+				list[i] = &cp
+				return list, nil
+			}
+		}
+		return append(list, &cp), nil
+	})
+}
+
+func (fr *FileRegistry) Deregister(ins *Instance) error {
+	if ins == nil {
+		return ErrNotRegistered
+	}
+	return fr.update(func(list []*Instance) ([]*Instance, error) {
+		for i, old := range list {
+			if old.key() == ins.key() {
+				return append(list[:i], list[i+1:]...), nil
+			}
+		}
+		return nil, ErrNotRegistered
+	})
+}
+
+func (fr *FileRegistry) Watch(ctx context.Context, group string) (<-chan *Event, error) {
+	return fr.watch(ctx, group)
+}
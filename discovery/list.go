@@ -0,0 +1,180 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package discovery
+
+import (
+	"context"
+	"sync"
+)
+
+//watchers queue events so a slow reader never blocks the registry
+type listWatcher struct {
+	ctx     context.Context
+	group   string
+	mux     sync.Mutex
+	pending []*Event
+	notify  chan struct{}
+	events  chan *Event
+}
+
+func newListWatcher(ctx context.Context, group string) *listWatcher {
+	return &listWatcher{
+		ctx:    ctx,
+		group:  group,
+		notify: make(chan struct{}, 1),
+		events: make(chan *Event),
+	}
+}
+
+func (w *listWatcher) push(events []*Event) {
+	w.mux.Lock()
+	for _, e := range events {
+		if e.Instance.Group == w.group {
+			w.pending = append(w.pending, e)
+		}
+	}
+	w.mux.Unlock()
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (w *listWatcher) run(done func()) {
+	defer func() {
+		done()
+		close(w.events)
+	}()
+	for {
+		w.mux.Lock()
+		pending := w.pending
+		w.pending = nil
+		w.mux.Unlock()
+
+		for _, e := range pending {
+			select {
+			case w.events <- e:
+			case <-w.ctx.Done():
+				return
+			}
+		}
+		select {
+		case <-w.notify:
+		case <-w.ctx.Done():
+			return
+		}
+	}
+}
+
+//listRegistry keeps the instances in memory, the static and file registries
+//are built on it
+type listRegistry struct {
+	mux       sync.Mutex
+	instances map[string]*Instance
+	watchers  map[*listWatcher]struct{}
+}
+
+func newListRegistry() *listRegistry {
+	return &listRegistry{
+		instances: make(map[string]*Instance),
+		watchers:  make(map[*listWatcher]struct{}),
+	}
+}
+
+func (lr *listRegistry) list() []*Instance {
+	lr.mux.Lock()
+	defer lr.mux.Unlock()
+	list := make([]*Instance, 0, len(lr.instances))
+	for _, ins := range lr.instances {
+		list = append(list, ins)
+	}
+	return list
+}
+
+func (lr *listRegistry) put(ins *Instance) {
+	lr.mux.Lock()
+	defer lr.mux.Unlock()
+	cp := *ins
+	old, ok := lr.instances[ins.key()]
+	if ok && old.equal(&cp) {
+		return
+	}
+	lr.instances[ins.key()] = &cp
+	e := &Event{Type: EventAdd, Instance: &cp}
+	if ok {
+		e.Type = EventUpdate
+	}
+	lr.notify([]*Event{e})
+}
+
+func (lr *listRegistry) del(ins *Instance) bool {
+	lr.mux.Lock()
+	defer lr.mux.Unlock()
+	old, ok := lr.instances[ins.key()]
+	if !ok {
+		return false
+	}
+	delete(lr.instances, ins.key())
+	lr.notify([]*Event{{Type: EventRemove, Instance: old}})
+	return true
+}
+
+//set replaces every instance, watchers get the difference
+func (lr *listRegistry) set(list []*Instance) {
+	lr.mux.Lock()
+	defer lr.mux.Unlock()
+	instances := make(map[string]*Instance, len(list))
+	events := make([]*Event, 0)
+	for _, ins := range list {
+		key := ins.key()
+		instances[key] = ins
+		old, ok := lr.instances[key]
+		if !ok {
+			events = append(events, &Event{Type: EventAdd, Instance: ins})
+		} else if !old.equal(ins) {
+			events = append(events, &Event{Type: EventUpdate, Instance: ins})
+		}
+	}
+	for key, old := range lr.instances {
+		_, ok := instances[key]
+		if !ok {
+			events = append(events, &Event{Type: EventRemove, Instance: old})
+		}
+	}
+	lr.instances = instances
+	if len(events) > 0 {
+		lr.notify(events)
+	}
+}
+
+func (lr *listRegistry) notify(events []*Event) {
+	for w := range lr.watchers {
+		w.push(events)
+	}
+}
+
+func (lr *listRegistry) watch(ctx context.Context, group string) (<-chan *Event, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	w := newListWatcher(ctx, group)
+
+	lr.mux.Lock()
+	events := make([]*Event, 0, len(lr.instances))
+	for _, ins := range lr.instances {
+		events = append(events, &Event{Type: EventAdd, Instance: ins})
+	}
+	w.push(events)
+	lr.watchers[w] = struct{}{}
+	lr.mux.Unlock()
+
+	go w.run(func() {
+		lr.mux.Lock()
+		delete(lr.watchers, w)
+		lr.mux.Unlock()
+	})
+	return w.events, nil
+}
@@ -0,0 +1,88 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+//Package discovery registers server instances and watches the instances of
+//a group, backed by etcd, a static list or a local file.
+package discovery
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/lightning-go/lightning/selector"
+)
+
+var (
+	ErrInvalidInstance = errors.New("invalid instance")
+	ErrNotRegistered   = errors.New("instance not registered")
+)
+
+type Instance struct {
+	Name     string            `json:"name"`
+	Host     string            `json:"host"`
+	Group    string            `json:"group"`
+	Type     int               `json:"type"`
+	Weight   int               `json:"weight"`
+	Load     *selector.Load    `json:"load,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func (ins *Instance) valid() bool {
+	return ins != nil && len(ins.Name) > 0 && len(ins.Host) > 0
+}
+
+func (ins *Instance) key() string {
+	return ins.Group + "/" + ins.Name
+}
+
+func (ins *Instance) equal(other *Instance) bool {
+	return reflect.DeepEqual(ins, other)
+}
+
+func (ins *Instance) SessionData() *selector.SessionData {
+	return &selector.SessionData{
+		Host:   ins.Host,
+		Name:   ins.Name,
+		Type:   ins.Type,
+		Weight: ins.Weight,
+		Load:   ins.Load,
+	}
+}
+
+type EventType int
+
+const (
+	EventAdd EventType = iota
+	EventUpdate
+	EventRemove
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventAdd:
+		return "add"
+	case EventUpdate:
+		return "update"
+	case EventRemove:
+		return "remove"
+	}
+	return "unknown"
+}
+
+type Event struct {
+	Type     EventType
+	Instance *Instance
+}
+
+type Registry interface {
+	//Register publishes ins until Deregister, registering it again updates it,
+	//ttl is the lease in seconds where the backend supports one
+	Register(ins *Instance, ttl int64) error
+	Deregister(ins *Instance) error
+	//Watch sends an add for each instance of group, then the changes, until
+	//ctx is done and the channel closed
+	Watch(ctx context.Context, group string) (<-chan *Event, error)
+}
@@ -0,0 +1,54 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, ch <-chan *Event) *Event {
+	select {
+	case e, ok := <-ch:
+		if !ok {
+			t.Fatal("watch closed")
+		}
+		return e
+	case <-time.After(time.Second * 3):
+		t.Fatal("watch timeout")
+	}
+	return nil
+}
+
+func TestStaticRegistry(t *testing.T) {
+	sr := NewStaticRegistry(
+		&Instance{Name: "a", Host: "127.0.0.1:1", Group: "g"},
+		&Instance{Name: "b", Host: "127.0.0.1:2", Group: "other"})
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := sr.Watch(ctx, "g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//only the instances of the group are seen
+	e := nextEvent(t, ch)
+	if e.Type != EventAdd || e.Instance.Name != "a" {
+		t.Fatal(e.Type, e.Instance)
+	}
+	sr.Register(&Instance{Name: "a", Host: "127.0.0.1:1", Group: "g", Weight: 3}, 0)
+	e = nextEvent(t, ch)
+	if e.Type != EventUpdate || e.Instance.Weight != 3 {
+		t.Fatal(e.Type, e.Instance)
+	}
+	sr.Deregister(&Instance{Name: "a", Group: "g"})
+	e = nextEvent(t, ch)
+	if e.Type != EventRemove || e.Instance.Name != "a" {
+		t.Fatal(e.Type, e.Instance)
+	}
+	cancel()
+	for range ch {
+	}
+}
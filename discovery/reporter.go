@@ -0,0 +1,115 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package discovery
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lightning-go/lightning/logger"
+	"github.com/lightning-go/lightning/selector"
+)
+
+const DefaultReportInterval = time.Second * 3
+
+//Reporter registers an instance again at every interval with its live load,
+//watchers see each report as an update
+type Reporter struct {
+	registry     Registry
+	ins          Instance
+	ttl          int64
+	interval     time.Duration
+	loadCallback func() *selector.Load
+	mux          sync.Mutex
+	done         chan struct{}
+}
+
+func NewReporter(registry Registry, ins *Instance, ttl int64) *Reporter {
+	if registry == nil || !ins.valid() {
+		return nil
+	}
+	return &Reporter{
+		registry: registry,
+		ins:      *ins,
+		ttl:      ttl,
+		interval: DefaultReportInterval,
+	}
+}
+
+//SetInterval should stay below the load ttl of the selectors
+func (r *Reporter) SetInterval(interval time.Duration) {
+	if interval > 0 {
+		r.interval = interval
+	}
+}
+
+//SetLoadCallback returns the load to report, the weight follows its sessions
+func (r *Reporter) SetLoadCallback(cb func() *selector.Load) {
+	r.loadCallback = cb
+}
+
+func (r *Reporter) report() error {
+	ins := r.ins
+	if r.loadCallback != nil {
+		load := r.loadCallback()
+		if load != nil {
+			//a new time makes every report a change
+			load.Time = time.Now().UnixNano() / int64(time.Millisecond)
+			ins.Load = load
+			ins.Weight = load.Sessions
+		}
+	}
+	return r.registry.Register(&ins, r.ttl)
+}
+
+//Start registers the instance and reports until Stop
+func (r *Reporter) Start() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.done != nil {
+		return nil
+	}
+	err := r.report()
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	r.done = done
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				//no report once Stop deregistered
+				r.mux.Lock()
+				var err error
+				if r.done == done {
+					err = r.report()
+				}
+				r.mux.Unlock()
+				if err != nil {
+					logger.Error(err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+//Stop ends the reports and deregisters the instance
+func (r *Reporter) Stop() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.done == nil {
+		return nil
+	}
+	close(r.done)
+	r.done = nil
+	return r.registry.Deregister(&r.ins)
+}
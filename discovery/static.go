@@ -0,0 +1,72 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lightning-go/lightning/conf"
+)
+
+//StaticRegistry serves a fixed list, Register and Deregister only change the
+//view of this process
+type StaticRegistry struct {
+	*listRegistry
+}
+
+func NewStaticRegistry(instances ...*Instance) *StaticRegistry {
+	sr := &StaticRegistry{
+		listRegistry: newListRegistry(),
+	}
+	list := make([]*Instance, 0, len(instances))
+	for _, ins := range instances {
+		if ins.valid() {
+			cp := *ins
+			list = append(list, &cp)
+		}
+	}
+	sr.set(list)
+	return sr
+}
+
+//NewConfigRegistry lists the remotes of cfg under the group of their config
+func NewConfigRegistry(cfg *conf.ServerConfig) *StaticRegistry {
+	list := make([]*Instance, 0)
+	if cfg != nil {
+		for _, name := range cfg.Remotes {
+			rCfg := conf.GetSrvCfg(name)
+			if rCfg == nil {
+				continue
+			}
+			list = append(list, &Instance{
+				Name:  rCfg.Name,
+				Host:  fmt.Sprintf("%v:%v", rCfg.Host, rCfg.Port),
+				Group: rCfg.Group,
+			})
+		}
+	}
+	return NewStaticRegistry(list...)
+}
+
+func (sr *StaticRegistry) Register(ins *Instance, ttl int64) error {
+	if !ins.valid() {
+		return ErrInvalidInstance
+	}
+	sr.put(ins)
+	return nil
+}
+
+func (sr *StaticRegistry) Deregister(ins *Instance) error {
+	if ins == nil || !sr.del(ins) {
+		return ErrNotRegistered
+	}
+	return nil
+}
+
+func (sr *StaticRegistry) Watch(ctx context.Context, group string) (<-chan *Event, error) {
+	return sr.watch(ctx, group)
+}
@@ -79,6 +79,10 @@ func (e *Etcd) init() bool {
 	return true
 }
 
+func (e *Etcd) GetClient() *clientv3.Client {
+	return e.client
+}
+
 func (e *Etcd) Get(key string, f func(k, v []byte)) {
 	if f == nil {
 		return
@@ -30,6 +30,7 @@ const (
 const (
 	ETCD_LOGIC_PATH = "/game/logic"
 	ETCD_GATE_PATH  = "/game/gate"
+	ETCD_ROUTE_PATH = "/game/route"
 )
 
 func MarshalDataEx(v interface{}) []byte {
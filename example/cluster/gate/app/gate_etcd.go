@@ -6,19 +6,22 @@
 package app
 
 import (
+	"context"
 	"fmt"
-	"strings"
 	"time"
 
+	"github.com/lightning-go/lightning/cluster"
 	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/discovery"
 	"github.com/lightning-go/lightning/etcd"
 	"github.com/lightning-go/lightning/example/cluster/common"
 	"github.com/lightning-go/lightning/logger"
-	"github.com/lightning-go/lightning/selector"
 )
 
 func (gs *GateServer) initEtcd() {
-	gs.watch()
+	if gs.watch() {
+		gs.initRouter()
+	}
 }
 
 func (gs *GateServer) watch() bool {
@@ -44,34 +47,27 @@ func (gs *GateServer) watch() bool {
 		return false
 	}
 
-	key := fmt.Sprintf("%v/%v/%v/", conf.GetServerName(), common.ETCD_LOGIC_PATH, group)
-	gs.etcdMgr.Watch(key, func(k, v []byte) {
-		sd := &selector.SessionData{}
-		err := common.Unmarshal(v, sd)
-		if err != nil {
-			logger.Error(err)
-			return
-		}
-
-		gs.serveSelector.AddRemoteData(sd, func(data *selector.SessionData) bool {
-			return gs.initRemote(data)
-		})
-
-	}, func(k []byte) {
-		logger.Tracef("watch delete data: %s", k)
-
-		key := string(k)
-		idx := strings.LastIndex(key, "/")
-		if idx == -1 {
-			logger.Errorf("key %v error", key)
-			return
-		}
-		key = key[idx+1:]
-		gs.serveSelector.DelRemoteData(key)
-
-		logger.Debugf("watch delete key: %s", key)
-
-	})
-
+	prefix := fmt.Sprintf("%v%v", conf.GetServerName(), common.ETCD_LOGIC_PATH)
+	registry := discovery.NewEtcdRegistry(gs.etcdMgr, prefix)
+	if registry == nil {
+		logger.Error("create discovery failed")
+		return false
+	}
+	err := gs.Watch(context.Background(), registry, group)
+	if err != nil {
+		logger.Error(err)
+		return false
+	}
 	return true
 }
+
+//sessions keep their logic node across gate restarts
+func (gs *GateServer) initRouter() {
+	key := fmt.Sprintf("%v%v", conf.GetServerName(), common.ETCD_ROUTE_PATH)
+	store := cluster.NewEtcdStore(gs.etcdMgr, key)
+	if store == nil {
+		return
+	}
+	store.SetTTL(int64(routeTTL / time.Second))
+	gs.SetRouter(cluster.NewRegistry(store))
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/discovery"
 	"github.com/lightning-go/lightning/etcd"
 	"github.com/lightning-go/lightning/example/cluster/common"
 	"github.com/lightning-go/lightning/example/cluster/core"
@@ -47,30 +48,29 @@ func (ls *LogicServer) keepOnline() {
 	cfg := ls.GetCfg()
 	group := utils.IF(cfg != nil, cfg.Group, "group").(string)
 
-	key := fmt.Sprintf("%v/%v/%v/%v", conf.GetServerName(), common.ETCD_LOGIC_PATH, group, ls.Name())
-	name := ls.Name()
-	host := ls.Host()
-
-	go func() {
-		for {
-			weight := int(core.GetClientCount())
-			sd := &selector.SessionData{
-				Host:   host,
-				Name:   name,
-				Type:   common.ST_LOGIC,
-				Weight: weight,
-			}
-			d, err := common.MarshalData(sd)
-			if err != nil {
-				logger.Error(err)
-				goto RETRY
-			}
-
-			ls.etcdMgr.Put(key, string(d), 5)
+	prefix := fmt.Sprintf("%v%v", conf.GetServerName(), common.ETCD_LOGIC_PATH)
+	registry := discovery.NewEtcdRegistry(ls.etcdMgr, prefix)
+	if registry == nil {
+		logger.Error("create discovery failed")
+		return
+	}
+	ins := &discovery.Instance{
+		Name:  ls.Name(),
+		Host:  ls.Host(),
+		Group: group,
+		Type:  common.ST_LOGIC,
+	}
 
-		RETRY:
-			time.Sleep(time.Second * 3)
+	//the gates pick the logic of the least load
+	reporter := discovery.NewReporter(registry, ins, 5)
+	reporter.SetLoadCallback(func() *selector.Load {
+		return &selector.Load{
+			Sessions: int(core.GetClientCount()),
+			Queue:    ls.QueueLen(),
 		}
-	}()
-
+	})
+	err := reporter.Start()
+	if err != nil {
+		logger.Error(err)
+	}
 }
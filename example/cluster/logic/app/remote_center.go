@@ -6,7 +6,10 @@
 package app
 
 import (
+	"time"
+
 	"github.com/lightning-go/lightning/module"
+	"github.com/lightning-go/lightning/network"
 	"github.com/lightning-go/lightning/defs"
 	"github.com/lightning-go/lightning/logger"
 	"github.com/lightning-go/lightning/utils"
@@ -50,6 +53,13 @@ func (ls *LogicServer) initRemoteCenter() {
 		return
 	}
 	center.SetCodec(&module.HeadCodec{})
+	//session events survive a center restart
+	outbox := network.NewOutbox(4096, network.OverflowDropOldest)
+	outbox.SetTTL(time.Minute)
+	outbox.SetDropCallback(func(packet defs.IPacket, reason error) {
+		logger.Warnf("center packet dropped: %v", reason)
+	})
+	center.SetOutbox(outbox)
 	center.SetConnCallback(ls.onCenterConn)
 	center.SetMsgCallback(ls.onCenterMsg)
 }
@@ -0,0 +1,73 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/example/rpc/service"
+	"github.com/lightning-go/lightning/logger"
+	"github.com/lightning-go/lightning/module"
+	"github.com/lightning-go/lightning/network"
+	"github.com/lightning-go/lightning/rpc"
+	"github.com/lightning-go/lightning/utils"
+)
+
+var addr = flag.String("a", "127.0.0.1:21100", "listen address")
+
+type LoginService struct{}
+
+func (ls *LoginService) Login(session defs.ISession, req *service.LoginReq, resp *service.LoginResp) int {
+	if req.Password == "" {
+		return 1
+	}
+	resp.Token = fmt.Sprintf("%v-%v", req.Account, time.Now().Unix())
+	return 0
+}
+
+func (ls *LoginService) Logout(session defs.ISession, req *service.LogoutReq) int {
+	logger.Infof("logout %v", req.Token)
+	return 0
+}
+
+func main() {
+	flag.Parse()
+
+	sf := utils.NewServiceFactory()
+	service.RegisterLoginService(sf, &LoginService{})
+
+	srv := network.NewTcpServer(*addr, "rpc-server", 0)
+	srv.SetCodec(module.NewHeadCodec())
+	srv.SetMsgCallback(func(conn defs.IConnection, packet defs.IPacket) {
+		sf.OnServiceHandle(network.NewSession(conn, packet.GetSessionId(), sf.OnServiceHandle), packet)
+	})
+	srv.Serve()
+
+	client := network.NewTcpClient("rpc-client", *addr)
+	client.SetCodec(module.NewHeadCodec())
+	if client.Connect() == nil {
+		logger.Error("connect failed")
+		return
+	}
+
+	stub := service.NewLoginServiceClient(rpc.NewClient(client))
+	resp, err := stub.Login(context.Background(), &service.LoginReq{Account: "jason", Password: "123"})
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	fmt.Println("login token:", resp.Token)
+
+	_, err = stub.Login(context.Background(), &service.LoginReq{Account: "jason"})
+	fmt.Println("empty password status:", rpc.Status(err))
+
+	stub.Logout(&service.LogoutReq{Token: resp.Token})
+	time.Sleep(100 * time.Millisecond)
+}
@@ -0,0 +1,63 @@
+// Code generated by lightning-gen. DO NOT EDIT.
+
+package service
+
+import (
+	"context"
+
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/rpc"
+	"github.com/lightning-go/lightning/utils"
+)
+
+// method ids of LoginService
+const (
+	LoginServiceLogin  = "Login"
+	LoginServiceLogout = "Logout"
+)
+
+// RegisterLoginService adds the methods of svc to sf, dispatched without reflection
+func RegisterLoginService(sf *utils.ServiceFactory, svc LoginService) {
+	sf.RegisterMethods(
+		&utils.MethodDesc{
+			Name: LoginServiceLogin,
+			NewReq: func() interface{} {
+				return new(LoginReq)
+			},
+			NewReply: func() interface{} {
+				return new(LoginResp)
+			},
+			Invoke: func(session defs.ISession, req, reply interface{}) int {
+				return svc.Login(session, req.(*LoginReq), reply.(*LoginResp))
+			},
+		},
+		&utils.MethodDesc{
+			Name: LoginServiceLogout,
+			NewReq: func() interface{} {
+				return new(LogoutReq)
+			},
+			Invoke: func(session defs.ISession, req, reply interface{}) int {
+				return svc.Logout(session, req.(*LogoutReq))
+			},
+		},
+	)
+}
+
+// LoginServiceClient calls LoginService through an rpc.Client
+type LoginServiceClient struct {
+	client *rpc.Client
+}
+
+func NewLoginServiceClient(client *rpc.Client) *LoginServiceClient {
+	return &LoginServiceClient{client: client}
+}
+
+func (c *LoginServiceClient) Login(ctx context.Context, req *LoginReq) (*LoginResp, error) {
+	resp := new(LoginResp)
+	err := c.client.CallCtx(ctx, LoginServiceLogin, req, resp)
+	return resp, err
+}
+
+func (c *LoginServiceClient) Logout(req *LogoutReq) error {
+	return c.client.Notify(LoginServiceLogout, req)
+}
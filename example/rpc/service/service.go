@@ -0,0 +1,30 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package service
+
+import (
+	"github.com/lightning-go/lightning/defs"
+)
+
+//go:generate go run github.com/lightning-go/lightning/cmd/lightning-gen -type LoginService
+
+type LoginReq struct {
+	Account  string
+	Password string
+}
+
+type LoginResp struct {
+	Token string
+}
+
+type LogoutReq struct {
+	Token string
+}
+
+type LoginService interface {
+	Login(session defs.ISession, req *LoginReq, resp *LoginResp) int
+	Logout(session defs.ISession, req *LogoutReq) int
+}
@@ -0,0 +1,182 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package gateway
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/logger"
+	"github.com/lightning-go/lightning/module"
+	"github.com/lightning-go/lightning/network"
+	"github.com/lightning-go/lightning/resilience"
+	"github.com/lightning-go/lightning/selector"
+	"github.com/lightning-go/lightning/utils"
+)
+
+//replies are matched to the requests by session and sequence
+type replyKey struct {
+	sessionId string
+	seq       uint64
+}
+
+type pendingReply struct {
+	report func(error)
+	timer  *time.Timer
+}
+
+//Backend is the connection of the gateway to a logic server
+type Backend struct {
+	*network.TcpClient
+	sd         *selector.SessionData
+	gw         *Gateway
+	sessions   sync.Map
+	client     *resilience.Client
+	pendingMux sync.Mutex
+	pending    map[replyKey]*pendingReply
+}
+
+func newBackend(gw *Gateway, sd *selector.SessionData) *Backend {
+	b := &Backend{
+		TcpClient: network.NewTcpClient(gw.Name(), sd.Host),
+		sd:        sd,
+		gw:        gw,
+		pending:   make(map[replyKey]*pendingReply),
+	}
+	if b.TcpClient == nil {
+		return nil
+	}
+	b.SetTimeout(gw.backendTimeout)
+	b.SetRetry(false)
+	codec := gw.backendCodec
+	if codec == nil {
+		codec = module.NewHeadCodec()
+	}
+	b.SetCodec(codec)
+	b.SetConnCallback(b.onConn)
+	b.SetMsgCallback(b.onMsg)
+	var breaker *resilience.Breaker
+	if gw.breakers != nil {
+		breaker = gw.breakers.Get(sd.Name)
+	}
+	b.client = resilience.NewClient(b.TcpClient, breaker)
+	return b
+}
+
+func (b *Backend) Data() *selector.SessionData {
+	return b.sd
+}
+
+//Client guards the calls to the backend with its breaker
+func (b *Backend) Client() *resilience.Client {
+	return b.client
+}
+
+//BackendName is the name the backend registered with
+func (b *Backend) BackendName() string {
+	return b.sd.Name
+}
+
+//SessionCount returns the number of session routes to the backend from this gateway
+func (b *Backend) SessionCount() int {
+	n := 0
+	b.sessions.Range(func(k, v interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func (b *Backend) onConn(conn defs.IConnection) {
+	closed := conn.IsClosed()
+	logger.Tracef("%s -> %s backend %s is %s",
+		conn.LocalAddr(), b.sd.Name, conn.RemoteAddr(),
+		utils.IF(closed, "down", "up"))
+	if closed {
+		b.failPending(module.ErrConnClosed)
+		b.gw.onBackendLost(b)
+	} else {
+		b.gw.onBackendConn(b)
+	}
+}
+
+//replies are written to the latest connection of the session key the backend addressed
+func (b *Backend) onMsg(conn defs.IConnection, packet defs.IPacket) {
+	logger.Tracef("onBackendMsg: %v - %v - %v", packet.GetSessionId(), packet.GetId(), string(packet.GetData()))
+
+	if packet.GetSequence() != 0 {
+		b.settle(replyKey{packet.GetSessionId(), packet.GetSequence()}, nil, nil)
+	}
+	session := b.gw.GetSession(packet.GetSessionId())
+	if session == nil {
+		return
+	}
+	if b.gw.backendService.OnServiceHandle(session, packet) {
+		return
+	}
+	session.WritePacket(packet)
+}
+
+//forward sends the packet through the breaker of the backend, requests, the
+//packets with a sequence, fail when the connection is lost before the reply
+//or the reply is later than the reply timeout
+func (b *Backend) forward(packet defs.IPacket) error {
+	if b.gw.breakers == nil {
+		b.SendPacket(packet)
+		return nil
+	}
+	var track func(func(error))
+	if packet.GetSequence() != 0 && b.gw.replyTimeout > 0 {
+		key := replyKey{packet.GetSessionId(), packet.GetSequence()}
+		track = func(report func(error)) {
+			b.await(key, report)
+		}
+	}
+	return b.client.Forward(packet, track)
+}
+
+func (b *Backend) await(key replyKey, report func(error)) {
+	p := &pendingReply{report: report}
+	b.pendingMux.Lock()
+	defer b.pendingMux.Unlock()
+	//a sequence sent again replaces the request, the first says nothing of the backend
+	old, ok := b.pending[key]
+	if ok {
+		old.timer.Stop()
+		old.report(context.Canceled)
+	}
+	p.timer = time.AfterFunc(b.gw.replyTimeout, func() {
+		b.settle(key, p, module.ErrTimeout)
+	})
+	b.pending[key] = p
+}
+
+//settle reports the request pending for key, p nil settles any request
+func (b *Backend) settle(key replyKey, p *pendingReply, err error) {
+	b.pendingMux.Lock()
+	cur, ok := b.pending[key]
+	if !ok || p != nil && cur != p {
+		b.pendingMux.Unlock()
+		return
+	}
+	delete(b.pending, key)
+	b.pendingMux.Unlock()
+	cur.timer.Stop()
+	cur.report(err)
+}
+
+func (b *Backend) failPending(err error) {
+	b.pendingMux.Lock()
+	pending := b.pending
+	b.pending = make(map[replyKey]*pendingReply)
+	b.pendingMux.Unlock()
+	for _, p := range pending {
+		p.timer.Stop()
+		p.report(err)
+	}
+}
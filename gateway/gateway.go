@@ -0,0 +1,641 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+//Package gateway forwards the packets of client sessions to logic backends.
+//A session is routed by its key to a backend on its first packet, picked by
+//the selector strategy of the packet route or owned in the cluster registry,
+//and the backend is told with the disconnect status when the client goes away.
+package gateway
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lightning-go/lightning/cluster"
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/discovery"
+	"github.com/lightning-go/lightning/logger"
+	"github.com/lightning-go/lightning/network"
+	"github.com/lightning-go/lightning/resilience"
+	"github.com/lightning-go/lightning/selector"
+	"github.com/lightning-go/lightning/utils"
+)
+
+const (
+	DefaultRateLimit      = 100
+	DefaultRateWindow     = time.Second
+	DefaultBackendTimeout = time.Second * 3
+	DefaultReplyTimeout   = time.Second * 3
+	DefaultTouchInterval  = time.Minute
+)
+
+type rateKey struct{}
+
+//rateCounter is shared by the reads and the handling of the session packets
+type rateCounter struct {
+	mux   sync.Mutex
+	start time.Time
+	count int64
+}
+
+//add counts a packet and returns the count of the window
+func (rc *rateCounter) add(now time.Time, window time.Duration) int64 {
+	rc.mux.Lock()
+	defer rc.mux.Unlock()
+	if now.Sub(rc.start) > window {
+		rc.start = now
+		rc.count = 0
+	}
+	rc.count++
+	return rc.count
+}
+
+//assignment is the backend a session key is routed to
+type assignment struct {
+	backend *Backend
+	touched int64
+}
+
+type Gateway struct {
+	*network.Server
+	rules          []*routeRule
+	router         *cluster.Registry
+	breakers       *resilience.Group
+	backends       sync.Map
+	routes         sync.Map
+	backendService *utils.ServiceFactory
+	backendCodec   defs.ICodec
+	backendTimeout time.Duration
+	replyTimeout   time.Duration
+	rateLimit      int64
+	rateWindow     time.Duration
+	disconnStatus  int
+	closeOnLost    bool
+	touchInterval  time.Duration
+	keyMux         sync.RWMutex
+	keys           map[string]string
+	conns          map[string]string
+
+	sessionKeyCallback   func(defs.ISession) string
+	authCallback         func(*Backend) bool
+	backendCallback      func(*Backend, bool)
+	sessionOpenCallback  func(defs.ISession)
+	sessionCloseCallback func(defs.ISession)
+	sessionRouteCallback func(defs.ISession, *Backend)
+}
+
+func NewGateway(name string, confPath ...string) *Gateway {
+	gw := &Gateway{
+		Server:         network.NewServer(name, confPath...),
+		rules:          []*routeRule{{strategy: selector.NewWeightSelector()}},
+		backendService: utils.NewServiceFactory(),
+		backendTimeout: DefaultBackendTimeout,
+		replyTimeout:   DefaultReplyTimeout,
+		rateLimit:      DefaultRateLimit,
+		rateWindow:     DefaultRateWindow,
+		closeOnLost:    true,
+		touchInterval:  DefaultTouchInterval,
+		keys:           make(map[string]string),
+		conns:          make(map[string]string),
+	}
+	gw.SetMsgCallback(gw.onMsg)
+	gw.SetNewConnCallback(gw.onNewConn)
+	gw.SetDisConnCallback(gw.onDisConn)
+	return gw
+}
+
+//SetStrategy picks the backend of sessions not routed yet for the packets
+//outside the route strategies, keyed by the session key, the least weighted
+//in turn by default
+func (gw *Gateway) SetStrategy(strategy selector.Selector) {
+	if strategy == nil {
+		return
+	}
+	gw.rules[len(gw.rules)-1].strategy = strategy
+	gw.filterStrategy()
+	gw.RangeBackend(func(name string, b *Backend) bool {
+		gw.setBackend(b.sd)
+		return true
+	})
+}
+
+//SetBreakers keeps the backends of an open circuit out of the routing, the
+//breakers are fed by the forwarded packets and the calls made through
+//Backend.Client, and kept by backend name across reconnects
+func (gw *Gateway) SetBreakers(breakers *resilience.Group) {
+	gw.breakers = breakers
+	gw.filterStrategy()
+}
+
+func (gw *Gateway) filterStrategy() {
+	for _, r := range gw.rules {
+		f, ok := r.strategy.(selector.Filterable)
+		if !ok {
+			continue
+		}
+		if gw.breakers == nil {
+			f.SetFilterCallback(nil)
+			continue
+		}
+		f.SetFilterCallback(gw.breakers.Filter)
+	}
+}
+
+func (gw *Gateway) isReady(name string) bool {
+	return gw.breakers == nil || gw.breakers.Ready(name)
+}
+
+//SetRouter keeps sessions on the backend owning them in the cluster registry,
+//the strategy of the route picks the owner of new sessions. The sessions of
+//the route strategies are kept by prefix#key
+func (gw *Gateway) SetRouter(router *cluster.Registry) {
+	gw.router = router
+	if router == nil {
+		return
+	}
+	router.SetPickCallback(func(key string) (string, bool) {
+		r, key := gw.parseKey(key)
+		sd := r.strategy.Select(key)
+		if sd == nil {
+			return "", false
+		}
+		return sd.Name, true
+	})
+	router.SetAliveCallback(func(name string) bool {
+		return gw.GetBackend(name) != nil && gw.isReady(name)
+	})
+}
+
+//SetTouchInterval is how often the registry assignment of an active session is
+//renewed, zero never renews it
+func (gw *Gateway) SetTouchInterval(interval time.Duration) {
+	gw.touchInterval = interval
+}
+
+//SetSessionKeyCallback returns the key sessions are routed by, stable across
+//reconnects, e.g. the account id set once authenticated. Sessions without a
+//key yet are served by the gateway services only. The connection id is the
+//key by default, so a reconnect may be routed to another backend
+func (gw *Gateway) SetSessionKeyCallback(cb func(defs.ISession) string) {
+	gw.sessionKeyCallback = cb
+}
+
+//SetBackendCodec sets the codec of the backend connections, HeadCodec by default
+func (gw *Gateway) SetBackendCodec(codec defs.ICodec) {
+	gw.backendCodec = codec
+}
+
+func (gw *Gateway) SetBackendTimeout(timeout time.Duration) {
+	gw.backendTimeout = timeout
+}
+
+//SetReplyTimeout is how long the breakers wait for the backend to answer a
+//forwarded packet carrying a sequence, late replies count as timeouts, zero
+//counts the writes only
+func (gw *Gateway) SetReplyTimeout(timeout time.Duration) {
+	gw.replyTimeout = timeout
+}
+
+//RegisterBackendService handles backend packets on the gateway instead of forwarding them
+func (gw *Gateway) RegisterBackendService(rcvr interface{}, cb ...defs.ParseMethodNameCallback) {
+	gw.backendService.Register(rcvr, cb...)
+}
+
+//SetRateLimit closes sessions sending more than limit packets in window, zero disables
+func (gw *Gateway) SetRateLimit(limit int64, window time.Duration) {
+	gw.rateLimit = limit
+	gw.rateWindow = window
+}
+
+//SetDisconnStatus is the status of the packet telling the backend a session
+//went away, zero sends none
+func (gw *Gateway) SetDisconnStatus(status int) {
+	gw.disconnStatus = status
+}
+
+//SetCloseOnBackendLost closes the sessions of a lost backend, otherwise they
+//are routed again on their next packet
+func (gw *Gateway) SetCloseOnBackendLost(v bool) {
+	gw.closeOnLost = v
+}
+
+//SetAuthCallback is called once a backend is connected, e.g. to send the
+//credentials of the gateway, false closes the backend
+func (gw *Gateway) SetAuthCallback(cb func(*Backend) bool) {
+	gw.authCallback = cb
+}
+
+//SetBackendCallback is called when a backend becomes available or is lost
+func (gw *Gateway) SetBackendCallback(cb func(backend *Backend, up bool)) {
+	gw.backendCallback = cb
+}
+
+func (gw *Gateway) SetSessionOpenCallback(cb func(defs.ISession)) {
+	gw.sessionOpenCallback = cb
+}
+
+func (gw *Gateway) SetSessionCloseCallback(cb func(defs.ISession)) {
+	gw.sessionCloseCallback = cb
+}
+
+func (gw *Gateway) SetSessionRouteCallback(cb func(defs.ISession, *Backend)) {
+	gw.sessionRouteCallback = cb
+}
+
+//Watch connects to the instances of group found by the registry and removes
+//them once deregistered, until ctx is done
+func (gw *Gateway) Watch(ctx context.Context, registry discovery.Registry, group string) error {
+	events, err := registry.Watch(ctx, group)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for e := range events {
+			logger.Tracef("discovery %v %v: %v", e.Type, e.Instance.Name, e.Instance.Host)
+			switch e.Type {
+			case discovery.EventAdd, discovery.EventUpdate:
+				gw.AddBackend(e.Instance.SessionData())
+			case discovery.EventRemove:
+				gw.DelBackend(e.Instance.Name)
+				gw.migrate(e.Instance.Name)
+			}
+		}
+	}()
+	return nil
+}
+
+//AddBackend connects to the backend, a known backend takes the new weight
+//and load, or is replaced when its host changed
+func (gw *Gateway) AddBackend(sd *selector.SessionData) bool {
+	if sd == nil {
+		return false
+	}
+	logger.Debugf("backend - name: %v, host: %v, type: %v, weight: %v",
+		sd.Name, sd.Host, sd.Type, sd.Weight)
+
+	old := gw.GetBackend(sd.Name)
+	if old != nil {
+		if old.sd.Host == sd.Host {
+			gw.setBackend(sd)
+			return true
+		}
+		gw.DelBackend(sd.Name)
+	}
+
+	b := newBackend(gw, sd)
+	if b == nil {
+		return false
+	}
+	b.Connect()
+	return b.IsWorking()
+}
+
+func (gw *Gateway) DelBackend(name string) {
+	v, ok := gw.backends.Load(name)
+	if !ok {
+		return
+	}
+	v.(*Backend).Close()
+}
+
+func (gw *Gateway) GetBackend(name string) *Backend {
+	v, ok := gw.backends.Load(name)
+	if !ok {
+		return nil
+	}
+	return v.(*Backend)
+}
+
+func (gw *Gateway) RangeBackend(f func(string, *Backend) bool) {
+	gw.backends.Range(func(k, v interface{}) bool {
+		return f(k.(string), v.(*Backend))
+	})
+}
+
+//GetRoute returns the backend the packets of id from the session key are routed to
+func (gw *Gateway) GetRoute(id, key string) *Backend {
+	a := gw.getAssignment(gw.ruleOf(id).key(key))
+	if a == nil {
+		return nil
+	}
+	return a.backend
+}
+
+func (gw *Gateway) getAssignment(key string) *assignment {
+	v, ok := gw.routes.Load(key)
+	if !ok {
+		return nil
+	}
+	return v.(*assignment)
+}
+
+//GetSession returns the latest connection of the session key
+func (gw *Gateway) GetSession(key string) defs.ISession {
+	gw.keyMux.RLock()
+	connId, ok := gw.conns[key]
+	gw.keyMux.RUnlock()
+	if !ok {
+		connId = key
+	}
+	return gw.GetConn(connId)
+}
+
+//Logout forgets the backends of the session key, e.g. once the player logged
+//out. Disconnects keep the registry assignments for a reconnect until their ttl
+func (gw *Gateway) Logout(key string) {
+	for _, r := range gw.rules {
+		rk := r.key(key)
+		a := gw.getAssignment(rk)
+		if a != nil {
+			gw.routes.Delete(rk)
+			a.backend.sessions.Delete(rk)
+		}
+		r.forget(key)
+		if gw.router == nil {
+			continue
+		}
+		err := gw.router.Release(rk)
+		if err != nil {
+			logger.Warnf("release session %v failed: %v", rk, err)
+		}
+	}
+}
+
+func (gw *Gateway) sessionKey(session defs.ISession) string {
+	if gw.sessionKeyCallback == nil {
+		return session.GetSessionId()
+	}
+	return gw.sessionKeyCallback(session)
+}
+
+//bind makes the connection the one the packets to its key are written to,
+//the latest connection of a key takes them over
+func (gw *Gateway) bind(connId, key string) {
+	gw.keyMux.RLock()
+	bound := gw.keys[connId] == key
+	gw.keyMux.RUnlock()
+	if bound {
+		return
+	}
+	gw.keyMux.Lock()
+	old, ok := gw.keys[connId]
+	if ok && gw.conns[old] == connId {
+		delete(gw.conns, old)
+	}
+	gw.keys[connId] = key
+	gw.conns[key] = connId
+	gw.keyMux.Unlock()
+}
+
+//unbind forgets the connection, true when it was the latest of its key
+func (gw *Gateway) unbind(connId string) (string, bool) {
+	gw.keyMux.Lock()
+	defer gw.keyMux.Unlock()
+	key, ok := gw.keys[connId]
+	if !ok {
+		return "", false
+	}
+	delete(gw.keys, connId)
+	if gw.conns[key] != connId {
+		return key, false
+	}
+	delete(gw.conns, key)
+	return key, true
+}
+
+func (gw *Gateway) onBackendConn(b *Backend) {
+	if gw.authCallback != nil && !gw.authCallback(b) {
+		logger.Warnf("backend %v auth failed", b.sd.Name)
+		b.Close()
+		return
+	}
+	gw.backends.Store(b.sd.Name, b)
+	gw.setBackend(b.sd)
+	if gw.backendCallback != nil {
+		gw.backendCallback(b, true)
+	}
+}
+
+func (gw *Gateway) onBackendLost(b *Backend) {
+	v, ok := gw.backends.Load(b.sd.Name)
+	if !ok || v.(*Backend) != b {
+		return
+	}
+	gw.backends.Delete(b.sd.Name)
+	gw.delBackend(b.sd.Name)
+
+	b.sessions.Range(func(k, v interface{}) bool {
+		rk := k.(string)
+		a := gw.getAssignment(rk)
+		if a != nil && a.backend == b {
+			gw.routes.Delete(rk)
+		}
+		if gw.router != nil {
+			gw.router.Invalidate(rk)
+		}
+		if gw.closeOnLost {
+			session := gw.GetSession(v.(string))
+			if session != nil {
+				session.Close()
+			}
+		}
+		return true
+	})
+	if gw.backendCallback != nil {
+		gw.backendCallback(b, false)
+	}
+}
+
+func (gw *Gateway) migrate(name string) {
+	if gw.router == nil {
+		return
+	}
+	n, err := gw.router.Migrate(name)
+	if err != nil {
+		logger.Error(err)
+	}
+	logger.Debugf("%v sessions of %v migrated", n, name)
+}
+
+func (gw *Gateway) onNewConn(conn defs.IConnection) {
+	session := gw.GetConn(conn.GetId())
+	if session == nil {
+		return
+	}
+	session.SetContext(rateKey{}, &rateCounter{start: time.Now()})
+	if gw.sessionOpenCallback != nil {
+		gw.sessionOpenCallback(session)
+	}
+}
+
+//the backends are told when the latest connection of a key goes away, the
+//registry keeps the assignments so a reconnect reaches the same backends
+func (gw *Gateway) onDisConn(conn defs.IConnection) {
+	session := gw.GetConn(conn.GetId())
+	if session != nil && gw.sessionCloseCallback != nil {
+		gw.sessionCloseCallback(session)
+	}
+
+	key, ok := gw.unbind(conn.GetId())
+	if !ok {
+		return
+	}
+	notified := make(map[*Backend]bool)
+	for _, r := range gw.rules {
+		rk := r.key(key)
+		a := gw.getAssignment(rk)
+		if a == nil {
+			continue
+		}
+		gw.routes.Delete(rk)
+		b := a.backend
+		b.sessions.Delete(rk)
+
+		//the backend learns of the disconnect even with its circuit open
+		if gw.disconnStatus != 0 && !notified[b] {
+			notified[b] = true
+			p := &defs.Packet{}
+			p.SetSessionId(key)
+			p.SetData(utils.NullData)
+			p.SetStatus(gw.disconnStatus)
+			b.SendPacket(p)
+		}
+		if gw.router != nil {
+			err := gw.router.Touch(rk)
+			if err != nil {
+				logger.Warnf("touch session %v failed: %v", rk, err)
+			}
+		}
+	}
+}
+
+func (gw *Gateway) onMsg(conn defs.IConnection, packet defs.IPacket) {
+	logger.Tracef("onMsg %v, %s", conn.GetId(), packet.GetData())
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Error(err)
+			logger.Error(string(debug.Stack()))
+		}
+	}()
+
+	connId := conn.GetId()
+	session := gw.GetConn(connId)
+	if session == nil {
+		return
+	}
+	if !gw.isMsgValid(session) {
+		session.Close()
+		return
+	}
+
+	packet.SetSessionId(connId)
+	if gw.OnServiceHandle(session, packet) {
+		return
+	}
+	key := gw.sessionKey(session)
+	if len(key) == 0 {
+		logger.Warnf("session %v has no key, packet %v dropped", connId, packet.GetId())
+		return
+	}
+	gw.bind(connId, key)
+	packet.SetSessionId(key)
+	gw.forward(session, key, packet)
+}
+
+func (gw *Gateway) isMsgValid(session defs.ISession) bool {
+	if gw.rateLimit <= 0 {
+		return true
+	}
+	counter, ok := session.GetContext(rateKey{}).(*rateCounter)
+	if !ok {
+		return true
+	}
+	if counter.add(time.Now(), gw.rateWindow) > gw.rateLimit {
+		logger.Warnf("recv msg count > %v in %v, session: %v",
+			gw.rateLimit, gw.rateWindow, session.GetSessionId())
+		return false
+	}
+	return true
+}
+
+//forwarded packets carry the session key, the backend addresses the replies to it
+func (gw *Gateway) forward(session defs.ISession, key string, packet defs.IPacket) {
+	r := gw.ruleOf(packet.GetId())
+	a := gw.getAssignment(r.key(key))
+	if a == nil || !a.backend.IsWorking() || !gw.isReady(a.backend.sd.Name) {
+		a = gw.route(session, r, key)
+		if a == nil {
+			return
+		}
+	}
+	gw.touch(r.key(key), a)
+	err := a.backend.forward(packet)
+	if err == nil {
+		return
+	}
+
+	//the circuit opened or the connection was lost since the route was taken
+	a = gw.route(session, r, key)
+	if a == nil {
+		return
+	}
+	err = a.backend.forward(packet)
+	if err != nil {
+		logger.Warnf("forward packet %v of session %v to %v failed: %v",
+			packet.GetId(), key, a.backend.sd.Name, err)
+	}
+}
+
+//touch renews the registry assignment of an active session
+func (gw *Gateway) touch(key string, a *assignment) {
+	if gw.router == nil || gw.touchInterval <= 0 {
+		return
+	}
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&a.touched)
+	if now-last < int64(gw.touchInterval) || !atomic.CompareAndSwapInt64(&a.touched, last, now) {
+		return
+	}
+	err := gw.router.Touch(key)
+	if err != nil {
+		logger.Warnf("touch session %v failed: %v", key, err)
+	}
+}
+
+func (gw *Gateway) route(session defs.ISession, r *routeRule, key string) *assignment {
+	rk := r.key(key)
+	var name string
+	if gw.router != nil {
+		var err error
+		name, err = gw.router.Route(rk)
+		if err != nil {
+			logger.Warnf("route session %v failed: %v", rk, err)
+			return nil
+		}
+	} else {
+		sd := r.strategy.Select(key)
+		if sd == nil {
+			logger.Warn("no backend available")
+			return nil
+		}
+		name = sd.Name
+	}
+
+	b := gw.GetBackend(name)
+	if b == nil {
+		logger.Warnf("backend: %v nil", name)
+		return nil
+	}
+	a := &assignment{backend: b}
+	gw.routes.Store(rk, a)
+	b.sessions.Store(rk, key)
+	if gw.sessionRouteCallback != nil {
+		gw.sessionRouteCallback(session, b)
+	}
+	return a
+}
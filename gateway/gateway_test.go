@@ -0,0 +1,584 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lightning-go/lightning/cluster"
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/discovery"
+	"github.com/lightning-go/lightning/logger"
+	"github.com/lightning-go/lightning/module"
+	"github.com/lightning-go/lightning/network"
+	"github.com/lightning-go/lightning/resilience"
+	"github.com/lightning-go/lightning/selector"
+)
+
+const testGroup = "logic"
+
+type accountKey struct{}
+
+//testBackend is a logic server answering each packet with its name unless muted
+type testBackend struct {
+	*network.TcpServer
+	ins     *discovery.Instance
+	packets chan defs.IPacket
+	mute    int32
+}
+
+func startBackend(t *testing.T, name string) *testBackend {
+	tb := &testBackend{
+		TcpServer: network.NewTcpServer("127.0.0.1:0", name, 0),
+		packets:   make(chan defs.IPacket, 16),
+	}
+	tb.ins = &discovery.Instance{Name: name, Host: tb.Host(), Group: testGroup}
+	tb.SetCodec(module.NewHeadCodec())
+	tb.SetMsgCallback(func(conn defs.IConnection, packet defs.IPacket) {
+		tb.packets <- packet
+		if packet.GetStatus() != 0 || atomic.LoadInt32(&tb.mute) > 0 {
+			return
+		}
+		p := &defs.Packet{}
+		p.SetId(packet.GetId())
+		p.SetSessionId(packet.GetSessionId())
+		p.SetSequence(packet.GetSequence())
+		p.SetData([]byte(name))
+		conn.WritePacket(p)
+	})
+	tb.Serve()
+	t.Cleanup(func() {
+		tb.Shutdown(0)
+	})
+	return tb
+}
+
+//newGateway serves the sessions keyed by the account their open callback set
+func newGateway(t *testing.T, account func() string) *Gateway {
+	logger.SetLevel(logger.FATAL)
+	path := filepath.Join(t.TempDir(), "srvConf.json")
+	err := ioutil.WriteFile(path, []byte(`{"servers": {"gate": {"name": "gate", "host": "127.0.0.1"}}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := NewGateway("gate", path)
+	gw.SetCodec(module.NewHeadCodec())
+	gw.SetSessionOpenCallback(func(session defs.ISession) {
+		session.SetContext(accountKey{}, account())
+	})
+	gw.SetSessionKeyCallback(func(session defs.ISession) string {
+		key, _ := session.GetContext(accountKey{}).(string)
+		return key
+	})
+	gw.Serve()
+	t.Cleanup(func() {
+		gw.Shutdown(0)
+	})
+	return gw
+}
+
+func fixedAccount(key string) func() string {
+	return func() string {
+		return key
+	}
+}
+
+//watch connects the gateway to the backends through a static registry
+func watch(t *testing.T, gw *Gateway, backends ...*testBackend) *discovery.StaticRegistry {
+	instances := make([]*discovery.Instance, 0, len(backends))
+	for _, b := range backends {
+		instances = append(instances, b.ins)
+	}
+	reg := discovery.NewStaticRegistry(instances...)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	err := gw.Watch(ctx, reg, testGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range backends {
+		waitFor(t, "backend "+b.Name(), func() bool {
+			return gw.GetBackend(b.Name()) != nil
+		})
+	}
+	return reg
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("%v: timed out", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func dialGateway(t *testing.T, gw *Gateway) (*network.TcpClient, chan defs.IPacket) {
+	_, port, err := net.SplitHostPort(gw.TcpServer.Host())
+	if err != nil {
+		t.Fatal(err)
+	}
+	replies := make(chan defs.IPacket, 16)
+	client := network.NewTcpClient("test_cli", "127.0.0.1:"+port)
+	client.SetRetry(false)
+	client.SetCodec(module.NewHeadCodec())
+	client.SetMsgCallback(func(conn defs.IConnection, packet defs.IPacket) {
+		replies <- packet
+	})
+	if client.Connect() == nil {
+		t.Fatal("connect failed")
+	}
+	return client, replies
+}
+
+func send(client *network.TcpClient, id string) {
+	p := &defs.Packet{}
+	p.SetId(id)
+	p.SetData([]byte("hi"))
+	client.SendPacket(p)
+}
+
+func request(client *network.TcpClient, seq uint64) {
+	p := &defs.Packet{}
+	p.SetId("Logic.Test")
+	p.SetSequence(seq)
+	p.SetData([]byte("hi"))
+	client.SendPacket(p)
+}
+
+func recvPacket(t *testing.T, ch chan defs.IPacket) defs.IPacket {
+	select {
+	case packet := <-ch:
+		return packet
+	case <-time.After(3 * time.Second):
+		t.Fatal("no packet received")
+	}
+	return nil
+}
+
+//recvFrom returns the backend the next packet reached
+func recvFrom(t *testing.T, backends ...*testBackend) (*testBackend, defs.IPacket) {
+	deadline := time.After(3 * time.Second)
+	for {
+		for _, b := range backends {
+			select {
+			case packet := <-b.packets:
+				return b, packet
+			default:
+			}
+		}
+		select {
+		case <-deadline:
+			t.Fatal("no backend received the packet")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func waitClosed(t *testing.T, client *network.TcpClient) {
+	waitFor(t, "client close", func() bool {
+		conn := client.GetConn()
+		return conn == nil || conn.IsClosed()
+	})
+}
+
+//the first packet picks the backend, the next ones and the replies follow the key
+func TestRouteOnFirstPacket(t *testing.T) {
+	gw := newGateway(t, fixedAccount("acc1"))
+	a, b := startBackend(t, "a"), startBackend(t, "b")
+	watch(t, gw, a, b)
+	if gw.GetRoute("Logic.Test", "acc1") != nil {
+		t.Fatal("routed before the first packet")
+	}
+
+	client, replies := dialGateway(t, gw)
+	defer client.Close()
+	send(client, "Logic.Test")
+	owner, packet := recvFrom(t, a, b)
+	if packet.GetSessionId() != "acc1" {
+		t.Fatalf("forwarded with session %q", packet.GetSessionId())
+	}
+	route := gw.GetRoute("Logic.Test", "acc1")
+	if route == nil || route.BackendName() != owner.Name() || route.SessionCount() != 1 {
+		t.Fatalf("routed to %v, backend %v received", route, owner.Name())
+	}
+	if reply := recvPacket(t, replies); string(reply.GetData()) != owner.Name() {
+		t.Fatalf("reply from %s", reply.GetData())
+	}
+
+	for i := 0; i < 3; i++ {
+		send(client, "Logic.Test")
+		if next, _ := recvFrom(t, a, b); next != owner {
+			t.Fatalf("packet %v reached %v instead of %v", i, next.Name(), owner.Name())
+		}
+	}
+}
+
+//packets of sessions without a key stay on the gateway
+func TestRouteWithoutKey(t *testing.T) {
+	gw := newGateway(t, fixedAccount(""))
+	a := startBackend(t, "a")
+	watch(t, gw, a)
+
+	client, _ := dialGateway(t, gw)
+	defer client.Close()
+	send(client, "Logic.Test")
+	select {
+	case packet := <-a.packets:
+		t.Fatalf("forwarded %v", packet.GetId())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDisconnectNotify(t *testing.T) {
+	gw := newGateway(t, fixedAccount("acc1"))
+	gw.SetDisconnStatus(99)
+	a := startBackend(t, "a")
+	watch(t, gw, a)
+
+	client, _ := dialGateway(t, gw)
+	send(client, "Logic.Test")
+	recvPacket(t, a.packets)
+	client.Close()
+
+	packet := recvPacket(t, a.packets)
+	if packet.GetStatus() != 99 || packet.GetSessionId() != "acc1" {
+		t.Fatalf("notified status %v of %q", packet.GetStatus(), packet.GetSessionId())
+	}
+	waitFor(t, "route drop", func() bool {
+		return gw.GetRoute("Logic.Test", "acc1") == nil
+	})
+}
+
+//the assignment outlives the connection, the reconnect reaches the same backend
+func TestReconnectKeepsBackend(t *testing.T) {
+	gw := newGateway(t, fixedAccount("acc1"))
+	gw.SetDisconnStatus(99)
+	gw.SetStrategy(selector.NewRandomSelector())
+	router := cluster.NewRegistry(cluster.NewMemStore())
+	gw.SetRouter(router)
+	a, b := startBackend(t, "a"), startBackend(t, "b")
+	watch(t, gw, a, b)
+
+	client, _ := dialGateway(t, gw)
+	send(client, "Logic.Test")
+	owner, _ := recvFrom(t, a, b)
+	client.Close()
+	if packet := recvPacket(t, owner.packets); packet.GetStatus() != 99 {
+		t.Fatalf("status %v", packet.GetStatus())
+	}
+	if node, err := router.Lookup("acc1"); err != nil || node != owner.Name() {
+		t.Fatalf("owner %q after disconnect: %v", node, err)
+	}
+
+	for i := 0; i < 5; i++ {
+		client, _ = dialGateway(t, gw)
+		send(client, "Logic.Test")
+		if next, _ := recvFrom(t, a, b); next != owner {
+			t.Fatalf("reconnect %v reached %v instead of %v", i, next.Name(), owner.Name())
+		}
+		client.Close()
+		recvPacket(t, owner.packets)
+	}
+
+	gw.Logout("acc1")
+	if node, _ := router.Lookup("acc1"); node != "" {
+		t.Fatalf("owner %q after logout", node)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	gw := newGateway(t, fixedAccount("acc1"))
+	gw.SetRateLimit(3, time.Minute)
+	a := startBackend(t, "a")
+	watch(t, gw, a)
+
+	client, _ := dialGateway(t, gw)
+	for i := 0; i < 5; i++ {
+		send(client, "Logic.Test")
+	}
+	waitClosed(t, client)
+	time.Sleep(50 * time.Millisecond)
+	if n := len(a.packets); n > 3 {
+		t.Fatalf("%v packets forwarded", n)
+	}
+}
+
+func TestCloseOnBackendLost(t *testing.T) {
+	gw := newGateway(t, fixedAccount("acc1"))
+	a := startBackend(t, "a")
+	watch(t, gw, a)
+
+	client, _ := dialGateway(t, gw)
+	send(client, "Logic.Test")
+	recvPacket(t, a.packets)
+	a.Shutdown(0)
+	waitClosed(t, client)
+	if gw.GetRoute("Logic.Test", "acc1") != nil {
+		t.Fatal("route kept")
+	}
+}
+
+//sessions of a lost backend are routed again on their next packet
+func TestKeepOnBackendLost(t *testing.T) {
+	gw := newGateway(t, fixedAccount("acc1"))
+	gw.SetCloseOnBackendLost(false)
+	a, b := startBackend(t, "a"), startBackend(t, "b")
+	watch(t, gw, a, b)
+
+	client, replies := dialGateway(t, gw)
+	defer client.Close()
+	send(client, "Logic.Test")
+	owner, _ := recvFrom(t, a, b)
+	recvPacket(t, replies)
+	other := a
+	if owner == a {
+		other = b
+	}
+	owner.Shutdown(0)
+	waitFor(t, "backend lost", func() bool {
+		return gw.GetBackend(owner.Name()) == nil
+	})
+
+	send(client, "Logic.Test")
+	recvPacket(t, other.packets)
+	if reply := recvPacket(t, replies); string(reply.GetData()) != other.Name() {
+		t.Fatalf("reply from %s", reply.GetData())
+	}
+}
+
+//a deregistered backend hands its sessions over in the registry
+func TestMigrateOnRemove(t *testing.T) {
+	gw := newGateway(t, fixedAccount("acc1"))
+	gw.SetCloseOnBackendLost(false)
+	router := cluster.NewRegistry(cluster.NewMemStore())
+	migrated := make(chan string, 1)
+	router.SetMigrateCallback(func(key, from, to string) {
+		migrated <- key + ":" + from + ">" + to
+	})
+	gw.SetRouter(router)
+	a, b := startBackend(t, "a"), startBackend(t, "b")
+	reg := watch(t, gw, a, b)
+
+	client, _ := dialGateway(t, gw)
+	defer client.Close()
+	send(client, "Logic.Test")
+	owner, _ := recvFrom(t, a, b)
+	other := a
+	if owner == a {
+		other = b
+	}
+
+	err := reg.Deregister(owner.ins)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-migrated:
+		if want := "acc1:" + owner.Name() + ">" + other.Name(); m != want {
+			t.Fatalf("migrated %v, want %v", m, want)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("session not migrated")
+	}
+	if node, _ := router.Lookup("acc1"); node != other.Name() {
+		t.Fatalf("owner %q", node)
+	}
+
+	send(client, "Logic.Test")
+	if packet := recvPacket(t, other.packets); packet.GetSessionId() != "acc1" {
+		t.Fatalf("forwarded with session %q", packet.GetSessionId())
+	}
+}
+
+func newBreakers() *resilience.Group {
+	breakers := resilience.NewGroup()
+	breakers.SetBreakerCallback(func(b *resilience.Breaker) {
+		b.SetMinRequests(2)
+		b.SetOpenTimeout(time.Minute)
+	})
+	return breakers
+}
+
+func notForwarded(t *testing.T, b *testBackend) {
+	select {
+	case packet := <-b.packets:
+		t.Fatalf("forwarded %v", packet.GetId())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+//answered requests keep the circuit closed, unanswered ones open it
+func TestBreakerReplyTimeout(t *testing.T) {
+	gw := newGateway(t, fixedAccount("acc1"))
+	breakers := newBreakers()
+	gw.SetBreakers(breakers)
+	gw.SetReplyTimeout(50 * time.Millisecond)
+	a := startBackend(t, "a")
+	watch(t, gw, a)
+
+	client, replies := dialGateway(t, gw)
+	defer client.Close()
+	for seq := uint64(1); seq <= 4; seq++ {
+		request(client, seq)
+		recvPacket(t, a.packets)
+		recvPacket(t, replies)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if state := breakers.Get("a").State(); state != resilience.StateClosed {
+		t.Fatalf("circuit %v with the replies in time", state)
+	}
+
+	atomic.StoreInt32(&a.mute, 1)
+	for seq := uint64(5); seq <= 8; seq++ {
+		request(client, seq)
+		recvPacket(t, a.packets)
+	}
+	waitFor(t, "circuit open", func() bool {
+		return breakers.Get("a").State() == resilience.StateOpen
+	})
+	request(client, 9)
+	notForwarded(t, a)
+}
+
+//the requests pending on a lost connection are failures
+func TestBreakerConnLost(t *testing.T) {
+	gw := newGateway(t, fixedAccount("acc1"))
+	breakers := newBreakers()
+	gw.SetBreakers(breakers)
+	gw.SetReplyTimeout(time.Minute)
+	a := startBackend(t, "a")
+	atomic.StoreInt32(&a.mute, 1)
+	watch(t, gw, a)
+
+	client, _ := dialGateway(t, gw)
+	request(client, 1)
+	request(client, 2)
+	recvPacket(t, a.packets)
+	recvPacket(t, a.packets)
+	a.Shutdown(0)
+	waitClosed(t, client)
+	if state := breakers.Get("a").State(); state != resilience.StateOpen {
+		t.Fatalf("circuit %v", state)
+	}
+}
+
+//a backend connected again keeps the state of its circuit
+func TestBreakerKeptAcrossReconnect(t *testing.T) {
+	gw := newGateway(t, fixedAccount("acc1"))
+	breakers := newBreakers()
+	gw.SetBreakers(breakers)
+	a := startBackend(t, "a")
+	watch(t, gw, a)
+
+	breaker := gw.GetBackend("a").Client().Breaker()
+	for i := 0; i < 2; i++ {
+		breaker.Do(func() error {
+			return errors.New("refused")
+		})
+	}
+	if breaker.State() != resilience.StateOpen {
+		t.Fatalf("circuit %v", breaker.State())
+	}
+
+	gw.DelBackend("a")
+	if !gw.AddBackend(a.ins.SessionData()) {
+		t.Fatal("backend not connected again")
+	}
+	if b := gw.GetBackend("a").Client().Breaker(); b != breaker || b.State() != resilience.StateOpen {
+		t.Fatalf("circuit %v after reconnect", b.State())
+	}
+
+	client, _ := dialGateway(t, gw)
+	defer client.Close()
+	request(client, 1)
+	notForwarded(t, a)
+}
+
+//the packets of a route go to the backends of its type, each route keeps its
+//own assignment of the session
+func TestRouteStrategy(t *testing.T) {
+	gw := newGateway(t, fixedAccount("acc1"))
+	gw.SetDisconnStatus(99)
+	gw.SetRouteStrategy("Chat.", 2, selector.NewRandomSelector())
+	router := cluster.NewRegistry(cluster.NewMemStore())
+	gw.SetRouter(router)
+	a, b, chat := startBackend(t, "a"), startBackend(t, "b"), startBackend(t, "chat")
+	chat.ins.Type = 2
+	watch(t, gw, a, b, chat)
+
+	client, replies := dialGateway(t, gw)
+	send(client, "Chat.Say")
+	if packet := recvPacket(t, chat.packets); packet.GetSessionId() != "acc1" {
+		t.Fatalf("forwarded with session %q", packet.GetSessionId())
+	}
+	recvPacket(t, replies)
+	for i := 0; i < 5; i++ {
+		send(client, "Logic.Test")
+		if owner, _ := recvFrom(t, a, b, chat); owner == chat {
+			t.Fatal("logic packet reached the chat backend")
+		}
+		recvPacket(t, replies)
+	}
+
+	logic := gw.GetRoute("Logic.Test", "acc1")
+	if r := gw.GetRoute("Chat.Say", "acc1"); r == nil || r.BackendName() != "chat" || logic == nil {
+		t.Fatalf("routed to %v and %v", r, logic)
+	}
+	if node, _ := router.Lookup("Chat." + routeSep + "acc1"); node != "chat" {
+		t.Fatalf("chat owner %q", node)
+	}
+	if node, _ := router.Lookup("acc1"); node != logic.BackendName() {
+		t.Fatalf("logic owner %q", node)
+	}
+
+	//both backends are told of the disconnect
+	client.Close()
+	owners := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		owner, packet := recvFrom(t, a, b, chat)
+		if packet.GetStatus() != 99 {
+			t.Fatalf("status %v", packet.GetStatus())
+		}
+		owners[owner.Name()] = true
+	}
+	if !owners["chat"] || !owners[logic.BackendName()] {
+		t.Fatalf("notified %v", owners)
+	}
+}
+
+//the hash of the session key keeps a reconnect on its backend without a registry
+func TestRouteHashByKey(t *testing.T) {
+	gw := newGateway(t, fixedAccount("acc1"))
+	gw.SetRouteStrategy("Chat.", 2, selector.NewHashSelector())
+	a, b := startBackend(t, "chat-a"), startBackend(t, "chat-b")
+	a.ins.Type, b.ins.Type = 2, 2
+	watch(t, gw, a, b)
+
+	var owner *testBackend
+	for i := 0; i < 5; i++ {
+		client, _ := dialGateway(t, gw)
+		send(client, "Chat.Say")
+		next, _ := recvFrom(t, a, b)
+		if owner == nil {
+			owner = next
+		} else if next != owner {
+			t.Fatalf("reconnect %v reached %v instead of %v", i, next.Name(), owner.Name())
+		}
+		client.Close()
+		waitFor(t, "route drop", func() bool {
+			return gw.GetRoute("Chat.Say", "acc1") == nil
+		})
+	}
+}
@@ -0,0 +1,123 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package gateway
+
+import (
+	"strings"
+
+	"github.com/lightning-go/lightning/selector"
+)
+
+//separates the prefix of a route from the session key in the registry
+const routeSep = "#"
+
+//routeRule picks the backend of the packets whose id starts with prefix, the
+//default rule has no prefix and takes the backend types no other rule claims
+type routeRule struct {
+	prefix      string
+	backendType int
+	strategy    selector.Selector
+}
+
+//key is the session key the assignments of the rule are kept by, the
+//default rule keeps the session key as is
+func (r *routeRule) key(key string) string {
+	if len(r.prefix) == 0 {
+		return key
+	}
+	return r.prefix + routeSep + key
+}
+
+func (r *routeRule) forget(key string) {
+	f, ok := r.strategy.(interface{ Forget(string) })
+	if ok {
+		f.Forget(key)
+	}
+}
+
+//SetRouteStrategy sends the packets whose id starts with prefix, e.g. a
+//service name or a message id, to the backends of backendType picked by
+//strategy with the session key, the longest prefix wins. Each route keeps
+//its own assignment of a session, the packets matching no route go to the
+//backends of the other types through the default strategy
+func (gw *Gateway) SetRouteStrategy(prefix string, backendType int, strategy selector.Selector) {
+	if len(prefix) == 0 || strategy == nil {
+		return
+	}
+	r := &routeRule{
+		prefix:      prefix,
+		backendType: backendType,
+		strategy:    strategy,
+	}
+	rules := make([]*routeRule, 0, len(gw.rules)+1)
+	for _, old := range gw.rules {
+		if old.prefix != prefix {
+			rules = append(rules, old)
+		}
+	}
+	i := 0
+	for i < len(rules) && len(rules[i].prefix) >= len(prefix) {
+		i++
+	}
+	rules = append(rules[:i], append([]*routeRule{r}, rules[i:]...)...)
+	gw.rules = rules
+	gw.filterStrategy()
+
+	gw.RangeBackend(func(name string, b *Backend) bool {
+		gw.setBackend(b.sd)
+		return true
+	})
+}
+
+//ruleOf returns the rule of the packet id, the default one is last
+func (gw *Gateway) ruleOf(id string) *routeRule {
+	for _, r := range gw.rules {
+		if strings.HasPrefix(id, r.prefix) {
+			return r
+		}
+	}
+	return gw.rules[len(gw.rules)-1]
+}
+
+//parseKey returns the rule and the session key of a registry key
+func (gw *Gateway) parseKey(key string) (*routeRule, string) {
+	last := len(gw.rules) - 1
+	for _, r := range gw.rules[:last] {
+		if strings.HasPrefix(key, r.prefix+routeSep) {
+			return r, key[len(r.prefix)+len(routeSep):]
+		}
+	}
+	return gw.rules[last], key
+}
+
+func (gw *Gateway) accepts(r *routeRule, sd *selector.SessionData) bool {
+	if len(r.prefix) > 0 {
+		return sd.Type == r.backendType
+	}
+	for _, other := range gw.rules[:len(gw.rules)-1] {
+		if other.backendType == sd.Type {
+			return false
+		}
+	}
+	return true
+}
+
+//setBackend adds the backend to the strategies of the rules taking its type
+func (gw *Gateway) setBackend(sd *selector.SessionData) {
+	for _, r := range gw.rules {
+		if gw.accepts(r, sd) {
+			r.strategy.Set(sd)
+		} else {
+			r.strategy.Del(sd.Name)
+		}
+	}
+}
+
+func (gw *Gateway) delBackend(name string) {
+	for _, r := range gw.rules {
+		r.strategy.Del(name)
+	}
+}
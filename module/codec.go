@@ -9,6 +9,7 @@ import (
 	"bufio"
 	"encoding/binary"
 	"io"
+	"github.com/lightning-go/lightning/defs"
 )
 
 type Encoder struct {
@@ -193,3 +194,26 @@ func (decoder *Decoder) DecodeDataFull(buf []byte) (n int, err error) {
 	}
 	return n, err
 }
+
+//carriesId reports whether the codec frames the packet id
+func carriesId(codec defs.ICodec) bool {
+	idCodec, ok := codec.(defs.IPacketIdCodec)
+	return ok && idCodec.CarriesId()
+}
+
+//writeBuffered writes through codecs without a write buffer
+func writeBuffered(codec defs.ICodec, packet defs.IPacket) error {
+	buffered, ok := codec.(defs.IBufferedCodec)
+	if ok {
+		return buffered.WriteBuffered(packet)
+	}
+	return codec.Write(packet)
+}
+
+func flushCodec(codec defs.ICodec) error {
+	buffered, ok := codec.(defs.IBufferedCodec)
+	if ok {
+		return buffered.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package module
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/defs"
+)
+
+//algorithm carried in the leading flag byte of every payload
+const (
+	CompressNone  uint8 = 0
+	CompressZlib  uint8 = 1
+	CompressGzip  uint8 = 2
+	CompressFlate uint8 = 3
+)
+
+const DefaultCompressThreshold = 1024
+
+//data of the CompressId packets
+const (
+	compressHello uint8 = 0
+	compressOn    uint8 = 1
+)
+
+type compressor interface {
+	io.WriteCloser
+	Reset(io.Writer)
+}
+
+var compressorPools = map[uint8]*sync.Pool{
+	CompressZlib: {New: func() interface{} {
+		return zlib.NewWriter(nil)
+	}},
+	CompressGzip: {New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}},
+	CompressFlate: {New: func() interface{} {
+		w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+		return w
+	}},
+}
+
+//CompressCodec decorates a framed codec such as HeadCodec or WSCodec,
+//payloads of at least threshold bytes are compressed and every payload is
+//prefixed with one flag byte naming its algorithm, so each peer decodes
+//whatever the other side chose and small packets are sent as is.
+//the inner codec must carry the packet data opaquely.
+//over a codec carrying the packet id the flag byte is negotiated: each peer
+//sends a CompressId hello, and once the hello of the peer is read the writer
+//sends CompressId on and flags every payload after it. a plain peer never
+//sends the hello, so both directions stay plain frames, and it drops the
+//hello as a reserved packet. without the packet id, e.g. over WSCodec, there
+//is nothing to negotiate with and both peers must use CompressCodec
+type CompressCodec struct {
+	inner         defs.ICodec
+	algorithm     uint8
+	threshold     int
+	maxPacketSize int
+	negotiate     bool
+	helloSent     bool  //the fields of the writer
+	sendFlagged   bool
+	peerHello     int32 //set by the reader
+	recvFlagged   bool  //the field of the reader
+}
+
+func NewCompressCodec(inner defs.ICodec, algorithm uint8, threshold int) *CompressCodec {
+	if threshold <= 0 {
+		threshold = DefaultCompressThreshold
+	}
+	return &CompressCodec{
+		inner:     inner,
+		algorithm: algorithm,
+		threshold: threshold,
+	}
+}
+
+func (cc *CompressCodec) Clone() defs.ICodec {
+	return NewCompressCodec(NewCodec(cc.inner), cc.algorithm, cc.threshold)
+}
+
+func (cc *CompressCodec) Init(conn defs.IConnection) bool {
+	if conn == nil || cc.inner == nil {
+		return false
+	}
+	if _, ok := compressorPools[cc.algorithm]; !ok && cc.algorithm != CompressNone {
+		return false
+	}
+
+	cc.maxPacketSize = int(conf.GetGlobalVal().MaxPacketSize)
+	limit, ok := conn.(defs.IPacketLimit)
+	if ok && limit.GetMaxPacketSize() > 0 {
+		cc.maxPacketSize = limit.GetMaxPacketSize()
+	}
+
+	if !cc.inner.Init(conn) {
+		return false
+	}
+	cc.negotiate = carriesId(cc.inner)
+	if !cc.negotiate {
+		atomic.StoreInt32(&cc.peerHello, 1)
+		cc.sendFlagged = true
+		cc.recvFlagged = true
+	}
+	return true
+}
+
+//Hello runs the handshake of the inner codec and sends the compress hello,
+//an inner handshake codec may only write once the peer answered, so then the
+//hello goes ahead of the first packet instead
+func (cc *CompressCodec) Hello() error {
+	handshake, ok := cc.inner.(defs.IHandshakeCodec)
+	if ok {
+		return handshake.Hello()
+	}
+	if !cc.negotiate || cc.helloSent {
+		return nil
+	}
+	cc.helloSent = true
+	return cc.inner.Write(compressPacket(compressHello))
+}
+
+func compressPacket(v uint8) defs.IPacket {
+	p := &defs.Packet{}
+	p.SetId(CompressId)
+	p.SetData([]byte{v})
+	return p
+}
+
+//the hello and the switch to flagged payloads go ahead of the packet in the same write
+func (cc *CompressCodec) writeNegotiation(write func(defs.IPacket) error) error {
+	if !cc.negotiate {
+		return nil
+	}
+	if !cc.helloSent {
+		cc.helloSent = true
+		err := write(compressPacket(compressHello))
+		if err != nil {
+			return err
+		}
+	}
+	if !cc.sendFlagged && atomic.LoadInt32(&cc.peerHello) == 1 {
+		cc.sendFlagged = true
+		return write(compressPacket(compressOn))
+	}
+	return nil
+}
+
+func (cc *CompressCodec) readNegotiation(packet defs.IPacket) error {
+	data := packet.GetData()
+	if len(data) != 1 {
+		return ErrHandshake
+	}
+	switch data[0] {
+	case compressHello:
+		atomic.StoreInt32(&cc.peerHello, 1)
+	case compressOn:
+		cc.recvFlagged = true
+	default:
+		return ErrHandshake
+	}
+	return nil
+}
+
+func (cc *CompressCodec) CarriesId() bool {
+	return carriesId(cc.inner)
+}
+
+func (cc *CompressCodec) Write(packet defs.IPacket) error {
+	return cc.write(packet, cc.inner.Write)
+}
+
+//WriteBuffered leaves the packed packet in the write buffer of the inner codec
+func (cc *CompressCodec) WriteBuffered(packet defs.IPacket) error {
+	return cc.write(packet, func(p defs.IPacket) error {
+		return writeBuffered(cc.inner, p)
+	})
+}
+
+func (cc *CompressCodec) write(packet defs.IPacket, write func(defs.IPacket) error) error {
+	err := cc.writeNegotiation(write)
+	if err != nil {
+		return err
+	}
+	if !cc.sendFlagged {
+		return write(packet)
+	}
+	p, err := cc.pack(packet)
+	if err != nil {
+		return err
+	}
+	return write(p)
+}
+
+func (cc *CompressCodec) Flush() error {
+	return flushCodec(cc.inner)
+}
+
+func (cc *CompressCodec) pack(packet defs.IPacket) (defs.IPacket, error) {
+	data, err := cc.compress(packet.GetData())
+	if err != nil {
+		return nil, err
+	}
+
+	//the packet may be shared between connections, so it is copied
+	p := &defs.Packet{}
+	p.SetId(packet.GetId())
+	p.SetSessionId(packet.GetSessionId())
+	p.SetSequence(packet.GetSequence())
+	p.SetStatus(packet.GetStatus())
+	p.SetData(data)
+	return p, nil
+}
+
+func (cc *CompressCodec) Read() (defs.IPacket, error) {
+	for {
+		packet, err := cc.inner.Read()
+		if err != nil {
+			return nil, err
+		}
+		if packet == nil {
+			continue
+		}
+		if cc.negotiate && packet.GetId() == CompressId {
+			err = cc.readNegotiation(packet)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !cc.recvFlagged {
+			return packet, nil
+		}
+		data, err := cc.uncompress(packet.GetData())
+		if err != nil {
+			return nil, err
+		}
+		packet.SetData(data)
+		return packet, nil
+	}
+}
+
+func (cc *CompressCodec) compress(data []byte) ([]byte, error) {
+	pool, ok := compressorPools[cc.algorithm]
+	if !ok || len(data) < cc.threshold {
+		buf := make([]byte, len(data)+1)
+		buf[0] = CompressNone
+		copy(buf[1:], data)
+		return buf, nil
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(data)/2 + 1)
+	buf.WriteByte(cc.algorithm)
+
+	w := pool.Get().(compressor)
+	defer pool.Put(w)
+	w.Reset(&buf)
+	_, err := w.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (cc *CompressCodec) uncompress(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrMalformedFrame
+	}
+
+	var r io.ReadCloser
+	var err error
+	src := bytes.NewReader(data[1:])
+	switch data[0] {
+	case CompressNone:
+		return data[1:], nil
+	case CompressZlib:
+		r, err = zlib.NewReader(src)
+	case CompressGzip:
+		r, err = gzip.NewReader(src)
+	case CompressFlate:
+		r = flate.NewReader(src)
+	default:
+		return nil, ErrMalformedFrame
+	}
+	if err != nil {
+		return nil, ErrMalformedFrame
+	}
+	defer r.Close()
+
+	//bounded so a small frame can not expand past the packet limit
+	var reader io.Reader = r
+	if cc.maxPacketSize > 0 {
+		reader = io.LimitReader(r, int64(cc.maxPacketSize)+1)
+	}
+	var out bytes.Buffer
+	n, err := io.Copy(&out, reader)
+	if err != nil {
+		return nil, ErrMalformedFrame
+	}
+	if cc.maxPacketSize > 0 && n > int64(cc.maxPacketSize) {
+		return nil, ErrPacketTooLarge
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,240 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package module
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lightning-go/lightning/defs"
+)
+
+//pipeCodec keeps the written packets and reads the ones delivered to it
+type pipeCodec struct {
+	carriesId bool
+	written   []defs.IPacket
+	toRead    []defs.IPacket
+}
+
+func (pc *pipeCodec) Init(conn defs.IConnection) bool {
+	return true
+}
+
+func (pc *pipeCodec) CarriesId() bool {
+	return pc.carriesId
+}
+
+func (pc *pipeCodec) Write(packet defs.IPacket) error {
+	p := &defs.Packet{}
+	p.SetId(packet.GetId())
+	p.SetData(append([]byte(nil), packet.GetData()...))
+	pc.written = append(pc.written, p)
+	return nil
+}
+
+func (pc *pipeCodec) Read() (defs.IPacket, error) {
+	if len(pc.toRead) == 0 {
+		return nil, ErrConnClosed
+	}
+	packet := pc.toRead[0]
+	pc.toRead = pc.toRead[1:]
+	return packet, nil
+}
+
+//deliver hands the packets written to from since the last call to to
+func deliver(from, to *pipeCodec) []defs.IPacket {
+	wire := from.written
+	from.written = nil
+	to.toRead = append(to.toRead, wire...)
+	return wire
+}
+
+func newCompressPeer(t *testing.T, carriesId bool) (*CompressCodec, *pipeCodec) {
+	pipe := &pipeCodec{carriesId: carriesId}
+	cc := NewCompressCodec(pipe, CompressGzip, 0)
+	if !cc.Init(&slowConn{}) {
+		t.Fatal("init failed")
+	}
+	if err := cc.Hello(); err != nil {
+		t.Fatal(err)
+	}
+	return cc, pipe
+}
+
+func dataPacket(data []byte) defs.IPacket {
+	p := &defs.Packet{}
+	p.SetId("1")
+	p.SetData(data)
+	return p
+}
+
+//readAll returns the packets delivered to cc
+func readAll(t *testing.T, cc *CompressCodec) [][]byte {
+	var out [][]byte
+	for {
+		p, err := cc.Read()
+		if err == ErrConnClosed {
+			return out
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, p.GetData())
+	}
+}
+
+func TestCompressRoundTrip(t *testing.T) {
+	small := []byte("hello")
+	large := bytes.Repeat([]byte("lightning "), 512)
+	for _, algorithm := range []uint8{CompressNone, CompressZlib, CompressGzip, CompressFlate} {
+		cc := NewCompressCodec(nil, algorithm, 0)
+		for _, data := range [][]byte{small, large} {
+			frame, err := cc.compress(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantFlag := algorithm
+			if len(data) < cc.threshold {
+				wantFlag = CompressNone
+			}
+			if frame[0] != wantFlag {
+				t.Fatalf("algorithm %v: flag %v for %v bytes", algorithm, frame[0], len(data))
+			}
+			if wantFlag != CompressNone && len(frame) >= len(data) {
+				t.Fatalf("algorithm %v: %v bytes compressed to %v", algorithm, len(data), len(frame))
+			}
+
+			//the reader decodes any algorithm
+			out, err := NewCompressCodec(nil, CompressZlib, 0).uncompress(frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, data) {
+				t.Fatalf("algorithm %v: round trip changed %v bytes", algorithm, len(data))
+			}
+		}
+	}
+}
+
+//a frame expanding past the packet limit is refused
+func TestCompressLimit(t *testing.T) {
+	cc := NewCompressCodec(nil, CompressGzip, 0)
+	frame, err := cc.compress(make([]byte, 64*1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc.maxPacketSize = 1024
+	if _, err := cc.uncompress(frame); err != ErrPacketTooLarge {
+		t.Fatalf("expanded frame: %v", err)
+	}
+}
+
+func TestCompressMalformed(t *testing.T) {
+	cc := NewCompressCodec(nil, CompressGzip, 0)
+	if _, err := cc.uncompress([]byte{CompressGzip, 'x', 'y'}); err != ErrMalformedFrame {
+		t.Fatalf("corrupt payload: %v", err)
+	}
+	if _, err := cc.uncompress([]byte{9, 'x'}); err != ErrMalformedFrame {
+		t.Fatalf("unknown algorithm: %v", err)
+	}
+	if _, err := cc.uncompress(nil); err != ErrMalformedFrame {
+		t.Fatalf("empty payload: %v", err)
+	}
+
+	a, _ := newCompressPeer(t, true)
+	bad := &defs.Packet{}
+	bad.SetId(CompressId)
+	bad.SetData([]byte{7})
+	a.inner.(*pipeCodec).toRead = []defs.IPacket{bad}
+	if _, err := a.Read(); err != ErrHandshake {
+		t.Fatalf("unknown negotiation: %v", err)
+	}
+}
+
+//each side flags its payloads once it read the hello of the peer
+func TestCompressNegotiate(t *testing.T) {
+	large := bytes.Repeat([]byte("lightning "), 512)
+	a, aWire := newCompressPeer(t, true)
+	b, bWire := newCompressPeer(t, true)
+
+	if err := a.Write(dataPacket(large)); err != nil {
+		t.Fatal(err)
+	}
+	wire := deliver(aWire, bWire)
+	if len(wire) != 2 || wire[0].GetId() != CompressId || !bytes.Equal(wire[1].GetData(), large) {
+		t.Fatalf("written before the peer hello: %v packets", len(wire))
+	}
+	if out := readAll(t, b); len(out) != 1 || !bytes.Equal(out[0], large) {
+		t.Fatalf("read %v packets", len(out))
+	}
+
+	if err := b.Write(dataPacket(large)); err != nil {
+		t.Fatal(err)
+	}
+	wire = deliver(bWire, aWire)
+	if len(wire) != 3 || wire[1].GetId() != CompressId || wire[2].GetData()[0] != CompressGzip ||
+		len(wire[2].GetData()) >= len(large) {
+		t.Fatalf("written after the peer hello: %v packets", len(wire))
+	}
+	if out := readAll(t, a); len(out) != 1 || !bytes.Equal(out[0], large) {
+		t.Fatalf("read %v packets", len(out))
+	}
+
+	//the switch is sent once
+	for i := 0; i < 2; i++ {
+		if err := a.Write(dataPacket([]byte("small"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wire = deliver(aWire, bWire)
+	if len(wire) != 3 || wire[0].GetId() != CompressId || wire[2].GetData()[0] != CompressNone {
+		t.Fatalf("written after the switch: %v packets", len(wire))
+	}
+	if out := readAll(t, b); len(out) != 2 || string(out[1]) != "small" {
+		t.Fatalf("read %v packets", len(out))
+	}
+}
+
+//a peer without CompressCodec never sends the hello, both directions stay plain
+func TestCompressPlainPeer(t *testing.T) {
+	large := bytes.Repeat([]byte("lightning "), 512)
+	a, aWire := newCompressPeer(t, true)
+	plain := &pipeCodec{carriesId: true}
+
+	for i := 0; i < 2; i++ {
+		if err := a.Write(dataPacket(large)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wire := deliver(aWire, plain)
+	if len(wire) != 3 || !bytes.Equal(wire[1].GetData(), large) || !bytes.Equal(wire[2].GetData(), large) {
+		t.Fatalf("written to a plain peer: %v packets", len(wire))
+	}
+
+	//a plain payload looking like a flag is not uncompressed
+	flagged := []byte{CompressGzip, 'x', 'y'}
+	plain.Write(dataPacket(flagged))
+	deliver(plain, aWire)
+	if out := readAll(t, a); len(out) != 1 || !bytes.Equal(out[0], flagged) {
+		t.Fatalf("read %v packets from a plain peer", len(out))
+	}
+}
+
+//without the packet id nothing is negotiated and every payload is flagged
+func TestCompressWithoutId(t *testing.T) {
+	a, aWire := newCompressPeer(t, false)
+	b, bWire := newCompressPeer(t, false)
+	if err := a.Write(dataPacket([]byte("small"))); err != nil {
+		t.Fatal(err)
+	}
+	wire := deliver(aWire, bWire)
+	if len(wire) != 1 || wire[0].GetData()[0] != CompressNone {
+		t.Fatalf("written %v packets", len(wire))
+	}
+	if out := readAll(t, b); len(out) != 1 || string(out[0]) != "small" {
+		t.Fatalf("read %v packets", len(out))
+	}
+}
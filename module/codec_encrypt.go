@@ -0,0 +1,284 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package module
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/defs"
+	"golang.org/x/crypto/curve25519"
+)
+
+var (
+	ErrHandshake        = errors.New("codec handshake failed")
+	ErrHandshakeTimeout = errors.New("codec handshake timeout")
+)
+
+const encryptKeyLabel = "lightning encrypt codec"
+
+//EncryptCodec decorates a codec carrying packet ids, such as HeadCodec.
+//both peers send an X25519 public key in a HandshakeId packet, then every
+//payload is sealed with AES-256-GCM under a key per direction. the nonce is
+//the frame count of the direction, so replayed, dropped or reordered frames
+//fail to open, and the sequence, status and ids are authenticated with it.
+//an optional pre-shared key is mixed into the keys against man in the middle.
+//switched in by UpdateCodec, packets read before the peer key are delivered
+//as plain packets, they were written before the peer switched and are as
+//trusted as the connection was until then. from the peer key on every packet
+//must open, a plain one closes the connection. used from the start instead,
+//a packet before the peer key is refused
+type EncryptCodec struct {
+	inner     defs.ICodec
+	psk       []byte
+	decorated bool
+	priv      []byte
+	pub       []byte
+	mux       sync.Mutex
+	writeMux  sync.Mutex
+	helloSent bool
+	ready     chan struct{}
+	sendAead  cipher.AEAD
+	recvAead  cipher.AEAD
+	sendSeq   uint64
+	recvSeq   uint64
+}
+
+func NewEncryptCodec(inner defs.ICodec, psk ...[]byte) *EncryptCodec {
+	ec := &EncryptCodec{
+		inner: inner,
+	}
+	if len(psk) > 0 {
+		ec.psk = psk[0]
+	}
+	return ec
+}
+
+func (ec *EncryptCodec) Clone() defs.ICodec {
+	return NewEncryptCodec(NewCodec(ec.inner), ec.psk)
+}
+
+func (ec *EncryptCodec) Decorate(codec defs.ICodec) {
+	ec.inner = codec
+	ec.decorated = true
+}
+
+func (ec *EncryptCodec) Init(conn defs.IConnection) bool {
+	if conn == nil || ec.inner == nil {
+		return false
+	}
+	if !ec.decorated && !ec.inner.Init(conn) {
+		return false
+	}
+
+	return ec.genKey() == nil
+}
+
+func (ec *EncryptCodec) genKey() error {
+	priv := make([]byte, curve25519.ScalarSize)
+	_, err := rand.Read(priv)
+	if err != nil {
+		return err
+	}
+	pub, err := curve25519.X25519(priv, curve25519.Basepoint)
+	if err != nil {
+		return err
+	}
+	ec.priv = priv
+	ec.pub = pub
+	ec.ready = make(chan struct{})
+	return nil
+}
+
+func (ec *EncryptCodec) Hello() error {
+	p := &defs.Packet{}
+	p.SetId(HandshakeId)
+	p.SetData(ec.pub)
+
+	ec.writeMux.Lock()
+	err := ec.inner.Write(p)
+	ec.writeMux.Unlock()
+	if err != nil {
+		return err
+	}
+
+	ec.mux.Lock()
+	ec.helloSent = true
+	ec.checkReady()
+	ec.mux.Unlock()
+	return nil
+}
+
+func (ec *EncryptCodec) handshake(packet defs.IPacket) error {
+	peer := packet.GetData()
+	if len(peer) != curve25519.PointSize {
+		return ErrHandshake
+	}
+	//low order keys giving a zero secret are refused
+	shared, err := curve25519.X25519(ec.priv, peer)
+	if err != nil {
+		return ErrHandshake
+	}
+
+	ec.mux.Lock()
+	defer ec.mux.Unlock()
+	if ec.recvAead != nil {
+		return ErrHandshake
+	}
+
+	ec.sendAead, err = ec.newAead(shared, ec.pub, peer)
+	if err != nil {
+		return ErrHandshake
+	}
+	ec.recvAead, err = ec.newAead(shared, peer, ec.pub)
+	if err != nil {
+		return ErrHandshake
+	}
+	ec.checkReady()
+	return nil
+}
+
+//writes start once the local key is sent and the peer key is known
+func (ec *EncryptCodec) checkReady() {
+	if ec.helloSent && ec.sendAead != nil {
+		close(ec.ready)
+	}
+}
+
+func (ec *EncryptCodec) newAead(shared, from, to []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, shared)
+	mac.Write([]byte(encryptKeyLabel))
+	mac.Write(from)
+	mac.Write(to)
+	mac.Write(ec.psk)
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (ec *EncryptCodec) waitReady() error {
+	select {
+	case <-ec.ready:
+		return nil
+	default:
+	}
+	timer := time.NewTimer(conf.GetGlobalVal().HttpTimeout)
+	defer timer.Stop()
+	select {
+	case <-ec.ready:
+		return nil
+	case <-timer.C:
+		return ErrHandshakeTimeout
+	}
+}
+
+func (ec *EncryptCodec) CarriesId() bool {
+	return carriesId(ec.inner)
+}
+
+func (ec *EncryptCodec) Write(packet defs.IPacket) error {
+	return ec.write(packet, ec.inner.Write)
+}
+
+//WriteBuffered leaves the sealed packet in the write buffer of the inner codec
+func (ec *EncryptCodec) WriteBuffered(packet defs.IPacket) error {
+	return ec.write(packet, func(p defs.IPacket) error {
+		return writeBuffered(ec.inner, p)
+	})
+}
+
+func (ec *EncryptCodec) Flush() error {
+	return flushCodec(ec.inner)
+}
+
+func (ec *EncryptCodec) write(packet defs.IPacket, write func(defs.IPacket) error) error {
+	err := ec.waitReady()
+	if err != nil {
+		return err
+	}
+
+	//the packet may be shared between connections, so it is copied
+	p := &defs.Packet{}
+	p.SetId(packet.GetId())
+	p.SetSessionId(packet.GetSessionId())
+	p.SetSequence(packet.GetSequence())
+	p.SetStatus(packet.GetStatus())
+
+	//nonces follow the wire order
+	ec.writeMux.Lock()
+	defer ec.writeMux.Unlock()
+	nonce := frameNonce(ec.sendAead, ec.sendSeq)
+	ec.sendSeq++
+	p.SetData(ec.sendAead.Seal(nil, nonce, packet.GetData(), additionalData(p)))
+	return write(p)
+}
+
+func (ec *EncryptCodec) Read() (defs.IPacket, error) {
+	for {
+		packet, err := ec.inner.Read()
+		if err != nil {
+			return nil, err
+		}
+		if packet == nil {
+			continue
+		}
+		packet, err = ec.Decode(packet)
+		if err != nil || packet != nil {
+			return packet, err
+		}
+	}
+}
+
+func (ec *EncryptCodec) Decode(packet defs.IPacket) (defs.IPacket, error) {
+	if packet.GetId() == HandshakeId {
+		return nil, ec.handshake(packet)
+	}
+
+	ec.mux.Lock()
+	defer ec.mux.Unlock()
+	if ec.recvAead == nil {
+		if ec.decorated {
+			return packet, nil
+		}
+		return nil, ErrHandshake
+	}
+
+	nonce := frameNonce(ec.recvAead, ec.recvSeq)
+	data, err := ec.recvAead.Open(nil, nonce, packet.GetData(), additionalData(packet))
+	if err != nil {
+		return nil, ErrMalformedFrame
+	}
+	ec.recvSeq++
+	packet.SetData(data)
+	return packet, nil
+}
+
+func frameNonce(aead cipher.AEAD, seq uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], seq)
+	return nonce
+}
+
+func additionalData(packet defs.IPacket) []byte {
+	id := packet.GetId()
+	sessionId := packet.GetSessionId()
+	ad := make([]byte, 16, 16+len(id)+len(sessionId))
+	binary.BigEndian.PutUint64(ad, packet.GetSequence())
+	binary.BigEndian.PutUint32(ad[8:], uint32(packet.GetStatus()))
+	binary.BigEndian.PutUint32(ad[12:], uint32(len(id)))
+	ad = append(ad, id...)
+	return append(ad, sessionId...)
+}
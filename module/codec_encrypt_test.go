@@ -0,0 +1,174 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package module
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/defs"
+)
+
+//wireCodec keeps the written packets instead of sending them
+type wireCodec struct {
+	written []defs.IPacket
+}
+
+func (wc *wireCodec) Init(conn defs.IConnection) bool {
+	return true
+}
+
+func (wc *wireCodec) Write(packet defs.IPacket) error {
+	wc.written = append(wc.written, packet)
+	return nil
+}
+
+func (wc *wireCodec) Read() (defs.IPacket, error) {
+	return nil, ErrConnClosed
+}
+
+//last takes a copy of the last written packet, Decode opens it in place
+func (wc *wireCodec) last() defs.IPacket {
+	packet := wc.written[len(wc.written)-1]
+	p := &defs.Packet{}
+	p.SetId(packet.GetId())
+	p.SetSessionId(packet.GetSessionId())
+	p.SetSequence(packet.GetSequence())
+	p.SetStatus(packet.GetStatus())
+	p.SetData(append([]byte(nil), packet.GetData()...))
+	return p
+}
+
+func newEncryptPeer(t *testing.T, decorated bool) (*EncryptCodec, *wireCodec) {
+	wire := &wireCodec{}
+	ec := NewEncryptCodec(wire)
+	ec.decorated = decorated
+	if err := ec.genKey(); err != nil {
+		t.Fatal(err)
+	}
+	return ec, wire
+}
+
+//handshake sends the key of each peer to the other
+func handshake(t *testing.T, a, b *EncryptCodec, aWire, bWire *wireCodec) {
+	if err := a.Hello(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Hello(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Decode(aWire.last()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Decode(bWire.last()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sealed(t *testing.T, ec *EncryptCodec, wire *wireCodec, data string) defs.IPacket {
+	p := &defs.Packet{}
+	p.SetId("1")
+	p.SetSequence(7)
+	p.SetData([]byte(data))
+	if err := ec.Write(p); err != nil {
+		t.Fatal(err)
+	}
+	return wire.last()
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	a, aWire := newEncryptPeer(t, false)
+	b, bWire := newEncryptPeer(t, false)
+	handshake(t, a, b, aWire, bWire)
+
+	for _, data := range []string{"hello", "", "world"} {
+		frame := sealed(t, a, aWire, data)
+		if data != "" && bytes.Contains(frame.GetData(), []byte(data)) {
+			t.Fatalf("%q written in plain", data)
+		}
+		p, err := b.Decode(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(p.GetData()) != data || p.GetId() != "1" || p.GetSequence() != 7 {
+			t.Fatalf("opened %q id %v seq %v", p.GetData(), p.GetId(), p.GetSequence())
+		}
+	}
+}
+
+//a frame opens once and only in its place in the stream
+func TestEncryptTamperReplay(t *testing.T) {
+	a, aWire := newEncryptPeer(t, false)
+	b, bWire := newEncryptPeer(t, false)
+	handshake(t, a, b, aWire, bWire)
+
+	first := sealed(t, a, aWire, "first")
+	tampered := aWire.last()
+	tampered.GetData()[0] ^= 1
+	if _, err := b.Decode(tampered); err != ErrMalformedFrame {
+		t.Fatalf("tampered data: %v", err)
+	}
+	tampered = aWire.last()
+	tampered.SetSequence(8)
+	if _, err := b.Decode(tampered); err != ErrMalformedFrame {
+		t.Fatalf("tampered sequence: %v", err)
+	}
+
+	second := sealed(t, a, aWire, "second")
+	if _, err := b.Decode(second); err != ErrMalformedFrame {
+		t.Fatalf("reordered frame: %v", err)
+	}
+	if _, err := b.Decode(first); err != nil {
+		t.Fatal(err)
+	}
+	replayed := aWire.written[len(aWire.written)-2]
+	if _, err := b.Decode(replayed); err != ErrMalformedFrame {
+		t.Fatalf("replayed frame: %v", err)
+	}
+}
+
+//switched in by UpdateCodec plain packets pass until the peer key only
+func TestEncryptPlainPackets(t *testing.T) {
+	plain := &defs.Packet{}
+	plain.SetData([]byte("plain"))
+
+	a, aWire := newEncryptPeer(t, false)
+	if _, err := a.Decode(plain); err != ErrHandshake {
+		t.Fatalf("plain packet before the key: %v", err)
+	}
+
+	a, aWire = newEncryptPeer(t, true)
+	b, bWire := newEncryptPeer(t, true)
+	p, err := a.Decode(plain)
+	if err != nil || string(p.GetData()) != "plain" {
+		t.Fatalf("plain packet before the switch: %v", err)
+	}
+	handshake(t, a, b, aWire, bWire)
+	if _, err := a.Decode(plain); err != ErrMalformedFrame {
+		t.Fatalf("plain packet after the key: %v", err)
+	}
+}
+
+func TestEncryptHandshakeTimeout(t *testing.T) {
+	val := conf.GetGlobalVal()
+	old := val.HttpTimeout
+	val.HttpTimeout = 50 * time.Millisecond
+	defer func() {
+		val.HttpTimeout = old
+	}()
+
+	a, _ := newEncryptPeer(t, false)
+	if err := a.Hello(); err != nil {
+		t.Fatal(err)
+	}
+	p := &defs.Packet{}
+	p.SetData([]byte("data"))
+	if err := a.Write(p); err != ErrHandshakeTimeout {
+		t.Fatalf("write without the peer key: %v", err)
+	}
+}
@@ -6,13 +6,19 @@
 package module
 
 import (
+	"bytes"
 	"encoding/binary"
+	"github.com/lightning-go/lightning/conf"
 	"github.com/lightning-go/lightning/defs"
 )
 
+//upper bound of the id and sessionId length fields
+const MaxIdLen = 1024
+
 type HeadCodec struct {
-	dec  *Decoder
-	enc  *Encoder
+	dec           *Decoder
+	enc           *Encoder
+	maxPacketSize int
 }
 
 func NewHeadCodec() *HeadCodec {
@@ -35,35 +41,112 @@ func (hc *HeadCodec) Init(conn defs.IConnection) bool {
 	hc.dec = NewDecoder(c, binary.BigEndian)
 	hc.enc = NewEncode(c, binary.BigEndian)
 
+	hc.maxPacketSize = int(conf.GetGlobalVal().MaxPacketSize)
+	limit, ok := conn.(defs.IPacketLimit)
+	if ok && limit.GetMaxPacketSize() > 0 {
+		hc.maxPacketSize = limit.GetMaxPacketSize()
+	}
+
+	return true
+}
+
+func checkLen(n int32, max int) error {
+	if n < 0 {
+		return ErrMalformedFrame
+	}
+	if max > 0 && int(n) > max {
+		return ErrPacketTooLarge
+	}
+	return nil
+}
+func (hc *HeadCodec) CarriesId() bool {
 	return true
 }
+
 func (hc *HeadCodec) Write(packet defs.IPacket) error {
+	err := hc.WriteBuffered(packet)
+	if err != nil {
+		return err
+	}
+	return hc.enc.Flush()
+}
+
+//WriteBuffered encodes packet without flushing
+func (hc *HeadCodec) WriteBuffered(packet defs.IPacket) error {
+	if hc.enc == nil {
+		return ErrCodecWriteNil
+	}
+	return hc.encode(hc.enc, packet)
+}
+
+func (hc *HeadCodec) Flush() error {
+	if hc.enc == nil {
+		return ErrCodecWriteNil
+	}
+	return hc.enc.Flush()
+}
+
+//EncodeFrame encodes packet as written to the connection, for SharedPacket
+func (hc *HeadCodec) EncodeFrame(packet defs.IPacket) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := NewEncode(&buf, binary.BigEndian)
+	err := hc.encode(enc, packet)
+	if err != nil {
+		return nil, err
+	}
+	err = enc.Flush()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (hc *HeadCodec) WriteFrame(frame []byte) error {
+	err := hc.WriteFrameBuffered(frame)
+	if err != nil {
+		return err
+	}
+	return hc.enc.Flush()
+}
+
+func (hc *HeadCodec) WriteFrameBuffered(frame []byte) error {
 	if hc.enc == nil {
 		return ErrCodecWriteNil
 	}
+	err := hc.enc.EncodeData(frame)
+	if err != nil {
+		hc.enc.Clean()
+		return err
+	}
+	return nil
+}
 
+func (hc *HeadCodec) encode(enc *Encoder, packet defs.IPacket) error {
 	//data len
 	data := packet.GetData()
 	dataLen := len(data)
-	err := hc.enc.EncodeInt32(int32(dataLen))
+	if hc.maxPacketSize > 0 && dataLen > hc.maxPacketSize {
+		return ErrPacketTooLarge
+	}
+	err := enc.EncodeInt32(int32(dataLen))
 	if err != nil {
-		hc.enc.Clean()
+		enc.Clean()
 		return err
 	}
 
 	//id len
 	id := packet.GetId()
 	idLen := len(id)
-	err = hc.enc.EncodeInt32(int32(idLen))
+	err = enc.EncodeInt32(int32(idLen))
 	if err != nil {
-		hc.enc.Clean()
+		enc.Clean()
 		return err
 	}
 	if idLen > 0 {
 		//id
-		err = hc.enc.EncodeData([]byte(id))
+		err = enc.EncodeData([]byte(id))
 		if err != nil {
-			hc.enc.Clean()
+			enc.Clean()
 			return err
 		}
 	}
@@ -71,43 +154,42 @@ func (hc *HeadCodec) Write(packet defs.IPacket) error {
 	//session len
 	sessionId := packet.GetSessionId()
 	sIdLen := len(sessionId)
-	err = hc.enc.EncodeInt32(int32(sIdLen))
+	err = enc.EncodeInt32(int32(sIdLen))
 	if err != nil {
-		hc.enc.Clean()
+		enc.Clean()
 		return err
 	}
 	if sIdLen > 0 {
 		//sessionId
-		err = hc.enc.EncodeData([]byte(sessionId))
+		err = enc.EncodeData([]byte(sessionId))
 		if err != nil {
-			hc.enc.Clean()
+			enc.Clean()
 			return err
 		}
 	}
 
 	//sequence
 	seq := packet.GetSequence()
-	err = hc.enc.EncodeUInt64(seq)
+	err = enc.EncodeUInt64(seq)
 	if err != nil {
-		hc.enc.Clean()
+		enc.Clean()
 		return err
 	}
 
 	//status
-	err = hc.enc.EncodeInt32(int32(packet.GetStatus()))
+	err = enc.EncodeInt32(int32(packet.GetStatus()))
 	if err != nil {
-		hc.enc.Clean()
+		enc.Clean()
 		return err
 	}
 
 	//data
-	err = hc.enc.EncodeData(data)
+	err = enc.EncodeData(data)
 	if err != nil {
-		hc.enc.Clean()
+		enc.Clean()
 		return err
 	}
 
-	hc.enc.Flush()
 	return nil
 }
 
@@ -122,6 +204,11 @@ func (hc *HeadCodec) Read() (defs.IPacket, error) {
 		hc.dec.Clean()
 		return nil, err
 	}
+	err = checkLen(dataLen, hc.maxPacketSize)
+	if err != nil {
+		hc.dec.Clean()
+		return nil, err
+	}
 
 	//id len
 	idLen, err := hc.dec.DecodeInt32()
@@ -129,6 +216,11 @@ func (hc *HeadCodec) Read() (defs.IPacket, error) {
 		hc.dec.Clean()
 		return nil, err
 	}
+	err = checkLen(idLen, MaxIdLen)
+	if err != nil {
+		hc.dec.Clean()
+		return nil, err
+	}
 	var id []byte
 	if idLen > 0 {
 		//id
@@ -147,6 +239,11 @@ func (hc *HeadCodec) Read() (defs.IPacket, error) {
 		hc.dec.Clean()
 		return nil, err
 	}
+	err = checkLen(sIdLen, MaxIdLen)
+	if err != nil {
+		hc.dec.Clean()
+		return nil, err
+	}
 	var sId []byte
 	if sIdLen > 0 {
 		//sessionId
@@ -0,0 +1,329 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package module
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/defs"
+)
+
+var ErrUnknownMsg = errors.New("unknown message")
+
+//heartbeat ids on the wire, outside the registrable range
+const (
+	protoPingId uint32 = math.MaxUint32
+	protoPongId uint32 = math.MaxUint32 - 1
+)
+
+//ProtoCodec frames packets with a numeric message id:
+//dataLen(int32) msgId(uint32) sessionIdLen(int32) sessionId seq(uint64) status(int32) data
+//registered ids are decoded into *defs.MsgPacket carrying the proto.Message
+type ProtoCodec struct {
+	dec           *Decoder
+	enc           *Encoder
+	registry      *MsgRegistry
+	maxPacketSize int
+}
+
+func NewProtoCodec() *ProtoCodec {
+	return &ProtoCodec{}
+}
+
+func (pc *ProtoCodec) Init(conn defs.IConnection) bool {
+	if conn == nil {
+		return false
+	}
+	iTcpConn, ok := conn.(defs.ITcpConnection)
+	if !ok {
+		return false
+	}
+	c := iTcpConn.GetConn()
+	if c == nil {
+		return false
+	}
+
+	pc.dec = NewDecoder(c, binary.BigEndian)
+	pc.enc = NewEncode(c, binary.BigEndian)
+	pc.registry = GetMsgRegistry()
+
+	pc.maxPacketSize = int(conf.GetGlobalVal().MaxPacketSize)
+	limit, ok := conn.(defs.IPacketLimit)
+	if ok && limit.GetMaxPacketSize() > 0 {
+		pc.maxPacketSize = limit.GetMaxPacketSize()
+	}
+
+	return true
+}
+
+func (pc *ProtoCodec) msgId(packet defs.IPacket) (uint32, error) {
+	msgPacket, ok := packet.(defs.IMsgPacket)
+	if ok && msgPacket.GetMsg() != nil {
+		id, ok := pc.registry.GetMsgId(msgPacket.GetMsg())
+		if !ok {
+			return 0, ErrUnknownMsg
+		}
+		return id, nil
+	}
+
+	id := packet.GetId()
+	switch id {
+	case "":
+		return 0, nil
+	case PingId:
+		return protoPingId, nil
+	case PongId:
+		return protoPongId, nil
+	}
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, ErrUnknownMsg
+	}
+	return uint32(n), nil
+}
+
+//ping and pong have reserved msg ids
+func (pc *ProtoCodec) CarriesId() bool {
+	return true
+}
+
+func (pc *ProtoCodec) Write(packet defs.IPacket) error {
+	err := pc.WriteBuffered(packet)
+	if err != nil {
+		return err
+	}
+	return pc.enc.Flush()
+}
+
+//WriteBuffered encodes packet without flushing
+func (pc *ProtoCodec) WriteBuffered(packet defs.IPacket) error {
+	if pc.enc == nil {
+		return ErrCodecWriteNil
+	}
+	return pc.encode(pc.enc, packet)
+}
+
+func (pc *ProtoCodec) Flush() error {
+	if pc.enc == nil {
+		return ErrCodecWriteNil
+	}
+	return pc.enc.Flush()
+}
+
+//the message is marshaled once for all the connections sharing the frame
+func (pc *ProtoCodec) EncodeFrame(packet defs.IPacket) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := NewEncode(&buf, binary.BigEndian)
+	err := pc.encode(enc, packet)
+	if err != nil {
+		return nil, err
+	}
+	err = enc.Flush()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (pc *ProtoCodec) WriteFrame(frame []byte) error {
+	err := pc.WriteFrameBuffered(frame)
+	if err != nil {
+		return err
+	}
+	return pc.enc.Flush()
+}
+
+func (pc *ProtoCodec) WriteFrameBuffered(frame []byte) error {
+	if pc.enc == nil {
+		return ErrCodecWriteNil
+	}
+	err := pc.enc.EncodeData(frame)
+	if err != nil {
+		pc.enc.Clean()
+		return err
+	}
+	return nil
+}
+
+func (pc *ProtoCodec) encode(enc *Encoder, packet defs.IPacket) error {
+	msgId, err := pc.msgId(packet)
+	if err != nil {
+		return err
+	}
+
+	data := packet.GetData()
+	msgPacket, ok := packet.(defs.IMsgPacket)
+	if len(data) == 0 && ok && msgPacket.GetMsg() != nil {
+		pb, ok := msgPacket.GetMsg().(proto.Message)
+		if !ok {
+			return ErrUnknownMsg
+		}
+		data, err = proto.Marshal(pb)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrEncodeFailed, err)
+		}
+	}
+	dataLen := len(data)
+	if pc.maxPacketSize > 0 && dataLen > pc.maxPacketSize {
+		return ErrPacketTooLarge
+	}
+
+	//data len
+	err = enc.EncodeInt32(int32(dataLen))
+	if err != nil {
+		enc.Clean()
+		return err
+	}
+
+	//msg id
+	err = enc.EncodeUInt32(msgId)
+	if err != nil {
+		enc.Clean()
+		return err
+	}
+
+	//session len
+	sessionId := packet.GetSessionId()
+	sIdLen := len(sessionId)
+	err = enc.EncodeInt32(int32(sIdLen))
+	if err != nil {
+		enc.Clean()
+		return err
+	}
+	if sIdLen > 0 {
+		//sessionId
+		err = enc.EncodeData([]byte(sessionId))
+		if err != nil {
+			enc.Clean()
+			return err
+		}
+	}
+
+	//sequence
+	err = enc.EncodeUInt64(packet.GetSequence())
+	if err != nil {
+		enc.Clean()
+		return err
+	}
+
+	//status
+	err = enc.EncodeInt32(int32(packet.GetStatus()))
+	if err != nil {
+		enc.Clean()
+		return err
+	}
+
+	//data
+	err = enc.EncodeData(data)
+	if err != nil {
+		enc.Clean()
+		return err
+	}
+
+	return nil
+}
+
+func (pc *ProtoCodec) Read() (defs.IPacket, error) {
+	if pc.dec == nil {
+		return nil, ErrCodecReadNil
+	}
+
+	//data len
+	dataLen, err := pc.dec.DecodeInt32()
+	if err != nil {
+		pc.dec.Clean()
+		return nil, err
+	}
+	err = checkLen(dataLen, pc.maxPacketSize)
+	if err != nil {
+		pc.dec.Clean()
+		return nil, err
+	}
+
+	//msg id
+	msgId, err := pc.dec.DecodeUInt32()
+	if err != nil {
+		pc.dec.Clean()
+		return nil, err
+	}
+
+	//sessionId len
+	sIdLen, err := pc.dec.DecodeInt32()
+	if err != nil {
+		pc.dec.Clean()
+		return nil, err
+	}
+	err = checkLen(sIdLen, MaxIdLen)
+	if err != nil {
+		pc.dec.Clean()
+		return nil, err
+	}
+	var sId []byte
+	if sIdLen > 0 {
+		//sessionId
+		idData := make([]byte, sIdLen)
+		n, err := pc.dec.DecodeDataFull(idData)
+		if err != nil {
+			pc.dec.Clean()
+			return nil, err
+		}
+		sId = idData[:n]
+	}
+
+	//sequence
+	seq, err := pc.dec.DecodeUInt64()
+	if err != nil {
+		pc.dec.Clean()
+		return nil, err
+	}
+
+	//status
+	status, err := pc.dec.DecodeInt32()
+	if err != nil {
+		pc.dec.Clean()
+		return nil, err
+	}
+
+	//data
+	buff := make([]byte, dataLen)
+	n, err := pc.dec.DecodeDataFull(buff)
+	if err != nil {
+		pc.dec.Clean()
+		return nil, err
+	}
+	data := buff[:n]
+
+	p := &defs.MsgPacket{}
+	switch msgId {
+	case protoPingId:
+		p.SetId(PingId)
+	case protoPongId:
+		p.SetId(PongId)
+	default:
+		p.SetId(strconv.FormatUint(uint64(msgId), 10))
+		msg, ok := pc.registry.NewMsg(msgId)
+		if ok {
+			err = proto.Unmarshal(data, msg)
+			if err != nil {
+				return nil, ErrMalformedFrame
+			}
+			p.SetMsg(msg)
+		}
+	}
+	p.SetSessionId(string(sId))
+	p.SetSequence(seq)
+	p.SetStatus(int(status))
+	p.SetData(data)
+
+	return p, nil
+}
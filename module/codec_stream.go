@@ -40,17 +40,27 @@ func (sc *StreamCodec) Init(conn defs.IConnection) bool {
 	return true
 }
 func (sc *StreamCodec) Write(packet defs.IPacket) error {
+	err := sc.WriteBuffered(packet)
+	if err != nil {
+		return err
+	}
+
+	return sc.enc.Flush()
+}
+
+//WriteBuffered copies the data to the write buffer without flushing
+func (sc *StreamCodec) WriteBuffered(packet defs.IPacket) error {
 	if sc.enc == nil {
 		return ErrCodecWriteNil
 	}
+	return sc.enc.EncodeData(packet.GetData())
+}
 
-	err := sc.enc.EncodeData(packet.GetData())
-	if err != nil {
-		return err
+func (sc *StreamCodec) Flush() error {
+	if sc.enc == nil {
+		return ErrCodecWriteNil
 	}
-
-	sc.enc.Flush()
-	return nil
+	return sc.enc.Flush()
 }
 
 func (sc *StreamCodec) Read() (defs.IPacket, error) {
@@ -0,0 +1,42 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package module
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/lightning-go/lightning/defs"
+)
+
+var errBrokenPipe = errors.New("broken pipe")
+
+//brokenWriter fails every write like a socket whose peer is gone
+type brokenWriter struct{}
+
+func (w brokenWriter) Write(p []byte) (int, error) {
+	return 0, errBrokenPipe
+}
+
+//a failed socket write reaches the write loop instead of looking like success
+func TestCodecWriteFlushError(t *testing.T) {
+	enc := func() *Encoder {
+		return NewEncode(brokenWriter{}, binary.BigEndian)
+	}
+	codecs := map[string]defs.ICodec{
+		"stream": &StreamCodec{enc: enc()},
+		"head":   &HeadCodec{enc: enc()},
+		"proto":  &ProtoCodec{enc: enc()},
+	}
+	for name, codec := range codecs {
+		p := &defs.Packet{}
+		p.SetData([]byte("data"))
+		if err := codec.Write(p); !errors.Is(err, errBrokenPipe) {
+			t.Fatalf("%v codec write: %v", name, err)
+		}
+	}
+}
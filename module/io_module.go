@@ -6,6 +6,7 @@
 package module
 
 import (
+	"context"
 	"github.com/lightning-go/lightning/defs"
 	"reflect"
 	"github.com/lightning-go/lightning/logger"
@@ -14,20 +15,38 @@ import (
 	"errors"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 	"github.com/lightning-go/lightning/utils"
 )
 
+//reserved packet ids of the heartbeat, codec handshake and streams, never delivered
+const (
+	PingId        = "$ping"
+	PongId        = "$pong"
+	HandshakeId   = "$handshake"
+	StreamAckId   = "$stream_ack"
+	StreamCloseId = "$stream_close"
+	CompressId    = "$compress"
+)
+
 var (
-	ErrConnClosed    = errors.New("conn closed")
-	ErrReadBuffNil   = errors.New("read buff is nil")
-	ErrCodecWriteNil = errors.New("codec write is nil")
-	ErrCodecReadNil  = errors.New("codec read is nil")
+	ErrConnClosed     = errors.New("conn closed")
+	ErrReadBuffNil    = errors.New("read buff is nil")
+	ErrCodecWriteNil  = errors.New("codec write is nil")
+	ErrCodecReadNil   = errors.New("codec read is nil")
+	ErrTimeout        = errors.New("rpc call timeout")
+	ErrPacketTooLarge = errors.New("packet too large")
+	ErrMalformedFrame = errors.New("malformed frame")
+	ErrWriteQueueFull = errors.New("write queue full")
+	ErrEncodeFailed   = errors.New("encode failed")
 )
 
 type RpcCall struct {
 	request  defs.IPacket
 	response defs.IPacket
 	reply    interface{}
+	err      error
 	Done     chan *RpcCall
 }
 
@@ -39,14 +58,33 @@ func (rpcCall *RpcCall) done() {
 	}
 }
 
+//queued by UpdateCodec, the writer switches once the packets before it are written
+type codecSwitch struct {
+	defs.Packet
+	codec defs.ICodec
+}
+
 type IOModule struct {
-	conn       defs.IConnection
-	codec      defs.ICodec
-	writeQueue chan defs.IPacket
-	readClose  chan bool
-	rpcPool    sync.Pool
-	idGen      *utils.IdGenerator
-	pending    sync.Map
+	conn         defs.IConnection
+	codec        defs.ICodec
+	codecMux     sync.Mutex
+	pendingHello defs.IPacket
+	writeQueue   chan defs.IPacket
+	writeClose   chan bool
+	readClose    chan bool
+	rpcPool      sync.Pool
+	idGen        *utils.IdGenerator
+	pending      sync.Map
+	streams      sync.Map
+	writing      int32
+	lastRead     int64
+	policy       int32
+	writeTimeout int64
+	highMark     int32
+	lowMark      int32
+	aboveHigh    int32
+	slow         int32
+	dropped      int64
 }
 
 func NewIOModule(conn defs.IConnection) *IOModule {
@@ -57,8 +95,10 @@ func NewIOModule(conn defs.IConnection) *IOModule {
 		conn:       conn,
 		codec:      nil,
 		writeQueue: make(chan defs.IPacket, conf.GetGlobalVal().MaxQueueSize),
+		writeClose: make(chan bool),
 		readClose:  make(chan bool),
 		idGen:      utils.NewIdGenerator(),
+		lastRead:   time.Now().UnixNano(),
 	}
 	m.rpcPool.New = func() interface{} {
 		return &RpcCall{}
@@ -74,19 +114,120 @@ func (ioModule *IOModule) freeRpcCall(rpcCall *RpcCall) {
 	ioModule.rpcPool.Put(rpcCall)
 }
 
+//SetWritePolicy decides what Write does once the write queue is full,
+//timeout bounds the wait of WriteBlock, zero waits until there is room
+func (ioModule *IOModule) SetWritePolicy(policy defs.WritePolicy, timeout time.Duration) {
+	atomic.StoreInt32(&ioModule.policy, int32(policy))
+	atomic.StoreInt64(&ioModule.writeTimeout, int64(timeout))
+}
+
+//SetWatermark notifies the connection once the queue length reaches high and
+//again once it falls back to low, high <= 0 disables
+func (ioModule *IOModule) SetWatermark(high, low int) {
+	if low < 0 || low >= high {
+		low = high / 2
+	}
+	atomic.StoreInt32(&ioModule.highMark, int32(high))
+	atomic.StoreInt32(&ioModule.lowMark, int32(low))
+}
+
+//Dropped returns the number of packets dropped by the write policy
+func (ioModule *IOModule) Dropped() int64 {
+	return atomic.LoadInt64(&ioModule.dropped)
+}
+
 func (ioModule *IOModule) UpdateCodec(codec defs.ICodec) {
-	ioModule.codec = ioModule.newCodec(codec)
-	if ioModule.codec == nil {
+	ioModule.Write(&codecSwitch{codec: codec})
+}
+
+func (ioModule *IOModule) getCodec() defs.ICodec {
+	ioModule.codecMux.Lock()
+	codec := ioModule.codec
+	ioModule.codecMux.Unlock()
+	return codec
+}
+
+func (ioModule *IOModule) switchCodec(codec defs.ICodec) {
+	newCodec := ioModule.newCodec(codec)
+	if newCodec == nil {
 		logger.Error("new codec failed")
 		return
 	}
-	if !ioModule.codec.Init(ioModule.conn) {
+	//decorators wrap the running codec to keep what it has buffered
+	decorator, isDecorator := newCodec.(defs.ICodecDecorator)
+	if isDecorator {
+		decorator.Decorate(ioModule.codec)
+	}
+	if !newCodec.Init(ioModule.conn) {
 		logger.Error("codec init failed")
 		return
 	}
+
+	ioModule.codecMux.Lock()
+	ioModule.codec = newCodec
+	hello := ioModule.pendingHello
+	ioModule.pendingHello = nil
+	ioModule.codecMux.Unlock()
+
+	if !ioModule.hello(newCodec) {
+		ioModule.Close()
+		return
+	}
+	if hello != nil && isDecorator {
+		_, err := decorator.Decode(hello)
+		if err != nil {
+			ioModule.onCodecError(err)
+			ioModule.Close()
+		}
+	}
+}
+
+func (ioModule *IOModule) hello(codec defs.ICodec) bool {
+	handshake, ok := codec.(defs.IHandshakeCodec)
+	if !ok {
+		return true
+	}
+	err := handshake.Hello()
+	if err != nil {
+		logger.Errorf("connection %v handshake failed: %v", ioModule.conn.GetId(), err)
+		return false
+	}
+	return true
+}
+
+//frames read by the previous codec while the writer switched codecs, nil when consumed
+func (ioModule *IOModule) readSwitched(codec defs.ICodec, packet defs.IPacket) (defs.IPacket, error) {
+	ioModule.codecMux.Lock()
+	defer ioModule.codecMux.Unlock()
+
+	if codec != ioModule.codec {
+		decorator, ok := ioModule.codec.(defs.ICodecDecorator)
+		if ok {
+			return decorator.Decode(packet)
+		}
+		return packet, nil
+	}
+	//the peer switched first, kept for the codec the local side is about to use
+	if packet.GetId() == HandshakeId {
+		ioModule.pendingHello = packet
+		return nil, nil
+	}
+	return packet, nil
 }
 
 func (ioModule *IOModule) newCodec(codec defs.ICodec) defs.ICodec {
+	return NewCodec(codec)
+}
+
+//NewCodec allocates a fresh codec of the same type as the given prototype
+func NewCodec(codec defs.ICodec) defs.ICodec {
+	if codec == nil {
+		return nil
+	}
+	cloner, ok := codec.(defs.ICodecCloner)
+	if ok {
+		return cloner.Clone()
+	}
 	mType := reflect.TypeOf(codec)
 	obj := reflect.New(mType.Elem())
 	v, ok := obj.Interface().(defs.ICodec)
@@ -103,8 +244,9 @@ func (ioModule *IOModule) Close() {
 }
 
 func (ioModule *IOModule) OnConnectionLost() {
-	if ioModule.writeQueue != nil {
-		close(ioModule.writeQueue)
+	//the queue itself stays open, writes may still be racing the close
+	if ioModule.writeClose != nil {
+		close(ioModule.writeClose)
 	}
 	if ioModule.readClose != nil {
 		close(ioModule.readClose)
@@ -132,6 +274,9 @@ func (ioModule *IOModule) Codec(codec defs.ICodec) bool {
 		logger.Error("codec init failed")
 		return false
 	}
+	if !ioModule.hello(ioModule.codec) {
+		return false
+	}
 
 	ioModule.enableRead()
 	ioModule.enableWrite()
@@ -177,19 +322,30 @@ func (ioModule *IOModule) pendDone() {
 	})
 }
 
-func (ioModule *IOModule) WriteAwait(packet defs.IPacket) (response defs.IPacket, err error) {
+func (ioModule *IOModule) WriteAwait(packet defs.IPacket) (defs.IPacket, error) {
+	return ioModule.WriteAwaitCtx(context.Background(), packet)
+}
+
+func (ioModule *IOModule) WriteAwaitCtx(ctx context.Context, packet defs.IPacket) (response defs.IPacket, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	seq := ioModule.idGen.Get()
 	packet.SetSequence(seq)
 
 	call := ioModule.newRpcCall()
 	call.request = packet
 	call.response = nil
+	call.err = nil
 	call.Done = make(chan *RpcCall, 1)
 
 	ioModule.pending.Store(seq, call)
-	err = ioModule.codec.Write(packet)
-	if err != nil {
-		logger.Error(err)
+	//queued behind the packets written before it, the writer owns the codec
+	if !ioModule.write(packet) {
+		err = ErrWriteQueueFull
+		if ioModule.conn.IsClosed() {
+			err = ErrConnClosed
+		}
 		iCall, ok := ioModule.pending.Load(seq)
 		if ok {
 			ioModule.pending.Delete(seq)
@@ -202,23 +358,275 @@ func (ioModule *IOModule) WriteAwait(packet defs.IPacket) (response defs.IPacket
 		}
 	}
 
-	call = <-call.Done
-	if call != nil {
-		response = call.response
+	select {
+	case call = <-call.Done:
+		if call != nil {
+			response = call.response
+			if call.err != nil {
+				err = call.err
+			}
+		}
+		ioModule.freeRpcCall(call)
+	case <-ctx.Done():
+		//the call may still be referenced by a late reply, so it is not put back to the pool
+		ioModule.pending.Delete(seq)
+		err = ctx.Err()
+		if err == context.DeadlineExceeded {
+			err = ErrTimeout
+		}
 	}
-	ioModule.freeRpcCall(call)
 	return
 }
 
 func (ioModule *IOModule) Write(packet defs.IPacket) {
+	ioModule.write(packet)
+}
+
+//write returns false when the packet was not queued
+func (ioModule *IOModule) write(packet defs.IPacket) bool {
 	if packet == nil {
-		return
+		return false
 	}
 	if ioModule.conn.IsClosed() {
-		return
+		return false
+	}
+	n := atomic.AddInt32(&ioModule.writing, 1)
+	if !ioModule.push(packet) {
+		ioModule.writeDone(1)
+		return false
+	}
+	ioModule.highWatermark(n)
+	return true
+}
+
+//push queues the packet following the write policy, false when it was dropped
+func (ioModule *IOModule) push(packet defs.IPacket) bool {
+	select {
+	case ioModule.writeQueue <- packet:
+		return true
+	default:
+	}
+	//the packets behind a codec switch need the new codec, it is never dropped
+	_, isSwitch := packet.(*codecSwitch)
+	if isSwitch {
+		return ioModule.pushWait(packet, nil)
+	}
+
+	switch defs.WritePolicy(atomic.LoadInt32(&ioModule.policy)) {
+	case defs.WriteDropNewest:
+		ioModule.drop(packet)
+		return false
+	case defs.WriteDropOldest:
+		return ioModule.pushDropOldest(packet)
+	case defs.WriteDisconnect:
+		ioModule.disconnect()
+		return false
+	}
+
+	timeout := time.Duration(atomic.LoadInt64(&ioModule.writeTimeout))
+	if timeout <= 0 {
+		return ioModule.pushWait(packet, nil)
 	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	if !ioModule.pushWait(packet, t.C) {
+		ioModule.drop(packet)
+		return false
+	}
+	return true
+}
+
+//pushWait waits for room until timeout fires or the connection is lost
+func (ioModule *IOModule) pushWait(packet defs.IPacket, timeout <-chan time.Time) bool {
 	select {
 	case ioModule.writeQueue <- packet:
+		return true
+	case <-timeout:
+		return false
+	case <-ioModule.writeClose:
+		return false
+	}
+}
+
+func (ioModule *IOModule) pushDropOldest(packet defs.IPacket) bool {
+	for {
+		select {
+		case ioModule.writeQueue <- packet:
+			return true
+		default:
+		}
+		select {
+		case old := <-ioModule.writeQueue:
+			_, isSwitch := old.(*codecSwitch)
+			if isSwitch {
+				logger.Warnf("connection %v write queue full at a codec switch", ioModule.conn.GetId())
+				ioModule.disconnect()
+				return false
+			}
+			ioModule.writeDone(1)
+			ioModule.drop(old)
+		default:
+		}
+	}
+}
+
+func (ioModule *IOModule) drop(packet defs.IPacket) {
+	atomic.AddInt64(&ioModule.dropped, 1)
+	logger.Tracef("connection %v write queue full, packet %v dropped",
+		ioModule.conn.GetId(), packet.GetId())
+}
+
+//disconnect closes the slow consumer once, the caller may hold locks of the
+//close callback, e.g. while broadcasting
+func (ioModule *IOModule) disconnect() {
+	if !atomic.CompareAndSwapInt32(&ioModule.slow, 0, 1) {
+		return
+	}
+	logger.Warnf("connection %v from %v write queue full, slow consumer closed",
+		ioModule.conn.GetId(), ioModule.conn.RemoteAddr())
+	go ioModule.Close()
+}
+
+//writeDone is called for the packets leaving the queue, written or dropped
+func (ioModule *IOModule) writeDone(count int32) {
+	n := atomic.AddInt32(&ioModule.writing, -count)
+	ioModule.lowWatermark(n)
+}
+
+func (ioModule *IOModule) highWatermark(n int32) {
+	high := atomic.LoadInt32(&ioModule.highMark)
+	if high <= 0 || n < high {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&ioModule.aboveHigh, 0, 1) {
+		return
+	}
+	handler, ok := ioModule.conn.(defs.IWatermarkHandler)
+	if ok {
+		handler.OnHighWatermark(int(n))
+	}
+}
+
+func (ioModule *IOModule) lowWatermark(n int32) {
+	if atomic.LoadInt32(&ioModule.aboveHigh) == 0 || n > atomic.LoadInt32(&ioModule.lowMark) {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&ioModule.aboveHigh, 1, 0) {
+		return
+	}
+	handler, ok := ioModule.conn.(defs.IWatermarkHandler)
+	if ok {
+		handler.OnLowWatermark(int(n))
+	}
+}
+
+//WriteQueueLen returns the number of packets queued or being written
+func (ioModule *IOModule) WriteQueueLen() int {
+	return int(atomic.LoadInt32(&ioModule.writing))
+}
+
+//EnableHeartbeat pings the peer after interval without reads and closes the
+//connection once nothing was read for idleTimeout, zero values disable each part.
+//A ping over a codec without the packet id would be an empty write the peer
+//never reads, so such codecs only get the idle timeout
+func (ioModule *IOModule) EnableHeartbeat(interval, idleTimeout time.Duration) {
+	if interval > 0 && !carriesId(ioModule.codec) {
+		logger.Warnf("connection %v codec %T carries no packet id, pings disabled",
+			ioModule.conn.GetId(), ioModule.codec)
+		interval = 0
+	}
+	if interval <= 0 && idleTimeout <= 0 {
+		return
+	}
+	tick := interval
+	if tick <= 0 || (idleTimeout > 0 && idleTimeout/2 < tick) {
+		tick = idleTimeout / 2
+	}
+	if tick <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ioModule.readClose:
+				return
+			case now := <-ticker.C:
+				if !ioModule.heartbeat(now, interval, idleTimeout) {
+					return
+				}
+			}
+		}
+	}()
+}
+
+func (ioModule *IOModule) heartbeat(now time.Time, interval, idleTimeout time.Duration) bool {
+	if ioModule.conn.IsClosed() {
+		return false
+	}
+	idle := now.Sub(time.Unix(0, atomic.LoadInt64(&ioModule.lastRead)))
+	if idleTimeout > 0 && idle >= idleTimeout {
+		logger.Warnf("connection %v idle %v, closed", ioModule.conn.GetId(), idle)
+		ioModule.Close()
+		return false
+	}
+	if interval > 0 && idle >= interval {
+		p := &defs.Packet{}
+		p.SetId(PingId)
+		ioModule.Write(p)
+	}
+	return true
+}
+
+func (ioModule *IOModule) readHeartbeat(packet defs.IPacket) bool {
+	switch packet.GetId() {
+	case PingId:
+		p := &defs.Packet{}
+		p.SetId(PongId)
+		p.SetSequence(packet.GetSequence())
+		ioModule.Write(p)
+		return true
+	case PongId:
+		return true
+	}
+	return false
+}
+
+//isEncodeError reports the errors of a packet the codec refused before
+//writing any of it, the connection stays usable
+func isEncodeError(err error) bool {
+	return errors.Is(err, ErrPacketTooLarge) || errors.Is(err, ErrUnknownMsg) ||
+		errors.Is(err, ErrEncodeFailed)
+}
+
+//onEncodeError drops the refused packet, a call awaiting it fails at once
+func (ioModule *IOModule) onEncodeError(packet defs.IPacket, err error) {
+	logger.Warnf("connection %v packet %v dropped: %v",
+		ioModule.conn.GetId(), packet.GetId(), err)
+	seq := packet.GetSequence()
+	iCall, ok := ioModule.pending.Load(seq)
+	if ok {
+		call, ok := iCall.(*RpcCall)
+		if ok && call.request == packet {
+			ioModule.pending.Delete(seq)
+			call.err = err
+			call.done()
+		}
+	}
+	handler, ok := ioModule.conn.(defs.ICodecErrorHandler)
+	if ok {
+		handler.OnCodecError(err)
+	}
+}
+
+func (ioModule *IOModule) onCodecError(err error) {
+	logger.Warnf("connection %v from %v closed: %v",
+		ioModule.conn.GetId(), ioModule.conn.RemoteAddr(), err)
+	handler, ok := ioModule.conn.(defs.ICodecErrorHandler)
+	if ok {
+		handler.OnCodecError(err)
 	}
 }
 
@@ -252,20 +660,148 @@ func (ioModule *IOModule) writeHandle() bool {
 		}
 	}()
 
-	for packet := range ioModule.writeQueue {
-		if packet == nil {
-			continue
+	for {
+		var packet defs.IPacket
+		select {
+		case packet = <-ioModule.writeQueue:
+		case <-ioModule.writeClose:
+			return true
 		}
-		err := ioModule.codec.Write(packet)
+		err := ioModule.writeBatch(packet)
 		if err != nil {
-			logger.Error(err)
-			break
+			logger.Errorf("connection %v write failed: %v", ioModule.conn.GetId(), err)
+			ioModule.Close()
+			return true
 		}
 		if len(ioModule.writeQueue) == 0 {
 			ioModule.conn.WriteComplete()
 		}
 	}
-	return true
+}
+
+//writeBatch encodes packet and the packets queued behind it into the codec
+//buffer and flushes them with a single write, a codec switch ends the batch
+func (ioModule *IOModule) writeBatch(packet defs.IPacket) error {
+	buffered, ok := ioModule.codec.(defs.IBufferedCodec)
+	if !ok {
+		return ioModule.writeOne(packet)
+	}
+
+	var deadline time.Time
+	delay := conf.GetGlobalVal().WriteDelay
+	if delay > 0 {
+		deadline = time.Now().Add(delay)
+	}
+	var n int32
+	var sw defs.IPacket
+	var err error
+	for packet != nil {
+		_, isSwitch := packet.(*codecSwitch)
+		if isSwitch {
+			sw = packet
+			break
+		}
+		err = ioModule.bufferPacket(buffered, packet)
+		n++
+		if isEncodeError(err) {
+			ioModule.onEncodeError(packet, err)
+			err = nil
+		}
+		if err != nil {
+			break
+		}
+		packet = ioModule.nextPacket(n, deadline)
+	}
+	//the packets buffered before a failing one still go out,
+	//the failing one is reported after them
+	flushErr := buffered.Flush()
+	if err == nil {
+		err = flushErr
+	}
+	ioModule.writeDone(n)
+
+	if sw == nil {
+		return err
+	}
+	if err != nil {
+		ioModule.writeDone(1)
+		return err
+	}
+	return ioModule.writeOne(sw)
+}
+
+//nextPacket takes the next queued packet of the batch, waiting until
+//deadline when the queue is empty, nil ends the batch
+func (ioModule *IOModule) nextPacket(n int32, deadline time.Time) defs.IPacket {
+	max := conf.GetGlobalVal().MaxWriteBatch
+	if max > 0 && n >= max {
+		return nil
+	}
+	select {
+	case packet := <-ioModule.writeQueue:
+		return packet
+	default:
+	}
+	wait := time.Until(deadline)
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case packet := <-ioModule.writeQueue:
+		return packet
+	case <-t.C:
+	case <-ioModule.writeClose:
+	}
+	return nil
+}
+
+func (ioModule *IOModule) writeOne(packet defs.IPacket) error {
+	defer ioModule.writeDone(1)
+	sw, ok := packet.(*codecSwitch)
+	if ok {
+		ioModule.switchCodec(sw.codec)
+		return nil
+	}
+	err := ioModule.writePacket(packet)
+	if isEncodeError(err) {
+		ioModule.onEncodeError(packet, err)
+		return nil
+	}
+	return err
+}
+
+func (ioModule *IOModule) bufferPacket(buffered defs.IBufferedCodec, packet defs.IPacket) error {
+	shared, ok := packet.(*SharedPacket)
+	if ok {
+		frameCodec, isFrame := ioModule.codec.(defs.IFrameCodec)
+		bufferedFrame, isBuffered := ioModule.codec.(defs.IBufferedFrameCodec)
+		if isFrame && isBuffered {
+			frame, err := shared.Frame(frameCodec)
+			if err != nil {
+				return err
+			}
+			return bufferedFrame.WriteFrameBuffered(frame)
+		}
+	}
+	return buffered.WriteBuffered(packet)
+}
+
+//shared packets are written as the frame encoded for the first member
+func (ioModule *IOModule) writePacket(packet defs.IPacket) error {
+	shared, ok := packet.(*SharedPacket)
+	if ok {
+		frameCodec, ok := ioModule.codec.(defs.IFrameCodec)
+		if ok {
+			frame, err := shared.Frame(frameCodec)
+			if err != nil {
+				return err
+			}
+			return frameCodec.WriteFrame(frame)
+		}
+	}
+	return ioModule.codec.Write(packet)
 }
 
 func (ioModule *IOModule) readHandle() bool {
@@ -283,9 +819,15 @@ func (ioModule *IOModule) readHandle() bool {
 		case <-ioModule.readClose:
 			quit = true
 		default:
-			packet, err := ioModule.codec.Read()
+			codec := ioModule.getCodec()
+			packet, err := codec.Read()
+			if err == nil && packet != nil {
+				packet, err = ioModule.readSwitched(codec, packet)
+			}
 			if err != nil {
-				if err != io.EOF && err != ErrConnClosed {
+				if err == ErrPacketTooLarge || err == ErrMalformedFrame || err == ErrHandshake {
+					ioModule.onCodecError(err)
+				} else if err != io.EOF && err != ErrConnClosed {
 					logger.Error(err)
 				}
 				quit = true
@@ -294,6 +836,17 @@ func (ioModule *IOModule) readHandle() bool {
 				if packet == nil {
 					continue
 				}
+				atomic.StoreInt64(&ioModule.lastRead, time.Now().UnixNano())
+				if ioModule.readHeartbeat(packet) {
+					continue
+				}
+				//the compress hello of a peer this codec does not negotiate with
+				if packet.GetId() == CompressId {
+					continue
+				}
+				if ioModule.readStream(packet) {
+					continue
+				}
 				if ioModule.readPending(packet) {
 					continue
 				}
@@ -303,6 +856,7 @@ func (ioModule *IOModule) readHandle() bool {
 	}
 
 	ioModule.pendDone()
+	ioModule.closeStreams()
 	ioModule.Close()
 	return quit
 }
@@ -0,0 +1,199 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package module
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/defs"
+)
+
+//slowConn never writes, the test drains the queue in place of the writer
+type slowConn struct {
+	defs.IConnection
+	closed int32
+	high   int32
+	low    int32
+}
+
+func (c *slowConn) GetId() string {
+	return "slow"
+}
+
+func (c *slowConn) RemoteAddr() string {
+	return "127.0.0.1:0"
+}
+
+func (c *slowConn) IsClosed() bool {
+	return atomic.LoadInt32(&c.closed) > 0
+}
+
+func (c *slowConn) Close() bool {
+	atomic.AddInt32(&c.closed, 1)
+	return true
+}
+
+func (c *slowConn) OnHighWatermark(n int) {
+	atomic.AddInt32(&c.high, 1)
+}
+
+func (c *slowConn) OnLowWatermark(n int) {
+	atomic.AddInt32(&c.low, 1)
+}
+
+func newSlowModule(size int32) (*IOModule, *slowConn) {
+	val := conf.GetGlobalVal()
+	old := val.MaxQueueSize
+	val.MaxQueueSize = size
+	defer func() {
+		val.MaxQueueSize = old
+	}()
+	conn := &slowConn{}
+	return NewIOModule(conn), conn
+}
+
+func idPacket(id string) defs.IPacket {
+	p := &defs.Packet{}
+	p.SetId(id)
+	return p
+}
+
+//take stands in for the writer taking one packet off the queue
+func (ioModule *IOModule) take() defs.IPacket {
+	packet := <-ioModule.writeQueue
+	ioModule.writeDone(1)
+	return packet
+}
+
+func TestWriteBlockTimeout(t *testing.T) {
+	m, _ := newSlowModule(1)
+	m.SetWritePolicy(defs.WriteBlock, 50*time.Millisecond)
+	if !m.write(idPacket("a")) {
+		t.Fatal("first write not queued")
+	}
+	begin := time.Now()
+	if m.write(idPacket("b")) {
+		t.Fatal("write to a full queue queued")
+	}
+	if time.Since(begin) < 50*time.Millisecond || m.Dropped() != 1 {
+		t.Fatalf("returned after %v, %v dropped", time.Since(begin), m.Dropped())
+	}
+
+	//a reader making room lets the blocked write through
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		m.take()
+	}()
+	if !m.write(idPacket("c")) {
+		t.Fatal("write not queued once there was room")
+	}
+	if p := m.take(); p.GetId() != "c" || m.WriteQueueLen() != 0 {
+		t.Fatalf("queued %v, %v left", p.GetId(), m.WriteQueueLen())
+	}
+}
+
+func TestWriteDropNewest(t *testing.T) {
+	m, conn := newSlowModule(2)
+	m.SetWritePolicy(defs.WriteDropNewest, 0)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		m.write(idPacket(id))
+	}
+	if m.Dropped() != 2 || m.WriteQueueLen() != 2 || conn.IsClosed() {
+		t.Fatalf("%v dropped, %v queued", m.Dropped(), m.WriteQueueLen())
+	}
+	if a, b := m.take(), m.take(); a.GetId() != "a" || b.GetId() != "b" {
+		t.Fatalf("kept %v %v", a.GetId(), b.GetId())
+	}
+}
+
+func TestWriteDropOldest(t *testing.T) {
+	m, conn := newSlowModule(2)
+	m.SetWritePolicy(defs.WriteDropOldest, 0)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		m.write(idPacket(id))
+	}
+	if m.Dropped() != 2 || m.WriteQueueLen() != 2 || conn.IsClosed() {
+		t.Fatalf("%v dropped, %v queued", m.Dropped(), m.WriteQueueLen())
+	}
+	if c, d := m.take(), m.take(); c.GetId() != "c" || d.GetId() != "d" {
+		t.Fatalf("kept %v %v", c.GetId(), d.GetId())
+	}
+}
+
+//the codec switch is never dropped, losing it would leave the peers on different codecs
+func TestWriteDropOldestCodecSwitch(t *testing.T) {
+	m, conn := newSlowModule(1)
+	m.SetWritePolicy(defs.WriteDropOldest, 0)
+	m.UpdateCodec(NewHeadCodec())
+	m.write(idPacket("a"))
+	waitConnClosed(t, conn)
+}
+
+func TestWriteDisconnect(t *testing.T) {
+	m, conn := newSlowModule(1)
+	m.SetWritePolicy(defs.WriteDisconnect, 0)
+	m.write(idPacket("a"))
+	if m.write(idPacket("b")) {
+		t.Fatal("write to a full queue queued")
+	}
+	m.write(idPacket("c"))
+	waitConnClosed(t, conn)
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&conn.closed); n != 1 {
+		t.Fatalf("closed %v times", n)
+	}
+}
+
+func waitConnClosed(t *testing.T, conn *slowConn) {
+	deadline := time.Now().Add(3 * time.Second)
+	for !conn.IsClosed() {
+		if time.Now().After(deadline) {
+			t.Fatal("connection not closed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+//each crossing is reported once, the low mark rearms the high mark
+func TestWriteWatermark(t *testing.T) {
+	m, conn := newSlowModule(8)
+	m.SetWatermark(4, 1)
+	for i := 0; i < 5; i++ {
+		m.write(idPacket("a"))
+	}
+	if conn.high != 1 || conn.low != 0 {
+		t.Fatalf("high %v low %v", conn.high, conn.low)
+	}
+	m.take()
+	m.take()
+	m.take()
+	if conn.low != 0 {
+		t.Fatal("low reported above the low mark")
+	}
+	m.take()
+	if conn.low != 1 {
+		t.Fatalf("low %v at the low mark", conn.low)
+	}
+	m.take()
+	if conn.low != 1 {
+		t.Fatal("low reported twice")
+	}
+	for i := 0; i < 4; i++ {
+		m.write(idPacket("a"))
+	}
+	if conn.high != 2 {
+		t.Fatalf("high %v after rearming", conn.high)
+	}
+
+	//low at or above high falls back to half of high
+	m.SetWatermark(4, 4)
+	if atomic.LoadInt32(&m.lowMark) != 2 {
+		t.Fatalf("low mark %v", m.lowMark)
+	}
+}
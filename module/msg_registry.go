@@ -0,0 +1,86 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package module
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+)
+
+var defaultMsgRegistry = NewMsgRegistry()
+
+func GetMsgRegistry() *MsgRegistry {
+	return defaultMsgRegistry
+}
+
+func RegisterMsg(id uint32, msg proto.Message) error {
+	return defaultMsgRegistry.Register(id, msg)
+}
+
+//MsgRegistry maps numeric message ids to protobuf message types
+type MsgRegistry struct {
+	mux   sync.RWMutex
+	types map[uint32]reflect.Type
+	ids   map[reflect.Type]uint32
+}
+
+func NewMsgRegistry() *MsgRegistry {
+	return &MsgRegistry{
+		types: make(map[uint32]reflect.Type),
+		ids:   make(map[reflect.Type]uint32),
+	}
+}
+
+func (r *MsgRegistry) Register(id uint32, msg proto.Message) error {
+	if msg == nil {
+		return fmt.Errorf("msg id %v: message is nil", id)
+	}
+	//0 is the id of packets carrying no message
+	if id == 0 || id >= protoPongId {
+		return fmt.Errorf("msg id %v is reserved", id)
+	}
+	typ := reflect.TypeOf(msg)
+	if typ.Kind() != reflect.Ptr {
+		return fmt.Errorf("msg id %v: %v is not a pointer", id, typ)
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if old, ok := r.types[id]; ok && old != typ {
+		return fmt.Errorf("msg id %v already registered by %v", id, old)
+	}
+	if oldId, ok := r.ids[typ]; ok && oldId != id {
+		return fmt.Errorf("%v already registered as msg id %v", typ, oldId)
+	}
+	r.types[id] = typ
+	r.ids[typ] = id
+	return nil
+}
+
+func (r *MsgRegistry) NewMsg(id uint32) (proto.Message, bool) {
+	r.mux.RLock()
+	typ, ok := r.types[id]
+	r.mux.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	msg, ok := reflect.New(typ.Elem()).Interface().(proto.Message)
+	return msg, ok
+}
+
+func (r *MsgRegistry) GetMsgId(msg interface{}) (uint32, bool) {
+	if msg == nil {
+		return 0, false
+	}
+	r.mux.RLock()
+	id, ok := r.ids[reflect.TypeOf(msg)]
+	r.mux.RUnlock()
+	return id, ok
+}
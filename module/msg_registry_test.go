@@ -0,0 +1,33 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package module
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+func TestMsgRegistryReserved(t *testing.T) {
+	r := NewMsgRegistry()
+	for _, id := range []uint32{0, protoPongId, protoPingId} {
+		if r.Register(id, &wrappers.StringValue{}) == nil {
+			t.Fatalf("reserved msg id %v registered", id)
+		}
+	}
+	if err := r.Register(1, &wrappers.StringValue{}); err != nil {
+		t.Fatal(err)
+	}
+	if r.Register(1, &wrappers.Int64Value{}) == nil {
+		t.Fatal("msg id registered twice")
+	}
+	if r.Register(2, &wrappers.StringValue{}) == nil {
+		t.Fatal("message registered under two ids")
+	}
+	if id, ok := r.GetMsgId(&wrappers.StringValue{}); !ok || id != 1 {
+		t.Fatalf("msg id %v", id)
+	}
+}
@@ -0,0 +1,63 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package module
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/lightning-go/lightning/defs"
+)
+
+type sharedFrame struct {
+	data []byte
+	err  error
+}
+
+//SharedPacket is written to many connections without copying, codecs
+//implementing IFrameCodec encode it once per codec type
+type SharedPacket struct {
+	defs.IPacket
+	mux    sync.Mutex
+	frames map[reflect.Type]*sharedFrame
+}
+
+func NewSharedPacket(packet defs.IPacket) *SharedPacket {
+	return &SharedPacket{
+		IPacket: packet,
+		frames:  make(map[reflect.Type]*sharedFrame),
+	}
+}
+
+func (sp *SharedPacket) GetMsg() interface{} {
+	msgPacket, ok := sp.IPacket.(defs.IMsgPacket)
+	if !ok {
+		return nil
+	}
+	return msgPacket.GetMsg()
+}
+
+func (sp *SharedPacket) SetMsg(msg interface{}) {
+	msgPacket, ok := sp.IPacket.(defs.IMsgPacket)
+	if ok {
+		msgPacket.SetMsg(msg)
+	}
+}
+
+//Frame returns the frame encoded by the first connection using the codec type
+func (sp *SharedPacket) Frame(codec defs.IFrameCodec) ([]byte, error) {
+	codecType := reflect.TypeOf(codec)
+	sp.mux.Lock()
+	defer sp.mux.Unlock()
+
+	frame, ok := sp.frames[codecType]
+	if !ok {
+		frame = &sharedFrame{}
+		frame.data, frame.err = codec.EncodeFrame(sp.IPacket)
+		sp.frames[codecType] = frame
+	}
+	return frame.data, frame.err
+}
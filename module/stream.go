@@ -0,0 +1,322 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package module
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/defs"
+)
+
+var (
+	ErrStreamClosed   = errors.New("stream closed")
+	ErrStreamExists   = errors.New("stream already exists")
+	ErrStreamOverflow = errors.New("stream window exceeded")
+	ErrNotStream      = errors.New("packet does not open a stream")
+)
+
+//stream sequences carry streamFlag, the sequences of the requests awaited on
+//either side never do, so replies and streams are told apart before any lookup.
+//the other bits are random, the streams opened by both sides do not collide
+const streamFlag = uint64(1) << 63
+
+func isStreamSeq(seq uint64) bool {
+	return seq&streamFlag != 0
+}
+
+func newStreamSeq() uint64 {
+	var b [8]byte
+	rand.Read(b[:])
+	return binary.BigEndian.Uint64(b[:]) | streamFlag
+}
+
+//a locally closed stream keeps swallowing late packets until the peer confirms
+const streamLinger = time.Second * 30
+
+//Stream carries packets in both directions under the sequence of the request
+//opening it. each side may send GlobalVal.StreamWindow packets ahead of what the
+//peer has received, the receiver grants more with StreamAckId as it consumes.
+//it ends when either side closes it or the connection is lost
+type Stream struct {
+	ioModule *IOModule
+	seq      uint64
+	window   int32
+	credits  int32
+	consumed int32
+	recv     chan defs.IPacket
+	granted  chan struct{}
+	done     chan struct{}
+	doneOnce sync.Once
+	err      error
+}
+
+func newStream(ioModule *IOModule, seq uint64) *Stream {
+	window := conf.GetGlobalVal().StreamWindow
+	if window <= 0 {
+		window = 1
+	}
+	return &Stream{
+		ioModule: ioModule,
+		seq:      seq,
+		window:   window,
+		credits:  window,
+		//room for the reply of the request opening the stream
+		recv:     make(chan defs.IPacket, window+1),
+		granted:  make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *Stream) Sequence() uint64 {
+	return s.seq
+}
+
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+//Err returns why the stream ended, nil while it is open
+func (s *Stream) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+func (s *Stream) Send(packet defs.IPacket) error {
+	return s.SendCtx(context.Background(), packet)
+}
+
+//SendCtx waits while the peer window is full
+func (s *Stream) SendCtx(ctx context.Context, packet defs.IPacket) error {
+	if packet == nil {
+		return nil
+	}
+	for !s.takeCredit() {
+		select {
+		case <-s.granted:
+		case <-s.done:
+			return s.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	s.ioModule.Write(streamPacket(packet, s.seq))
+	return nil
+}
+
+func (s *Stream) takeCredit() bool {
+	for {
+		credits := atomic.LoadInt32(&s.credits)
+		if credits <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&s.credits, credits, credits-1) {
+			return true
+		}
+	}
+}
+
+func (s *Stream) Recv() (defs.IPacket, error) {
+	return s.RecvCtx(context.Background())
+}
+
+//RecvCtx returns the packets already received before reporting the end of the stream
+func (s *Stream) RecvCtx(ctx context.Context) (defs.IPacket, error) {
+	select {
+	case packet := <-s.recv:
+		s.consume()
+		return packet, nil
+	default:
+	}
+
+	select {
+	case packet := <-s.recv:
+		s.consume()
+		return packet, nil
+	case <-s.done:
+		select {
+		case packet := <-s.recv:
+			return packet, nil
+		default:
+			return nil, s.err
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Stream) consume() {
+	consumed := atomic.AddInt32(&s.consumed, 1)
+	if consumed < (s.window+1)/2 {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&s.consumed, consumed, 0) {
+		return
+	}
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, uint32(consumed))
+	s.writeControl(StreamAckId, data)
+}
+
+//Close ends the stream on both sides
+func (s *Stream) Close() error {
+	if !s.finish(ErrStreamClosed, true) {
+		return nil
+	}
+	time.AfterFunc(streamLinger, func() {
+		s.ioModule.dropStream(s)
+	})
+	return nil
+}
+
+func (s *Stream) finish(err error, notify bool) bool {
+	finished := false
+	s.doneOnce.Do(func() {
+		s.err = err
+		close(s.done)
+		finished = true
+	})
+	if finished && notify {
+		s.writeControl(StreamCloseId, nil)
+	}
+	return finished
+}
+
+func (s *Stream) writeControl(id string, data []byte) {
+	p := &defs.Packet{}
+	p.SetId(id)
+	p.SetSequence(s.seq)
+	p.SetData(data)
+	s.ioModule.Write(p)
+}
+
+func (s *Stream) deliver(packet defs.IPacket) {
+	if s.Err() != nil {
+		return
+	}
+	select {
+	case s.recv <- packet:
+	default:
+		s.finish(ErrStreamOverflow, true)
+	}
+}
+
+func (s *Stream) grant(data []byte) {
+	if len(data) < 4 {
+		return
+	}
+	atomic.AddInt32(&s.credits, int32(binary.BigEndian.Uint32(data)))
+	select {
+	case s.granted <- struct{}{}:
+	default:
+	}
+}
+
+//pushed packets may be shared by several streams, so they are copied
+func streamPacket(packet defs.IPacket, seq uint64) defs.IPacket {
+	p := &defs.MsgPacket{}
+	p.SetId(packet.GetId())
+	p.SetSessionId(packet.GetSessionId())
+	p.SetStatus(packet.GetStatus())
+	p.SetData(packet.GetData())
+	p.SetSequence(seq)
+	msgPacket, ok := packet.(defs.IMsgPacket)
+	if ok {
+		p.SetMsg(msgPacket.GetMsg())
+	}
+	return p
+}
+
+//OpenStream writes the request opening a stream, the peer accepts it with AcceptStream
+func (ioModule *IOModule) OpenStream(packet defs.IPacket) (defs.IStream, error) {
+	if packet == nil {
+		return nil, ErrStreamClosed
+	}
+	if ioModule.conn.IsClosed() {
+		return nil, ErrConnClosed
+	}
+	s := newStream(ioModule, newStreamSeq())
+	for {
+		_, loaded := ioModule.streams.LoadOrStore(s.seq, s)
+		if !loaded {
+			break
+		}
+		s.seq = newStreamSeq()
+	}
+	ioModule.Write(streamPacket(packet, s.seq))
+	return s, nil
+}
+
+//AcceptStream attaches a stream to the sequence of a received request
+func (ioModule *IOModule) AcceptStream(packet defs.IPacket) (defs.IStream, error) {
+	if packet == nil {
+		return nil, ErrStreamClosed
+	}
+	if ioModule.conn.IsClosed() {
+		return nil, ErrConnClosed
+	}
+	if !isStreamSeq(packet.GetSequence()) {
+		return nil, ErrNotStream
+	}
+	s := newStream(ioModule, packet.GetSequence())
+	_, loaded := ioModule.streams.LoadOrStore(s.seq, s)
+	if loaded {
+		return nil, ErrStreamExists
+	}
+	return s, nil
+}
+
+func (ioModule *IOModule) dropStream(s *Stream) {
+	v, ok := ioModule.streams.Load(s.seq)
+	if ok && v == s {
+		ioModule.streams.Delete(s.seq)
+	}
+}
+
+func (ioModule *IOModule) readStream(packet defs.IPacket) bool {
+	if !isStreamSeq(packet.GetSequence()) {
+		return false
+	}
+	id := packet.GetId()
+	isControl := id == StreamAckId || id == StreamCloseId
+	v, ok := ioModule.streams.Load(packet.GetSequence())
+	if !ok {
+		return isControl
+	}
+	s := v.(*Stream)
+
+	switch id {
+	case StreamAckId:
+		s.grant(packet.GetData())
+	case StreamCloseId:
+		ioModule.dropStream(s)
+		s.finish(ErrStreamClosed, true)
+	default:
+		s.deliver(packet)
+	}
+	return true
+}
+
+func (ioModule *IOModule) closeStreams() {
+	ioModule.streams.Range(func(key, value interface{}) bool {
+		ioModule.streams.Delete(key)
+		value.(*Stream).finish(ErrConnClosed, false)
+		return true
+	})
+}
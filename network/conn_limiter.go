@@ -0,0 +1,104 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package network
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/lightning-go/lightning/conf"
+)
+
+var (
+	ErrServerFull = errors.New("server connection limit reached")
+	ErrIpLimit    = errors.New("ip connection limit reached")
+)
+
+type ConnLimiter struct {
+	mux      sync.Mutex
+	maxConn  int
+	maxPerIp int
+	count    int
+	ipCount  map[string]int
+}
+
+func NewConnLimiter(maxConn int) *ConnLimiter {
+	cl := &ConnLimiter{
+		ipCount: make(map[string]int),
+	}
+	cl.SetMaxConn(maxConn)
+	return cl
+}
+
+//maxConn <= 0 falls back to GlobalVal.MaxConnNum
+func (cl *ConnLimiter) SetMaxConn(maxConn int) {
+	if maxConn <= 0 {
+		maxConn = conf.GetGlobalVal().MaxConnNum
+	}
+	cl.mux.Lock()
+	cl.maxConn = maxConn
+	cl.mux.Unlock()
+}
+
+//maxPerIp <= 0 means unlimited
+func (cl *ConnLimiter) SetMaxConnPerIp(maxPerIp int) {
+	cl.mux.Lock()
+	cl.maxPerIp = maxPerIp
+	cl.mux.Unlock()
+}
+
+func (cl *ConnLimiter) Count() int {
+	cl.mux.Lock()
+	count := cl.count
+	cl.mux.Unlock()
+	return count
+}
+
+func (cl *ConnLimiter) Acquire(addr string) error {
+	ip := hostOfAddr(addr)
+
+	cl.mux.Lock()
+	defer cl.mux.Unlock()
+
+	if cl.maxConn > 0 && cl.count >= cl.maxConn {
+		return ErrServerFull
+	}
+	if cl.maxPerIp > 0 && cl.ipCount[ip] >= cl.maxPerIp {
+		return ErrIpLimit
+	}
+	cl.count++
+	cl.ipCount[ip]++
+	return nil
+}
+
+func (cl *ConnLimiter) Release(addr string) {
+	ip := hostOfAddr(addr)
+
+	cl.mux.Lock()
+	defer cl.mux.Unlock()
+
+	if cl.count > 0 {
+		cl.count--
+	}
+	n, ok := cl.ipCount[ip]
+	if !ok {
+		return
+	}
+	if n <= 1 {
+		delete(cl.ipCount, ip)
+	} else {
+		cl.ipCount[ip] = n - 1
+	}
+}
+
+func hostOfAddr(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,97 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/module"
+)
+
+func TestConnLimiter(t *testing.T) {
+	cl := NewConnLimiter(3)
+	cl.SetMaxConnPerIp(2)
+
+	if cl.Acquire("10.0.0.1:1000") != nil || cl.Acquire("10.0.0.1:1001") != nil {
+		t.Fatal("connections under the limits refused")
+	}
+	if err := cl.Acquire("10.0.0.1:1002"); err != ErrIpLimit {
+		t.Fatalf("third connection of an ip: %v", err)
+	}
+	if err := cl.Acquire("10.0.0.2:1000"); err != nil {
+		t.Fatalf("connection of another ip: %v", err)
+	}
+	if err := cl.Acquire("10.0.0.3:1000"); err != ErrServerFull {
+		t.Fatalf("connection over the server limit: %v", err)
+	}
+
+	//the port does not matter on release
+	cl.Release("10.0.0.1:2000")
+	if err := cl.Acquire("10.0.0.1:1003"); err != nil {
+		t.Fatalf("connection after a release: %v", err)
+	}
+	if cl.Count() != 3 {
+		t.Fatalf("count %v", cl.Count())
+	}
+
+	cl.SetMaxConnPerIp(0)
+	cl.Release("10.0.0.2:1000")
+	if err := cl.Acquire("10.0.0.1:1004"); err != nil {
+		t.Fatalf("unlimited per ip: %v", err)
+	}
+}
+
+func TestServerIpLimit(t *testing.T) {
+	rejected := make(chan error, 4)
+	srv := newServer(module.NewHeadCodec(), nil)
+	srv.SetMaxConnPerIp(2)
+	srv.SetRejectCallback(func(addr string, reason error) {
+		rejected <- reason
+	})
+	reject := &defs.Packet{}
+	reject.SetId("full")
+	srv.SetRejectPacket(reject)
+	srv.Serve()
+	defer srv.Shutdown(0)
+
+	first := dial(t, srv.Host(), module.NewHeadCodec(), nil)
+	dial(t, srv.Host(), module.NewHeadCodec(), nil)
+
+	received := make(chan defs.IPacket, 1)
+	third := dial(t, srv.Host(), module.NewHeadCodec(), func(conn defs.IConnection, packet defs.IPacket) {
+		received <- packet
+	})
+	select {
+	case err := <-rejected:
+		if err != ErrIpLimit {
+			t.Fatalf("rejected for %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("third connection of the ip not rejected")
+	}
+	if p := recvPacket(t, received); p.GetId() != "full" {
+		t.Fatalf("reject packet %v", p.GetId())
+	}
+	waitClosed(t, third.GetConn())
+
+	//a closed connection frees its slot
+	first.Close()
+	deadline := time.Now().Add(3 * time.Second)
+	for srv.connLimiter.Count() > 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("slot not released")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	dial(t, srv.Host(), module.NewHeadCodec(), nil)
+	select {
+	case err := <-rejected:
+		t.Fatalf("connection after a release rejected: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,239 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package network
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/logger"
+	"github.com/lightning-go/lightning/module"
+)
+
+//newServer is configured by the caller, then Serve
+func newServer(codec defs.ICodec, cb defs.MsgCallback) *TcpServer {
+	logger.SetLevel(logger.FATAL)
+	srv := NewTcpServer("127.0.0.1:0", "test", 0)
+	srv.SetCodec(codec)
+	srv.SetMsgCallback(cb)
+	return srv
+}
+
+func dial(tb testing.TB, addr string, codec defs.ICodec, cb defs.MsgCallback) *TcpClient {
+	client := NewTcpClient("test_cli", addr)
+	client.SetRetry(false)
+	client.SetCodec(codec)
+	client.SetMsgCallback(cb)
+	if client.Connect() == nil {
+		tb.Fatal("connect failed")
+	}
+	return client
+}
+
+func recvPacket(tb testing.TB, ch chan defs.IPacket) defs.IPacket {
+	select {
+	case packet := <-ch:
+		return packet
+	case <-time.After(3 * time.Second):
+		tb.Fatal("no packet received")
+	}
+	return nil
+}
+
+func waitClosed(tb testing.TB, conn defs.IConnection) {
+	deadline := time.Now().Add(3 * time.Second)
+	for !conn.IsClosed() {
+		if time.Now().After(deadline) {
+			tb.Fatal("connection not closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+//a packet the codec refuses is dropped, the next one still goes out
+func TestWriteOversizedPacket(t *testing.T) {
+	codecErrs := make(chan error, 4)
+	awaitErr := make(chan error, 2)
+	big := make([]byte, 32)
+	srv := newServer(&module.HeadCodec{}, func(conn defs.IConnection, packet defs.IPacket) {
+		conn.WriteData(big)
+		conn.WriteData([]byte("ok"))
+		go func() {
+			_, err := conn.WriteDataAwait(big)
+			awaitErr <- err
+		}()
+	})
+	srv.SetMaxPacketSize(16)
+	srv.SetCodecErrorCallback(func(conn defs.IConnection, err error) {
+		codecErrs <- err
+	})
+	srv.Serve()
+	defer srv.Shutdown(0)
+
+	received := make(chan defs.IPacket, 4)
+	client := dial(t, srv.Host(), &module.HeadCodec{}, func(conn defs.IConnection, packet defs.IPacket) {
+		received <- packet
+	})
+	defer client.Close()
+	client.SendData([]byte("go"))
+
+	if data := string(recvPacket(t, received).GetData()); data != "ok" {
+		t.Fatalf("received %q, want the packet after the oversized one", data)
+	}
+	select {
+	case err := <-codecErrs:
+		if !errors.Is(err, module.ErrPacketTooLarge) {
+			t.Fatalf("codec error %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("codec error callback not called")
+	}
+	select {
+	case err := <-awaitErr:
+		if !errors.Is(err, module.ErrPacketTooLarge) {
+			t.Fatalf("await error %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("await of a dropped packet did not return")
+	}
+	//the server side connection is still open
+	client.SendData([]byte("go"))
+	if data := string(recvPacket(t, received).GetData()); data != "ok" {
+		t.Fatalf("received %q after the dropped packets", data)
+	}
+}
+
+var errBoom = errors.New("boom")
+
+//failCodec fails the buffered write of the packets with id fail
+type failCodec struct {
+	module.HeadCodec
+}
+
+func (fc *failCodec) WriteBuffered(packet defs.IPacket) error {
+	if packet.GetId() == "fail" {
+		return errBoom
+	}
+	return fc.HeadCodec.WriteBuffered(packet)
+}
+
+//a write failing mid batch still flushes the packets buffered before it
+func TestWriteBatchFailure(t *testing.T) {
+	val := conf.GetGlobalVal()
+	old := val.WriteDelay
+	val.WriteDelay = 50 * time.Millisecond
+	defer func() {
+		val.WriteDelay = old
+	}()
+
+	srv := newServer(&failCodec{}, func(conn defs.IConnection, packet defs.IPacket) {
+		conn.WriteData([]byte("a"))
+		conn.WriteData([]byte("b"))
+		conn.WriteDataById("fail", []byte("c"))
+	})
+	srv.Serve()
+	defer srv.Shutdown(0)
+
+	received := make(chan defs.IPacket, 4)
+	client := dial(t, srv.Host(), &module.HeadCodec{}, func(conn defs.IConnection, packet defs.IPacket) {
+		received <- packet
+	})
+	client.SendData([]byte("go"))
+
+	for _, want := range []string{"a", "b"} {
+		if data := string(recvPacket(t, received).GetData()); data != want {
+			t.Fatalf("received %q, want %q", data, want)
+		}
+	}
+	waitClosed(t, client.GetConn())
+}
+
+//a peer that never sends its key gets the connection closed once a write
+//times out waiting for it
+func TestEncryptHandshakeTimeout(t *testing.T) {
+	val := conf.GetGlobalVal()
+	old := val.HttpTimeout
+	val.HttpTimeout = 50 * time.Millisecond
+	defer func() {
+		val.HttpTimeout = old
+	}()
+
+	srv := newServer(module.NewEncryptCodec(module.NewHeadCodec()), nil)
+	srv.SetConnCallback(func(conn defs.IConnection) {
+		if !conn.IsClosed() {
+			conn.WriteData([]byte("hello"))
+		}
+	})
+	srv.Serve()
+	defer srv.Shutdown(0)
+
+	client := dial(t, srv.Host(), module.NewHeadCodec(), nil)
+	waitClosed(t, client.GetConn())
+}
+
+//a plain peer gets plain frames back and never sees the compress hello
+func TestCompressPlainPeer(t *testing.T) {
+	codecErrs := make(chan error, 1)
+	srv := newServer(module.NewCompressCodec(module.NewHeadCodec(), module.CompressGzip, 0),
+		func(conn defs.IConnection, packet defs.IPacket) {
+			conn.WritePacket(packet)
+		})
+	srv.SetCodecErrorCallback(func(conn defs.IConnection, err error) {
+		codecErrs <- err
+	})
+	srv.Serve()
+	defer srv.Shutdown(0)
+
+	received := make(chan defs.IPacket, 4)
+	client := dial(t, srv.Host(), module.NewHeadCodec(), func(conn defs.IConnection, packet defs.IPacket) {
+		received <- packet
+	})
+	defer client.Close()
+
+	//the first byte looks like a flag
+	large := append([]byte{module.CompressGzip}, bytes.Repeat([]byte("lightning "), 512)...)
+	for i := 0; i < 2; i++ {
+		client.SendData(large)
+		p := recvPacket(t, received)
+		if p.GetId() == module.CompressId || !bytes.Equal(p.GetData(), large) {
+			t.Fatalf("echo %v of %v bytes", p.GetId(), len(p.GetData()))
+		}
+	}
+	select {
+	case err := <-codecErrs:
+		t.Fatalf("codec error %v", err)
+	default:
+	}
+}
+
+func TestCompressPeers(t *testing.T) {
+	codec := func() defs.ICodec {
+		return module.NewCompressCodec(module.NewHeadCodec(), module.CompressZlib, 0)
+	}
+	srv := newServer(codec(), func(conn defs.IConnection, packet defs.IPacket) {
+		conn.WritePacket(packet)
+	})
+	srv.Serve()
+	defer srv.Shutdown(0)
+
+	received := make(chan defs.IPacket, 4)
+	client := dial(t, srv.Host(), codec(), func(conn defs.IConnection, packet defs.IPacket) {
+		received <- packet
+	})
+	defer client.Close()
+
+	large := bytes.Repeat([]byte("lightning "), 512)
+	for i := 0; i < 3; i++ {
+		client.SendData(large)
+		if p := recvPacket(t, received); !bytes.Equal(p.GetData(), large) {
+			t.Fatalf("echo of %v bytes", len(p.GetData()))
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"context"
 	"net"
 	"sync/atomic"
+	"time"
 
 	"github.com/lightning-go/lightning/defs"
 	"github.com/lightning-go/lightning/logger"
@@ -30,6 +31,11 @@ type Connection struct {
 	isClosed      int32
 	isAuthorized  bool
 	ctx           context.Context
+	heartbeat     time.Duration
+	idleTimeout   time.Duration
+	maxPacketSize int
+	codecErr      defs.CodecErrorCallback
+	limit         writeLimit
 }
 
 func NewConnection(conn net.Conn) *Connection {
@@ -60,6 +66,29 @@ func (c *Connection) SetIOModule(ioModule defs.IIOModule) {
 	c.ioModule = ioModule
 }
 
+func (c *Connection) SetHeartbeat(interval, idleTimeout time.Duration) {
+	c.heartbeat = interval
+	c.idleTimeout = idleTimeout
+}
+
+func (c *Connection) SetMaxPacketSize(size int) {
+	c.maxPacketSize = size
+}
+
+func (c *Connection) GetMaxPacketSize() int {
+	return c.maxPacketSize
+}
+
+func (c *Connection) SetCodecErrorCallback(cb defs.CodecErrorCallback) {
+	c.codecErr = cb
+}
+
+func (c *Connection) OnCodecError(err error) {
+	if c.codecErr != nil {
+		c.codecErr(c, err)
+	}
+}
+
 func (c *Connection) SetConnCallback(cb defs.ConnCallback) {
 	c.connCallback = cb
 }
@@ -80,6 +109,44 @@ func (c *Connection) SetWriteCompleteCallback(cb defs.WriteCompleteCallback) {
 	c.writeComplete = cb
 }
 
+//SetWritePolicy decides what writes do once the write queue is full,
+//timeout bounds the wait of WriteBlock
+func (c *Connection) SetWritePolicy(policy defs.WritePolicy, timeout time.Duration) {
+	c.limit.policy = policy
+	c.limit.timeout = timeout
+	if c.ioModule != nil {
+		c.limit.apply(c.ioModule)
+	}
+}
+
+//cb is called once the write queue length reaches mark, mark <= 0 disables
+func (c *Connection) SetHighWatermarkCallback(cb defs.WatermarkCallback, mark int) {
+	c.limit.highCallback = cb
+	c.limit.highMark = mark
+}
+
+//cb is called once the queue falls back to mark after reaching the high watermark
+func (c *Connection) SetLowWatermarkCallback(cb defs.WatermarkCallback, mark int) {
+	c.limit.lowCallback = cb
+	c.limit.lowMark = mark
+}
+
+func (c *Connection) setWriteLimit(limit writeLimit) {
+	c.limit = limit
+}
+
+func (c *Connection) OnHighWatermark(n int) {
+	if c.limit.highCallback != nil {
+		c.limit.highCallback(c, n)
+	}
+}
+
+func (c *Connection) OnLowWatermark(n int) {
+	if c.limit.lowCallback != nil {
+		c.limit.lowCallback(c, n)
+	}
+}
+
 func (c *Connection) SetContext(key, value interface{}) {
 	utils.SetMapContext(c.ctx, key, value)
 }
@@ -130,11 +197,13 @@ func (c *Connection) Start() bool {
 			return false
 		}
 	}
+	c.limit.apply(c.ioModule)
 	ok := c.ioModule.Codec(c.codec)
 	if !ok {
 		logger.Error("io module codec error")
 		return false
 	}
+	c.ioModule.EnableHeartbeat(c.heartbeat, c.idleTimeout)
 	c.OnConnection()
 	return true
 }
@@ -162,13 +231,20 @@ func (c *Connection) Close() bool {
 	return true
 }
 
+func (c *Connection) WriteQueueLen() int {
+	if c.ioModule == nil {
+		return 0
+	}
+	return c.ioModule.WriteQueueLen()
+}
+
 func (c *Connection) WriteComplete() {
 	if c.writeComplete != nil {
 		c.writeComplete(c)
 	}
 }
 
-func (c *Connection) write(packet defs.IPacket, await bool) (defs.IPacket, error) {
+func (c *Connection) write(ctx context.Context, packet defs.IPacket, await bool) (defs.IPacket, error) {
 	if c.IsClosed() {
 		return nil, nil
 	}
@@ -176,44 +252,70 @@ func (c *Connection) write(packet defs.IPacket, await bool) (defs.IPacket, error
 		return nil, nil
 	}
 	if await {
-		return c.ioModule.WriteAwait(packet)
+		return c.ioModule.WriteAwaitCtx(ctx, packet)
 	}
 	c.ioModule.Write(packet)
 	return nil, nil
 }
 
-func (c *Connection) writeData(id string, data []byte, await bool) (defs.IPacket, error) {
+func (c *Connection) writeData(ctx context.Context, id string, data []byte, await bool) (defs.IPacket, error) {
 	if data == nil || len(data) == 0 {
 		return nil, nil
 	}
 	p := &defs.Packet{}
 	p.SetId(id)
 	p.SetData(data)
-	return c.write(p, await)
+	return c.write(ctx, p, await)
 }
 
 func (c *Connection) WriteData(data []byte) {
-	c.writeData("", data, false)
+	c.writeData(context.Background(), "", data, false)
 }
 
 func (c *Connection) WriteDataById(id string, data []byte) {
-	c.writeData(id, data, false)
+	c.writeData(context.Background(), id, data, false)
 }
 
 func (c *Connection) WritePacket(packet defs.IPacket) {
-	c.write(packet, false)
+	c.write(context.Background(), packet, false)
 }
 
 func (c *Connection) WriteDataAwait(data []byte) (defs.IPacket, error) {
-	return c.writeData("", data, true)
+	return c.writeData(context.Background(), "", data, true)
 }
 
 func (c *Connection) WriteDataByIdAwait(id string, data []byte) (defs.IPacket, error) {
-	return c.writeData(id, data, true)
+	return c.writeData(context.Background(), id, data, true)
 }
 
 func (c *Connection) WritePacketAwait(packet defs.IPacket) (defs.IPacket, error) {
-	return c.write(packet, true)
+	return c.write(context.Background(), packet, true)
+}
+
+func (c *Connection) WriteDataAwaitCtx(ctx context.Context, data []byte) (defs.IPacket, error) {
+	return c.writeData(ctx, "", data, true)
+}
+
+func (c *Connection) WriteDataByIdAwaitCtx(ctx context.Context, id string, data []byte) (defs.IPacket, error) {
+	return c.writeData(ctx, id, data, true)
+}
+
+func (c *Connection) WritePacketAwaitCtx(ctx context.Context, packet defs.IPacket) (defs.IPacket, error) {
+	return c.write(ctx, packet, true)
+}
+
+func (c *Connection) OpenStream(packet defs.IPacket) (defs.IStream, error) {
+	if c.IsClosed() || c.ioModule == nil {
+		return nil, module.ErrConnClosed
+	}
+	return c.ioModule.OpenStream(packet)
+}
+
+func (c *Connection) AcceptStream(packet defs.IPacket) (defs.IStream, error) {
+	if c.IsClosed() || c.ioModule == nil {
+		return nil, module.ErrConnClosed
+	}
+	return c.ioModule.AcceptStream(packet)
 }
 
 func (c *Connection) ReadPacket(packet defs.IPacket) {
@@ -54,14 +54,48 @@ func (cm *ConnectionMgr) GetConn(connId string) defs.IConnection {
 	return conn
 }
 
+func (cm *ConnectionMgr) RangeConn(f func(defs.IConnection) bool) {
+	cm.mux.RLock()
+	conns := make([]defs.IConnection, 0, len(cm.conns))
+	for _, conn := range cm.conns {
+		conns = append(conns, conn)
+	}
+	cm.mux.RUnlock()
+
+	for _, conn := range conns {
+		if conn == nil {
+			continue
+		}
+		if !f(conn) {
+			break
+		}
+	}
+}
+
+func (cm *ConnectionMgr) WriteQueueLen() int {
+	n := 0
+	cm.mux.RLock()
+	for _, conn := range cm.conns {
+		if conn == nil {
+			continue
+		}
+		n += conn.WriteQueueLen()
+	}
+	cm.mux.RUnlock()
+	return n
+}
+
 func (cm *ConnectionMgr) Clean() {
 	cm.mux.Lock()
-	for _, conn := range cm.conns {
+	conns := cm.conns
+	cm.conns = make(map[string]defs.IConnection)
+	cm.mux.Unlock()
+
+	//close outside the lock, the close callback deletes from the manager
+	for _, conn := range conns {
 		if conn == nil {
 			continue
 		}
 		conn.Close()
-		delete(cm.conns, conn.GetId())
 	}
-	cm.mux.Unlock()
 }
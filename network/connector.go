@@ -6,29 +6,47 @@
 package network
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"github.com/lightning-go/lightning/defs"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 	"github.com/lightning-go/lightning/logger"
 )
 
+var ErrConnectTimeout = errors.New("connect timeout")
+
 type Connector struct {
-	addr           string
-	close          chan bool
-	working        bool
-	connCallback   defs.ClientConnCallback
-	cancelCallback func()
+	addr              string
+	close             chan bool
+	working           int32
+	connCallback      defs.ClientConnCallback
+	cancelCallback    func()
+	dialCallback      func(ctx context.Context) error
+	reconnectCallback func(*ReconnectEvent)
+	tlsConfig         *tls.Config
+	policy            *ReconnectPolicy
+	stopMux           sync.Mutex
+	stop              context.CancelFunc
 }
 
 func NewConnector(addr string) *Connector {
 	return &Connector{
-		addr:  addr,
-		close: make(chan bool),
+		addr:   addr,
+		close:  make(chan bool),
+		policy: NewReconnectPolicy(),
 	}
 }
 
 func (c *Connector) IsWorking() bool {
-	return c.working
+	return atomic.LoadInt32(&c.working) == 1
+}
+
+func (c *Connector) SetTLSConfig(cfg *tls.Config) {
+	c.tlsConfig = cfg
 }
 
 func (c *Connector) SetCancelCallback(cb func()) {
@@ -39,6 +57,26 @@ func (c *Connector) SetConnCallback(cb defs.ClientConnCallback) {
 	c.connCallback = cb
 }
 
+//SetDialCallback replaces the tcp dial, the callback hands the connection
+//over itself, e.g. for websocket
+func (c *Connector) SetDialCallback(cb func(ctx context.Context) error) {
+	c.dialCallback = cb
+}
+
+func (c *Connector) SetReconnectPolicy(policy *ReconnectPolicy) {
+	if policy != nil {
+		c.policy = policy
+	}
+}
+
+func (c *Connector) GetReconnectPolicy() *ReconnectPolicy {
+	return c.policy
+}
+
+func (c *Connector) SetReconnectCallback(cb func(*ReconnectEvent)) {
+	c.reconnectCallback = cb
+}
+
 func (c *Connector) CancelHandle() {
 	if c.cancelCallback != nil {
 		c.cancelCallback()
@@ -51,59 +89,154 @@ func (c *Connector) connectionHandle(conn net.Conn) {
 	}
 }
 
+func (c *Connector) onEvent(e *ReconnectEvent) {
+	if c.reconnectCallback != nil {
+		c.reconnectCallback(e)
+	}
+}
+
 func (c *Connector) Close(v bool) {
 	c.close <- v
 }
 
 func (c *Connector) Start(timeout time.Duration) {
-	if c.working {
+	c.StartCtx(context.Background(), timeout)
+}
+
+//StartCtx connects until the connection is closed without retry, dialing
+//gives up once ctx is done, timeout has passed since the first dial or the
+//attempts of the policy are spent
+func (c *Connector) StartCtx(ctx context.Context, timeout time.Duration) {
+	if !atomic.CompareAndSwapInt32(&c.working, 0, 1) {
 		return
 	}
-	c.connect(c.addr, timeout)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.stopMux.Lock()
+	c.stop = cancel
+	c.stopMux.Unlock()
+	defer cancel()
+	c.connect(ctx, c.addr, timeout)
+	atomic.StoreInt32(&c.working, 0)
 }
 
-func (c *Connector) connect(addr string, timeout time.Duration) {
-	c.working = true
-	var tmpDelay time.Duration
+//Stop cancels dialing and backoff, an established connection is not closed
+func (c *Connector) Stop() {
+	c.stopMux.Lock()
+	stop := c.stop
+	c.stopMux.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+func (c *Connector) dial(ctx context.Context, addr string) (net.Conn, error) {
+	d := net.Dialer{Timeout: c.policy.DialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	setNoDelay(conn)
+	if c.tlsConfig == nil {
+		return conn, nil
+	}
+	tlsConn := tls.Client(conn, c.tlsConfig)
+	err = tlsHandshake(tlsConn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
 
-	maxDelay := 3 * time.Second
-	if timeout > maxDelay {
-		maxDelay = timeout
+func (c *Connector) dialOnce(ctx context.Context, addr string) error {
+	if c.policy.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.policy.DialTimeout)
+		defer cancel()
 	}
+	if c.dialCallback != nil {
+		return c.dialCallback(ctx)
+	}
+	conn, err := c.dial(ctx, addr)
+	if err != nil {
+		return err
+	}
+	go c.connectionHandle(conn)
+	return nil
+}
 
+func (c *Connector) connect(ctx context.Context, addr string, timeout time.Duration) {
+	policy := c.policy
+	var delay time.Duration
 	for {
-		conn, err := net.Dial("tcp", addr)
-		if err != nil {
-			if tmpDelay == 0 {
-				tmpDelay = time.Second
-			} else {
-				tmpDelay += time.Second
-			}
-
-			if timeout > 0 && tmpDelay > time.Duration(timeout) {
-				c.CancelHandle()
-				break
-			}
-
-			if tmpDelay > maxDelay {
-				tmpDelay = maxDelay
-			}
-
-			logger.Warnf("connecting to %v error, retrying in %v second", addr, tmpDelay.Seconds())
-			time.Sleep(tmpDelay)
-			continue
+		//the lost connection is dialed again after a random delay
+		if delay > 0 && !c.sleep(ctx, delay) {
+			c.giveUp(addr, 0, ctx.Err())
+			return
+		}
+		if !c.dialRetry(ctx, addr, timeout) {
+			return
 		}
-
-		go c.connectionHandle(conn)
 
 		retry := <-c.close
 		if !retry {
 			break
 		}
-
-		tmpDelay = 0
+		delay = policy.first()
 		logger.Tracef("reconnecting to %v", addr)
 	}
+}
+
+//dialRetry returns false when it gave up
+func (c *Connector) dialRetry(ctx context.Context, addr string, timeout time.Duration) bool {
+	policy := c.policy
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		c.onEvent(&ReconnectEvent{Type: ReconnectConnecting, Addr: addr, Attempt: attempt})
+		err := c.dialOnce(ctx, addr)
+		if err == nil {
+			c.onEvent(&ReconnectEvent{Type: ReconnectConnected, Addr: addr, Attempt: attempt})
+			return true
+		}
+		if ctx.Err() != nil {
+			c.giveUp(addr, attempt, ctx.Err())
+			return false
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			c.giveUp(addr, attempt, err)
+			return false
+		}
+
+		delay := policy.Backoff(attempt)
+		if timeout > 0 && time.Since(start)+delay > timeout {
+			c.giveUp(addr, attempt, ErrConnectTimeout)
+			return false
+		}
+		logger.Warnf("connecting to %v error: %v, retrying in %v", addr, err, delay)
+		c.onEvent(&ReconnectEvent{Type: ReconnectBackoff, Addr: addr, Attempt: attempt, Delay: delay, Err: err})
+		if !c.sleep(ctx, delay) {
+			c.giveUp(addr, attempt, ctx.Err())
+			return false
+		}
+	}
+}
+
+func (c *Connector) sleep(ctx context.Context, delay time.Duration) bool {
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
 
-	c.working = false
+func (c *Connector) giveUp(addr string, attempt int, err error) {
+	logger.Warnf("connecting to %v gave up: %v", addr, err)
+	c.onEvent(&ReconnectEvent{Type: ReconnectGaveUp, Addr: addr, Attempt: attempt, Err: err})
+	c.CancelHandle()
 }
@@ -0,0 +1,78 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/module"
+)
+
+const (
+	testPing = 20 * time.Millisecond
+	testIdle = 100 * time.Millisecond
+)
+
+var heartbeatCodecs = []struct {
+	name      string
+	codec     func() defs.ICodec
+	carriesId bool
+}{
+	{"stream", func() defs.ICodec { return module.NewStreamCodec() }, false},
+	{"head", func() defs.ICodec { return module.NewHeadCodec() }, true},
+	{"proto", func() defs.ICodec { return module.NewProtoCodec() }, true},
+	{"compress", func() defs.ICodec {
+		return module.NewCompressCodec(module.NewHeadCodec(), module.CompressGzip, 0)
+	}, true},
+	{"encrypt", func() defs.ICodec { return module.NewEncryptCodec(module.NewHeadCodec()) }, true},
+}
+
+func heartbeatConn(t *testing.T, codec func() defs.ICodec, interval time.Duration) (*TcpServer, *TcpClient) {
+	srv := newServer(codec(), nil)
+	srv.SetHeartbeat(interval, testIdle)
+	srv.Serve()
+	client := dial(t, srv.Host(), codec(), nil)
+	return srv, client
+}
+
+// the client answers the pings of the server, which keeps the connection open
+// past testIdle over the codecs carrying the ping
+func TestHeartbeatKeepsIdleConnection(t *testing.T) {
+	for _, c := range heartbeatCodecs {
+		if !c.carriesId {
+			continue
+		}
+		t.Run(c.name, func(t *testing.T) {
+			srv, client := heartbeatConn(t, c.codec, testPing)
+			defer srv.Shutdown(0)
+
+			time.Sleep(5 * testIdle)
+			if client.GetConn().IsClosed() {
+				t.Fatal("idle connection closed with the heartbeat on")
+			}
+		})
+	}
+}
+
+// a silent peer is closed after the idle timeout once pings are not sent,
+// a codec without the packet id never sends them but still detects the
+// half-open connection
+func TestHeartbeatIdleTimeout(t *testing.T) {
+	for _, c := range heartbeatCodecs {
+		interval := time.Duration(0)
+		if !c.carriesId {
+			interval = testPing
+		}
+		t.Run(c.name, func(t *testing.T) {
+			srv, client := heartbeatConn(t, c.codec, interval)
+			defer srv.Shutdown(0)
+
+			waitClosed(t, client.GetConn())
+		})
+	}
+}
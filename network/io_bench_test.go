@@ -0,0 +1,100 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package network
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/module"
+)
+
+//batch sizes compared by the benchmarks, 1 flushes every packet
+var benchBatches = []int32{1, 128}
+
+func benchEchoServer(codec defs.ICodec) *TcpServer {
+	srv := newServer(codec, func(conn defs.IConnection, packet defs.IPacket) {
+		conn.WritePacket(packet)
+	})
+	srv.Serve()
+	return srv
+}
+
+func withBatch(b *testing.B, f func(b *testing.B)) {
+	val := conf.GetGlobalVal()
+	old := val.MaxWriteBatch
+	defer func() {
+		val.MaxWriteBatch = old
+	}()
+	for _, batch := range benchBatches {
+		b.Run(fmt.Sprintf("batch=%v", batch), func(b *testing.B) {
+			val.MaxWriteBatch = batch
+			f(b)
+		})
+	}
+}
+
+//as example/pingpong, blocks bounce between the client and the echo server
+func BenchmarkPingPong(b *testing.B) {
+	withBatch(b, func(b *testing.B) {
+		const blockSize = 16
+		const inflight = 64
+		srv := benchEchoServer(&module.StreamCodec{})
+		defer srv.Shutdown(0)
+
+		total := int64(b.N) * blockSize
+		var read int64
+		done := make(chan bool)
+		client := dial(b, srv.Host(), &module.StreamCodec{}, func(conn defs.IConnection, packet defs.IPacket) {
+			n := atomic.AddInt64(&read, int64(len(packet.GetData())))
+			if n >= total {
+				select {
+				case done <- true:
+				default:
+				}
+				return
+			}
+			conn.WritePacket(packet)
+		})
+		defer client.Close()
+
+		block := make([]byte, blockSize)
+		b.SetBytes(blockSize)
+		b.ResetTimer()
+		for i := 0; i < inflight && i < b.N; i++ {
+			client.SendData(block)
+		}
+		<-done
+	})
+}
+
+//as example/ttcp, small head framed messages are sent back to back and echoed
+func BenchmarkTTcp(b *testing.B) {
+	withBatch(b, func(b *testing.B) {
+		msg := []byte("hello world!!! hi Jason, it is a test!!!")
+		srv := benchEchoServer(&module.HeadCodec{})
+		defer srv.Shutdown(0)
+
+		var count int64
+		done := make(chan bool)
+		client := dial(b, srv.Host(), &module.HeadCodec{}, func(conn defs.IConnection, packet defs.IPacket) {
+			if atomic.AddInt64(&count, 1) == int64(b.N) {
+				done <- true
+			}
+		})
+		defer client.Close()
+
+		b.SetBytes(int64(len(msg)))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			client.SendData(msg)
+		}
+		<-done
+	})
+}
@@ -0,0 +1,258 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package network
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lightning-go/lightning/defs"
+)
+
+var (
+	ErrOutboxFull    = errors.New("outbox full")
+	ErrOutboxExpired = errors.New("outbox packet expired")
+	ErrOutboxClosed  = errors.New("outbox closed")
+)
+
+type OverflowPolicy int
+
+const (
+	OverflowDropOldest OverflowPolicy = iota
+	OverflowDropNewest
+	OverflowBlock
+)
+
+type outboxItem struct {
+	packet defs.IPacket
+	queued time.Time
+}
+
+//Outbox keeps the packets sent while a client is reconnecting and replays
+//them in order once the link is back. It holds from the drop until the
+//replay ends, so packets sent meanwhile keep their order behind the held ones
+type Outbox struct {
+	mux            sync.Mutex
+	cond           *sync.Cond
+	items          []outboxItem
+	size           int
+	policy         OverflowPolicy
+	blockTimeout   time.Duration
+	ttl            time.Duration
+	holding        bool
+	closed         bool
+	expireCallback func(packet defs.IPacket, queued time.Time) bool
+	dropCallback   func(packet defs.IPacket, reason error)
+}
+
+func NewOutbox(size int, policy OverflowPolicy) *Outbox {
+	if size <= 0 {
+		return nil
+	}
+	ob := &Outbox{
+		items:  make([]outboxItem, 0, size),
+		size:   size,
+		policy: policy,
+	}
+	ob.cond = sync.NewCond(&ob.mux)
+	return ob
+}
+
+//SetBlockTimeout bounds the wait of OverflowBlock, the packet is dropped
+//after it, zero waits until there is room
+func (ob *Outbox) SetBlockTimeout(timeout time.Duration) {
+	ob.mux.Lock()
+	ob.blockTimeout = timeout
+	ob.mux.Unlock()
+}
+
+//SetTTL drops the packets held longer than ttl instead of replaying them
+func (ob *Outbox) SetTTL(ttl time.Duration) {
+	ob.mux.Lock()
+	ob.ttl = ttl
+	ob.mux.Unlock()
+}
+
+//SetExpireCallback decides which held packets are stale, true drops the packet
+func (ob *Outbox) SetExpireCallback(cb func(packet defs.IPacket, queued time.Time) bool) {
+	ob.mux.Lock()
+	ob.expireCallback = cb
+	ob.mux.Unlock()
+}
+
+//SetDropCallback is called for every packet dropped, with the reason
+func (ob *Outbox) SetDropCallback(cb func(packet defs.IPacket, reason error)) {
+	ob.mux.Lock()
+	ob.dropCallback = cb
+	ob.mux.Unlock()
+}
+
+func (ob *Outbox) Len() int {
+	ob.mux.Lock()
+	defer ob.mux.Unlock()
+	return len(ob.items)
+}
+
+//hold makes the packets sent from now on go to the outbox
+func (ob *Outbox) hold() {
+	ob.mux.Lock()
+	ob.holding = true
+	ob.mux.Unlock()
+}
+
+//Holding reports whether packets are held, until the replay ends
+func (ob *Outbox) Holding() bool {
+	ob.mux.Lock()
+	defer ob.mux.Unlock()
+	return ob.holding
+}
+
+func (ob *Outbox) drop(packet defs.IPacket, reason error) {
+	if ob.dropCallback != nil {
+		ob.dropCallback(packet, reason)
+	}
+}
+
+//Push holds the packet, false when it was dropped. Holding is left as is,
+//hold marks the drop and the replay ends it
+func (ob *Outbox) Push(packet defs.IPacket) bool {
+	if packet == nil {
+		return false
+	}
+	ob.mux.Lock()
+	return ob.push(packet)
+}
+
+//pushHeld holds the packet while the outbox is holding or down reports the
+//link is down, decided under the lock the replay ends with, so a packet is
+//never left behind by a replay that already finished. false means the caller
+//writes the packet itself
+func (ob *Outbox) pushHeld(packet defs.IPacket, down func() bool) bool {
+	ob.mux.Lock()
+	if !ob.holding {
+		if !down() {
+			ob.mux.Unlock()
+			return false
+		}
+		ob.holding = !ob.closed
+	}
+	ob.push(packet)
+	return true
+}
+
+//push is called with the lock held and releases it
+func (ob *Outbox) push(packet defs.IPacket) bool {
+	if ob.closed {
+		ob.mux.Unlock()
+		ob.drop(packet, ErrOutboxClosed)
+		return false
+	}
+
+	var oldest defs.IPacket
+	if len(ob.items) >= ob.size {
+		switch ob.policy {
+		case OverflowDropOldest:
+			oldest = ob.items[0].packet
+			ob.items[0] = outboxItem{}
+			ob.items = ob.items[1:]
+		case OverflowBlock:
+			if !ob.wait() {
+				ob.mux.Unlock()
+				ob.drop(packet, ErrOutboxFull)
+				return false
+			}
+		default:
+			ob.mux.Unlock()
+			ob.drop(packet, ErrOutboxFull)
+			return false
+		}
+	}
+	ob.items = append(ob.items, outboxItem{packet: packet, queued: time.Now()})
+	ob.mux.Unlock()
+	if oldest != nil {
+		ob.drop(oldest, ErrOutboxFull)
+	}
+	return true
+}
+
+//wait for room with the lock held, false on timeout or close
+func (ob *Outbox) wait() bool {
+	var timer *time.Timer
+	timedOut := false
+	if ob.blockTimeout > 0 {
+		timer = time.AfterFunc(ob.blockTimeout, func() {
+			ob.mux.Lock()
+			timedOut = true
+			ob.mux.Unlock()
+			ob.cond.Broadcast()
+		})
+		defer timer.Stop()
+	}
+	for len(ob.items) >= ob.size && !ob.closed && !timedOut {
+		ob.cond.Wait()
+	}
+	return len(ob.items) < ob.size
+}
+
+func (ob *Outbox) expired(item outboxItem, now time.Time) bool {
+	if ob.ttl > 0 && now.Sub(item.queued) > ob.ttl {
+		return true
+	}
+	return ob.expireCallback != nil && ob.expireCallback(item.packet, item.queued)
+}
+
+//Flush replays the held packets in order with write and stops holding,
+//packets pushed during the replay are replayed too. A write returning false
+//keeps the packet and the rest for the next link
+func (ob *Outbox) Flush(write func(defs.IPacket) bool) {
+	for {
+		ob.mux.Lock()
+		if len(ob.items) == 0 {
+			ob.holding = false
+			ob.mux.Unlock()
+			return
+		}
+		item := ob.items[0]
+		ob.items[0] = outboxItem{}
+		ob.items = ob.items[1:]
+		expired := ob.expired(item, time.Now())
+		ob.mux.Unlock()
+		ob.cond.Broadcast()
+
+		if expired {
+			ob.drop(item.packet, ErrOutboxExpired)
+			continue
+		}
+		if !write(item.packet) {
+			ob.mux.Lock()
+			ob.items = append([]outboxItem{item}, ob.items...)
+			ob.mux.Unlock()
+			return
+		}
+	}
+}
+
+func newDataPacket(id string, data []byte) defs.IPacket {
+	p := &defs.Packet{}
+	p.SetId(id)
+	p.SetData(data)
+	return p
+}
+
+//Close drops the held packets and wakes the blocked senders
+func (ob *Outbox) Close() {
+	ob.mux.Lock()
+	items := ob.items
+	ob.items = nil
+	ob.closed = true
+	ob.holding = false
+	ob.mux.Unlock()
+	ob.cond.Broadcast()
+	for _, item := range items {
+		ob.drop(item.packet, ErrOutboxClosed)
+	}
+}
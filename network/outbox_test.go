@@ -0,0 +1,118 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package network
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lightning-go/lightning/defs"
+)
+
+type sink struct {
+	mux  sync.Mutex
+	seqs []uint64
+}
+
+func (s *sink) write(packet defs.IPacket) bool {
+	s.mux.Lock()
+	s.seqs = append(s.seqs, packet.GetSequence())
+	s.mux.Unlock()
+	return true
+}
+
+func seqPacket(seq uint64) defs.IPacket {
+	p := &defs.Packet{}
+	p.SetSequence(seq)
+	return p
+}
+
+//packets sent while the replay runs are neither stranded nor reordered
+func TestOutboxSendDuringReplay(t *testing.T) {
+	const senders = 4
+	const count = 500
+	for round := 0; round < 50; round++ {
+		ob := NewOutbox(senders*count, OverflowBlock)
+		out := &sink{}
+		var up int32
+		down := func() bool {
+			return atomic.LoadInt32(&up) == 0
+		}
+		ob.hold()
+		for i := uint64(0); i < 8; i++ {
+			ob.Push(seqPacket(i))
+		}
+
+		var wg sync.WaitGroup
+		for s := uint64(0); s < senders; s++ {
+			wg.Add(1)
+			go func(s uint64) {
+				defer wg.Done()
+				for i := uint64(0); i < count; i++ {
+					p := seqPacket((s+1)<<32 | i)
+					if !ob.pushHeld(p, down) {
+						out.write(p)
+					}
+				}
+			}(s)
+		}
+
+		atomic.StoreInt32(&up, 1)
+		ob.Flush(func(packet defs.IPacket) bool {
+			runtime.Gosched()
+			return out.write(packet)
+		})
+		wg.Wait()
+
+		if ob.Holding() || ob.Len() != 0 {
+			t.Fatalf("round %v: holding %v with %v packets after the replay", round, ob.Holding(), ob.Len())
+		}
+		if len(out.seqs) != 8+senders*count {
+			t.Fatalf("round %v: wrote %v of %v", round, len(out.seqs), 8+senders*count)
+		}
+		//each sender keeps its order
+		next := make(map[uint64]uint64)
+		for _, seq := range out.seqs {
+			s, i := seq>>32, seq&0xffffffff
+			if i != next[s] {
+				t.Fatalf("round %v: sender %v packet %v written before %v", round, s, i, next[s])
+			}
+			next[s] = i + 1
+		}
+	}
+}
+
+func TestOutboxOverflow(t *testing.T) {
+	var dropped []uint64
+	ob := NewOutbox(2, OverflowDropOldest)
+	ob.SetDropCallback(func(packet defs.IPacket, reason error) {
+		dropped = append(dropped, packet.GetSequence())
+	})
+	for i := uint64(0); i < 4; i++ {
+		ob.Push(seqPacket(i))
+	}
+	if ob.Holding() {
+		t.Fatal("push must not start holding")
+	}
+	out := &sink{}
+	ob.Flush(out.write)
+	if len(dropped) != 2 || dropped[0] != 0 || dropped[1] != 1 {
+		t.Fatalf("dropped %v", dropped)
+	}
+	if len(out.seqs) != 2 || out.seqs[0] != 2 || out.seqs[1] != 3 {
+		t.Fatalf("replayed %v", out.seqs)
+	}
+
+	ob = NewOutbox(1, OverflowBlock)
+	ob.SetBlockTimeout(20 * time.Millisecond)
+	ob.Push(seqPacket(0))
+	if ob.Push(seqPacket(1)) {
+		t.Fatal("blocked push must time out")
+	}
+}
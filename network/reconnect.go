@@ -0,0 +1,100 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package network
+
+import (
+	"math/rand"
+	"time"
+)
+
+//ReconnectPolicy is the backoff of the clients between failed dials, the
+//delay grows by Multiplier from InitialDelay up to MaxDelay and is spread by
+//Jitter so clients of a restarted server do not come back all at once
+type ReconnectPolicy struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	Jitter       float64 //0 to 1, the part of the delay randomized
+	MaxAttempts  int     //failed dials before giving up, 0 is unlimited
+	DialTimeout  time.Duration
+}
+
+func NewReconnectPolicy() *ReconnectPolicy {
+	return &ReconnectPolicy{
+		InitialDelay: time.Second,
+		MaxDelay:     time.Second * 10,
+		Multiplier:   2,
+		Jitter:       0.2,
+		DialTimeout:  time.Second * 5,
+	}
+}
+
+//Backoff is the delay after the failed attempt, counted from 1
+func (p *ReconnectPolicy) Backoff(attempt int) time.Duration {
+	delay := float64(p.InitialDelay)
+	for i := 1; i < attempt && delay < float64(p.MaxDelay); i++ {
+		delay *= p.Multiplier
+	}
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	return p.jitter(delay)
+}
+
+//first is the delay before redialing a lost connection, zero to InitialDelay
+func (p *ReconnectPolicy) first() time.Duration {
+	if p.Jitter <= 0 || p.InitialDelay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(p.InitialDelay)))
+}
+
+func (p *ReconnectPolicy) jitter(delay float64) time.Duration {
+	if p.Jitter > 0 {
+		j := p.Jitter
+		if j > 1 {
+			j = 1
+		}
+		delay += delay * j * (rand.Float64()*2 - 1)
+	}
+	if delay < 0 {
+		return 0
+	}
+	return time.Duration(delay)
+}
+
+type ReconnectEventType int
+
+const (
+	ReconnectConnecting ReconnectEventType = iota
+	ReconnectConnected
+	ReconnectBackoff
+	ReconnectGaveUp
+)
+
+func (t ReconnectEventType) String() string {
+	switch t {
+	case ReconnectConnecting:
+		return "connecting"
+	case ReconnectConnected:
+		return "connected"
+	case ReconnectBackoff:
+		return "backoff"
+	case ReconnectGaveUp:
+		return "gave up"
+	}
+	return "unknown"
+}
+
+//ReconnectEvent tells the steps of the connector, Delay is set for a backoff
+//and Err for a backoff or giving up
+type ReconnectEvent struct {
+	Type    ReconnectEventType
+	Addr    string
+	Attempt int
+	Delay   time.Duration
+	Err     error
+}
@@ -0,0 +1,249 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package network
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lightning-go/lightning/logger"
+)
+
+func msPolicy() *ReconnectPolicy {
+	return &ReconnectPolicy{
+		InitialDelay: time.Millisecond,
+		MaxDelay:     4 * time.Millisecond,
+		Multiplier:   2,
+		DialTimeout:  time.Second,
+	}
+}
+
+//closedAddr is an address nothing listens on, dials fail right away
+func closedAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+//recordConnector runs the connector to the end and returns its events
+func recordConnector(ctx context.Context, c *Connector, timeout time.Duration) []*ReconnectEvent {
+	logger.SetLevel(logger.FATAL)
+	var events []*ReconnectEvent
+	c.SetReconnectCallback(func(e *ReconnectEvent) {
+		events = append(events, e)
+	})
+	c.StartCtx(ctx, timeout)
+	return events
+}
+
+func eventTypes(events []*ReconnectEvent) []ReconnectEventType {
+	types := make([]ReconnectEventType, 0, len(events))
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func sameTypes(a, b []ReconnectEventType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	p := &ReconnectPolicy{
+		InitialDelay: 10 * time.Millisecond,
+		MaxDelay:     50 * time.Millisecond,
+		Multiplier:   2,
+	}
+	want := []time.Duration{10, 20, 40, 50, 50, 50}
+	for i, w := range want {
+		if d := p.Backoff(i + 1); d != w*time.Millisecond {
+			t.Fatalf("attempt %v backoff %v, want %v", i+1, d, w*time.Millisecond)
+		}
+	}
+	//the growth stops at the cap instead of overflowing
+	if d := p.Backoff(1000); d != p.MaxDelay {
+		t.Fatalf("attempt 1000 backoff %v", d)
+	}
+}
+
+func TestReconnectJitter(t *testing.T) {
+	p := &ReconnectPolicy{
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     time.Second,
+		Multiplier:   2,
+		Jitter:       0.5,
+	}
+	spread := false
+	for i := 0; i < 200; i++ {
+		d := p.Backoff(1)
+		if d < 50*time.Millisecond || d > 150*time.Millisecond {
+			t.Fatalf("backoff %v out of the jitter", d)
+		}
+		if d != 100*time.Millisecond {
+			spread = true
+		}
+		if f := p.first(); f < 0 || f >= p.InitialDelay {
+			t.Fatalf("first delay %v", f)
+		}
+	}
+	if !spread {
+		t.Fatal("backoff not randomized")
+	}
+
+	//a jitter above 1 is the whole delay
+	p.Jitter = 3
+	for i := 0; i < 200; i++ {
+		if d := p.Backoff(1); d < 0 || d > 200*time.Millisecond {
+			t.Fatalf("backoff %v out of the jitter", d)
+		}
+	}
+}
+
+func TestReconnectGiveUp(t *testing.T) {
+	policy := msPolicy()
+	policy.MaxAttempts = 3
+	c := NewConnector(closedAddr(t))
+	c.SetReconnectPolicy(policy)
+	canceled := false
+	c.SetCancelCallback(func() {
+		canceled = true
+	})
+
+	events := recordConnector(context.Background(), c, 0)
+	want := []ReconnectEventType{
+		ReconnectConnecting, ReconnectBackoff,
+		ReconnectConnecting, ReconnectBackoff,
+		ReconnectConnecting, ReconnectGaveUp,
+	}
+	if !sameTypes(eventTypes(events), want) {
+		t.Fatalf("events %v", eventTypes(events))
+	}
+	for i, e := range events {
+		if e.Attempt != i/2+1 {
+			t.Fatalf("event %v of attempt %v", e.Type, e.Attempt)
+		}
+	}
+	if events[1].Delay != time.Millisecond || events[3].Delay != 2*time.Millisecond || events[1].Err == nil {
+		t.Fatalf("backoff %v %v: %v", events[1].Delay, events[3].Delay, events[1].Err)
+	}
+	if events[5].Err == nil || !canceled || c.IsWorking() {
+		t.Fatalf("gave up with %v", events[5].Err)
+	}
+}
+
+//each dial is bounded by the DialTimeout of the policy
+func TestReconnectDialTimeout(t *testing.T) {
+	policy := msPolicy()
+	policy.MaxAttempts = 1
+	policy.DialTimeout = 20 * time.Millisecond
+	c := NewConnector("hang")
+	c.SetReconnectPolicy(policy)
+	c.SetDialCallback(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	begin := time.Now()
+	events := recordConnector(context.Background(), c, 0)
+	last := events[len(events)-1]
+	if last.Type != ReconnectGaveUp || !errors.Is(last.Err, context.DeadlineExceeded) {
+		t.Fatalf("gave up with %v %v", last.Type, last.Err)
+	}
+	if elapsed := time.Since(begin); elapsed < 20*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("dial returned after %v", elapsed)
+	}
+}
+
+//the timeout of StartCtx stops the retries before a backoff past it
+func TestReconnectConnectTimeout(t *testing.T) {
+	policy := msPolicy()
+	policy.InitialDelay = 50 * time.Millisecond
+	policy.MaxDelay = 50 * time.Millisecond
+	c := NewConnector(closedAddr(t))
+	c.SetReconnectPolicy(policy)
+
+	events := recordConnector(context.Background(), c, 20*time.Millisecond)
+	want := []ReconnectEventType{ReconnectConnecting, ReconnectGaveUp}
+	if !sameTypes(eventTypes(events), want) || events[1].Err != ErrConnectTimeout {
+		t.Fatalf("events %v: %v", eventTypes(events), events[len(events)-1].Err)
+	}
+}
+
+func TestReconnectCancel(t *testing.T) {
+	policy := msPolicy()
+	policy.InitialDelay = time.Hour
+	policy.MaxDelay = time.Hour
+	c := NewConnector(closedAddr(t))
+	c.SetReconnectPolicy(policy)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	begin := time.Now()
+	events := recordConnector(ctx, c, 0)
+	want := []ReconnectEventType{ReconnectConnecting, ReconnectBackoff, ReconnectGaveUp}
+	if !sameTypes(eventTypes(events), want) || events[2].Err != context.Canceled {
+		t.Fatalf("events %v: %v", eventTypes(events), events[len(events)-1].Err)
+	}
+	if time.Since(begin) > time.Second {
+		t.Fatal("backoff not canceled")
+	}
+}
+
+//a lost connection is dialed again from the first attempt
+func TestReconnectAfterLost(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	c := NewConnector(ln.Addr().String())
+	c.SetReconnectPolicy(msPolicy())
+	c.SetConnCallback(func(conn net.Conn) {
+		conn.Close()
+	})
+	go func() {
+		c.Close(true)
+		c.Close(false)
+	}()
+	events := recordConnector(context.Background(), c, 0)
+	want := []ReconnectEventType{
+		ReconnectConnecting, ReconnectConnected,
+		ReconnectConnecting, ReconnectConnected,
+	}
+	if !sameTypes(eventTypes(events), want) {
+		t.Fatalf("events %v", eventTypes(events))
+	}
+	for _, e := range events {
+		if e.Attempt != 1 {
+			t.Fatalf("event %v of attempt %v", e.Type, e.Attempt)
+		}
+	}
+}
@@ -6,8 +6,10 @@
 package network
 
 import (
+	"crypto/tls"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/lightning-go/lightning/conf"
 	"github.com/lightning-go/lightning/defs"
@@ -44,6 +46,14 @@ func NewServer(name string, confPath ...string) *Server {
 	}
 	s.init()
 
+	if cfg.TLS != nil {
+		tlsCfg, err := NewServerTLSConfig(cfg.TLS)
+		if err != nil {
+			panic(fmt.Sprintf("%v tls config load failed: %v", name, err))
+		}
+		s.SetTLSConfig(tlsCfg)
+	}
+
 	for _, remoteName := range cfg.Remotes {
 		rCfg := conf.GetSrvCfg(remoteName)
 		if rCfg == nil {
@@ -57,6 +67,13 @@ func NewServer(name string, confPath ...string) *Server {
 
 func (s *Server) init() {
 	s.SetConnCallback(s.onConn)
+	s.SetMaxConnPerIp(s.cfg.MaxConnPerIp)
+	s.SetMaxPacketSize(s.cfg.MaxPacketSize)
+	s.SetHeartbeat(time.Duration(s.cfg.Heartbeat)*time.Second,
+		time.Duration(s.cfg.IdleTimeout)*time.Second)
+	s.AddDrainCheck(func() bool {
+		return s.connMgr.QueueLen() == 0
+	})
 }
 
 func (s *Server) AddRemoteClient(cfg *conf.ServerConfig) *TcpClient {
@@ -69,10 +86,38 @@ func (s *Server) AddRemoteClient(cfg *conf.ServerConfig) *TcpClient {
 	if c == nil {
 		return nil
 	}
+	c.SetHeartbeat(time.Duration(cfg.Heartbeat)*time.Second,
+		time.Duration(cfg.IdleTimeout)*time.Second)
+	c.SetMaxPacketSize(cfg.MaxPacketSize)
+	if cfg.TLS != nil {
+		tlsCfg, err := s.remoteTLSConfig(cfg)
+		if err != nil {
+			logger.Errorf("%v tls config load failed: %v", cfg.Name, err)
+			return nil
+		}
+		c.SetTLSConfig(tlsCfg)
+	}
 	s.remotes.Store(cfg.Name, c)
 	return c
 }
 
+//client side of an encrypted remote, presents the local certificate and
+//trusts the local CA, falling back to the remote CA
+func (s *Server) remoteTLSConfig(cfg *conf.ServerConfig) (*tls.Config, error) {
+	local := conf.TLSConfig{}
+	if s.cfg.TLS != nil {
+		local = *s.cfg.TLS
+	}
+	if len(local.CAFile) == 0 {
+		local.CAFile = cfg.TLS.CAFile
+	}
+	local.ServerName = cfg.TLS.ServerName
+	if len(local.ServerName) == 0 {
+		local.ServerName = cfg.Host
+	}
+	return NewClientTLSConfig(&local)
+}
+
 func (s *Server) GetRemoteClient(name string) *TcpClient {
 	c, ok := s.remotes.Load(name)
 	if !ok {
@@ -164,3 +209,8 @@ func (s *Server) RangeConn(f func(string, defs.ISession) bool) {
 func (s *Server) GetConnNum() int64 {
 	return s.connMgr.SessionCount()
 }
+
+//QueueLen returns the number of packets waiting in the session queues
+func (s *Server) QueueLen() int {
+	return s.connMgr.QueueLen()
+}
@@ -8,9 +8,12 @@ package network
 import (
 	"github.com/lightning-go/lightning/defs"
 	"sync"
+	"time"
 	"github.com/lightning-go/lightning/utils"
 )
 
+const drainCheckInterval = time.Millisecond * 10
+
 var srvMgr *ServerMgr
 var srvMgrOnce sync.Once
 
@@ -26,8 +29,20 @@ func WaitExit() {
 	GetSrvMgr().AllStop()
 }
 
+func waitDrain(timeout time.Duration, drained func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for !drained() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(drainCheckInterval)
+	}
+	return true
+}
+
 type ServerMgr struct {
-	servers map[string]defs.IServer
+	servers   map[string]defs.IServer
+	exitHooks []func()
 }
 
 func newServerMgr() *ServerMgr {
@@ -81,11 +96,29 @@ func (sm *ServerMgr) StopServer(server defs.IServer) {
 	server.Stop()
 }
 
+//hooks run after all servers are stopped, e.g. db.FlushAll syncing the MemMgr db queues
+func (sm *ServerMgr) AddExitHook(f func()) {
+	if f == nil {
+		return
+	}
+	sm.exitHooks = append(sm.exitHooks, f)
+}
+
 func (sm *ServerMgr) AllStop() {
+	var wg sync.WaitGroup
 	for _, srv := range sm.servers {
 		if srv == nil {
 			continue
 		}
-		srv.Stop()
+		wg.Add(1)
+		go func(srv defs.IServer) {
+			defer wg.Done()
+			srv.Stop()
+		}(srv)
+	}
+	wg.Wait()
+
+	for _, f := range sm.exitHooks {
+		f()
 	}
 }
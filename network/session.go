@@ -6,6 +6,7 @@
 package network
 
 import (
+	"context"
 	"github.com/lightning-go/lightning/defs"
 	"github.com/lightning-go/lightning/logger"
 	"sync"
@@ -48,6 +49,7 @@ type Session struct {
 	queue        chan *queueData
 	queueWorking int32
 	queueWait    sync.WaitGroup
+	queueLen     int32
 	closed       int32
 }
 
@@ -151,6 +153,32 @@ func (s *Session) WriteDataByIdAwait(id string, data []byte) (defs.IPacket, erro
 	return s.conn.WriteDataByIdAwait(id, data)
 }
 
+func (s *Session) WritePacketAwaitCtx(ctx context.Context, packet defs.IPacket) (defs.IPacket, error) {
+	return s.conn.WritePacketAwaitCtx(ctx, packet)
+}
+
+func (s *Session) WriteDataAwaitCtx(ctx context.Context, data []byte) (defs.IPacket, error) {
+	return s.conn.WriteDataAwaitCtx(ctx, data)
+}
+
+func (s *Session) WriteDataByIdAwaitCtx(ctx context.Context, id string, data []byte) (defs.IPacket, error) {
+	return s.conn.WriteDataByIdAwaitCtx(ctx, id, data)
+}
+
+func (s *Session) OpenStream(packet defs.IPacket) (defs.IStream, error) {
+	return s.conn.OpenStream(packet)
+}
+
+//AcceptStream attaches a stream to the request, e.g. the packet being served
+func (s *Session) AcceptStream(packet defs.IPacket) (defs.IStream, error) {
+	return s.conn.AcceptStream(packet)
+}
+
+//QueueLen returns the number of async packets waiting for or in service
+func (s *Session) QueueLen() int {
+	return int(atomic.LoadInt32(&s.queueLen))
+}
+
 func (s *Session) enableReadQueue() {
 	//if s.serve == nil {
 	if s.serviceHandle == nil {
@@ -181,13 +209,21 @@ func (s *Session) enableReadQueue() {
 				continue
 			}
 			//s.serve.OnServiceHandle(d.session, d.packet)
-			s.serviceHandle(d.session, d.packet)
-			freeSessionQueueData(d)
+			s.serveQueued(d)
 		}
 		logger.Tracef("session closed %v", s.id)
 	}()
 }
 
+//the queue length drops even when the handler panics, so draining does not wait for it
+func (s *Session) serveQueued(d *queueData) {
+	defer func() {
+		atomic.AddInt32(&s.queueLen, -1)
+		freeSessionQueueData(d)
+	}()
+	s.serviceHandle(d.session, d.packet)
+}
+
 func (s *Session) OnService(session defs.ISession, packet defs.IPacket) bool {
 	if s.isSessionClosed() {
 		return false
@@ -213,6 +249,7 @@ func (s *Session) OnService(session defs.ISession, packet defs.IPacket) bool {
 		d.session = session
 		d.packet = packet
 
+		atomic.AddInt32(&s.queueLen, 1)
 		select {
 		case s.queue <- d:
 		}
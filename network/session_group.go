@@ -0,0 +1,279 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package network
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/module"
+)
+
+//Group is a named set of sessions, e.g. a room or a guild
+type Group struct {
+	name    string
+	mux     sync.Mutex
+	members map[string]defs.ISession
+	//rebuilt after joins and leaves, broadcasts range it without locking
+	snapshot atomic.Value
+}
+
+func newGroup(name string) *Group {
+	return &Group{
+		name:    name,
+		members: make(map[string]defs.ISession),
+	}
+}
+
+func (g *Group) Name() string {
+	return g.name
+}
+
+func (g *Group) Count() int {
+	g.mux.Lock()
+	n := len(g.members)
+	g.mux.Unlock()
+	return n
+}
+
+//Members returns the sessions of the group, the slice must not be modified
+func (g *Group) Members() []defs.ISession {
+	members, ok := g.snapshot.Load().([]defs.ISession)
+	if ok && members != nil {
+		return members
+	}
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	members, ok = g.snapshot.Load().([]defs.ISession)
+	if ok && members != nil {
+		return members
+	}
+	members = make([]defs.ISession, 0, len(g.members))
+	for _, s := range g.members {
+		members = append(members, s)
+	}
+	g.snapshot.Store(members)
+	return members
+}
+
+func (g *Group) join(s defs.ISession) {
+	g.mux.Lock()
+	g.members[s.GetSessionId()] = s
+	g.snapshot.Store([]defs.ISession(nil))
+	g.mux.Unlock()
+}
+
+//returns the number of members left
+func (g *Group) leave(sessionId string) int {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	_, ok := g.members[sessionId]
+	if ok {
+		delete(g.members, sessionId)
+		g.snapshot.Store([]defs.ISession(nil))
+	}
+	return len(g.members)
+}
+
+////////////////////////////////////////////////////////////////
+
+func JoinGroup(name string, s defs.ISession) {
+	defaultSessionMgr.JoinGroup(name, s)
+}
+
+func LeaveGroup(name, sessionId string) {
+	defaultSessionMgr.LeaveGroup(name, sessionId)
+}
+
+func Broadcast(name string, packet defs.IPacket, exclude ...string) int {
+	return defaultSessionMgr.Broadcast(name, packet, exclude...)
+}
+
+func Multicast(sessionIds []string, packet defs.IPacket, exclude ...string) int {
+	return defaultSessionMgr.Multicast(sessionIds, packet, exclude...)
+}
+
+////////////////////////////////////////////////////////////////
+
+//JoinGroup adds s to the group, created on the first join
+func (sm *SessionMgr) JoinGroup(name string, s defs.ISession) {
+	if s == nil {
+		return
+	}
+	sessionId := s.GetSessionId()
+
+	sm.groupMux.Lock()
+	defer sm.groupMux.Unlock()
+	g, ok := sm.groups[name]
+	if !ok {
+		g = newGroup(name)
+		sm.groups[name] = g
+	}
+	g.join(s)
+
+	names, ok := sm.sessionGroups[sessionId]
+	if !ok {
+		names = make(map[string]struct{})
+		sm.sessionGroups[sessionId] = names
+	}
+	names[name] = struct{}{}
+}
+
+//LeaveGroup removes the session from the group, empty groups are dropped
+func (sm *SessionMgr) LeaveGroup(name, sessionId string) {
+	sm.groupMux.Lock()
+	defer sm.groupMux.Unlock()
+	sm.leaveGroup(name, sessionId)
+
+	names, ok := sm.sessionGroups[sessionId]
+	if ok {
+		delete(names, name)
+		if len(names) == 0 {
+			delete(sm.sessionGroups, sessionId)
+		}
+	}
+}
+
+func (sm *SessionMgr) leaveGroup(name, sessionId string) {
+	g, ok := sm.groups[name]
+	if !ok {
+		return
+	}
+	if g.leave(sessionId) == 0 {
+		delete(sm.groups, name)
+	}
+}
+
+//LeaveAllGroups is called when the session is deleted
+func (sm *SessionMgr) LeaveAllGroups(sessionId string) {
+	sm.groupMux.Lock()
+	defer sm.groupMux.Unlock()
+	names, ok := sm.sessionGroups[sessionId]
+	if !ok {
+		return
+	}
+	for name := range names {
+		sm.leaveGroup(name, sessionId)
+	}
+	delete(sm.sessionGroups, sessionId)
+}
+
+func (sm *SessionMgr) GetGroup(name string) *Group {
+	sm.groupMux.RLock()
+	g := sm.groups[name]
+	sm.groupMux.RUnlock()
+	return g
+}
+
+func (sm *SessionMgr) GroupMembers(name string) []defs.ISession {
+	g := sm.GetGroup(name)
+	if g == nil {
+		return nil
+	}
+	return g.Members()
+}
+
+//SessionGroups returns the names of the groups the session joined
+func (sm *SessionMgr) SessionGroups(sessionId string) []string {
+	sm.groupMux.RLock()
+	defer sm.groupMux.RUnlock()
+	names := sm.sessionGroups[sessionId]
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+	return list
+}
+
+func (sm *SessionMgr) GroupCount() int {
+	sm.groupMux.RLock()
+	n := len(sm.groups)
+	sm.groupMux.RUnlock()
+	return n
+}
+
+//Broadcast writes packet to the members of the group except the excluded
+//session ids, it returns the number of members written to
+func (sm *SessionMgr) Broadcast(name string, packet defs.IPacket, exclude ...string) int {
+	g := sm.GetGroup(name)
+	if g == nil || packet == nil {
+		return 0
+	}
+	return fanOut(g.Members(), packet, exclude)
+}
+
+//Multicast writes packet to the sessions of the given ids
+func (sm *SessionMgr) Multicast(sessionIds []string, packet defs.IPacket, exclude ...string) int {
+	if packet == nil {
+		return 0
+	}
+	members := make([]defs.ISession, 0, len(sessionIds))
+	for _, sessionId := range sessionIds {
+		s := sm.GetSession(sessionId)
+		if s != nil {
+			members = append(members, s)
+		}
+	}
+	return fanOut(members, packet, exclude)
+}
+
+//BroadcastAll writes packet to every session
+func (sm *SessionMgr) BroadcastAll(packet defs.IPacket, exclude ...string) int {
+	if packet == nil {
+		return 0
+	}
+	members := make([]defs.ISession, 0, sm.SessionCount())
+	sm.RangeSession(func(sessionId string, s defs.ISession) bool {
+		members = append(members, s)
+		return true
+	})
+	return fanOut(members, packet, exclude)
+}
+
+//the packet is encoded once for the sessions owning their connection, sessions
+//multiplexed on a gate connection get a copy addressed by their id
+func fanOut(members []defs.ISession, packet defs.IPacket, exclude []string) int {
+	shared := module.NewSharedPacket(packet)
+	n := 0
+	for _, s := range members {
+		sessionId := s.GetSessionId()
+		if excluded(sessionId, exclude) {
+			continue
+		}
+		if sessionId == s.GetConnId() {
+			s.WritePacket(shared)
+		} else {
+			s.WritePacket(sessionPacket(packet, sessionId))
+		}
+		n++
+	}
+	return n
+}
+
+func excluded(sessionId string, exclude []string) bool {
+	for _, id := range exclude {
+		if id == sessionId {
+			return true
+		}
+	}
+	return false
+}
+
+func sessionPacket(packet defs.IPacket, sessionId string) defs.IPacket {
+	p := &defs.MsgPacket{}
+	p.SetId(packet.GetId())
+	p.SetSessionId(sessionId)
+	p.SetStatus(packet.GetStatus())
+	p.SetSequence(packet.GetSequence())
+	p.SetData(packet.GetData())
+	msgPacket, ok := packet.(defs.IMsgPacket)
+	if ok {
+		p.SetMsg(msgPacket.GetMsg())
+	}
+	return p
+}
@@ -0,0 +1,165 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package network
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/module"
+)
+
+//only the methods used by the session manager are implemented
+type groupSession struct {
+	defs.ISession
+	id      string
+	connId  string
+	mux     sync.Mutex
+	written []defs.IPacket
+}
+
+func newGroupSession(id, connId string) *groupSession {
+	return &groupSession{id: id, connId: connId}
+}
+
+func (s *groupSession) GetSessionId() string {
+	return s.id
+}
+
+func (s *groupSession) GetConnId() string {
+	return s.connId
+}
+
+func (s *groupSession) CloseSession() bool {
+	return true
+}
+
+func (s *groupSession) WritePacket(packet defs.IPacket) {
+	s.mux.Lock()
+	s.written = append(s.written, packet)
+	s.mux.Unlock()
+}
+
+func (s *groupSession) count() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return len(s.written)
+}
+
+func TestSessionGroups(t *testing.T) {
+	sm := NewSessionMgr()
+	a := newGroupSession("a", "a")
+	b := newGroupSession("b", "b")
+	c := newGroupSession("c", "c")
+	for _, s := range []*groupSession{a, b, c} {
+		sm.AddSession(s)
+	}
+	sm.JoinGroup("room", a)
+	sm.JoinGroup("room", b)
+	sm.JoinGroup("guild", a)
+	sm.JoinGroup("guild", c)
+
+	if sm.GroupCount() != 2 || len(sm.GroupMembers("room")) != 2 {
+		t.Fatalf("%v groups, %v in room", sm.GroupCount(), len(sm.GroupMembers("room")))
+	}
+	names := sm.SessionGroups("a")
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "guild" || names[1] != "room" {
+		t.Fatalf("groups of a %v", names)
+	}
+
+	if n := sm.Broadcast("room", &defs.Packet{}, "b"); n != 1 || a.count() != 1 || b.count() != 0 {
+		t.Fatalf("broadcast excluding b wrote %v", n)
+	}
+	if n := sm.Multicast([]string{"b", "c", "unknown"}, &defs.Packet{}); n != 2 || c.count() != 1 {
+		t.Fatalf("multicast wrote %v", n)
+	}
+
+	//leaving the last member drops the group, deleting a session leaves all its groups
+	sm.LeaveGroup("guild", "c")
+	sm.DelSession("a")
+	if sm.GroupCount() != 1 || sm.GetGroup("guild") != nil {
+		t.Fatalf("%v groups left", sm.GroupCount())
+	}
+	if members := sm.GroupMembers("room"); len(members) != 1 || members[0] != defs.ISession(b) {
+		t.Fatalf("room members %v", members)
+	}
+	if len(sm.SessionGroups("a")) != 0 {
+		t.Fatal("deleted session still in groups")
+	}
+}
+
+//sessions owning their connection share one packet, sessions behind a gate
+//connection get a copy addressed to them
+func TestFanOutPackets(t *testing.T) {
+	sm := NewSessionMgr()
+	direct := newGroupSession("direct", "direct")
+	gated1 := newGroupSession("s1", "gate")
+	gated2 := newGroupSession("s2", "gate")
+	for _, s := range []*groupSession{direct, gated1, gated2} {
+		sm.AddSession(s)
+		sm.JoinGroup("room", s)
+	}
+
+	packet := &defs.Packet{}
+	packet.SetId("news")
+	packet.SetData([]byte("hello"))
+	if n := sm.Broadcast("room", packet); n != 3 {
+		t.Fatalf("broadcast wrote %v", n)
+	}
+	if _, ok := direct.written[0].(*module.SharedPacket); !ok {
+		t.Fatalf("direct session got %T", direct.written[0])
+	}
+	for _, s := range []*groupSession{gated1, gated2} {
+		p := s.written[0]
+		if p.GetSessionId() != s.id || p.GetId() != "news" || string(p.GetData()) != "hello" {
+			t.Fatalf("gated session %v got %v %v", s.id, p.GetSessionId(), p.GetId())
+		}
+	}
+	if packet.GetSessionId() != "" {
+		t.Fatal("broadcast packet modified")
+	}
+}
+
+//the shared packet reaches every connection of the group
+func TestBroadcastConnections(t *testing.T) {
+	sm := NewSessionMgr()
+	joined := make(chan bool, 3)
+	srv := newServer(module.NewHeadCodec(), func(conn defs.IConnection, packet defs.IPacket) {
+		s := NewSession(conn, conn.GetId(), func(session defs.ISession, packet defs.IPacket) bool {
+			return true
+		})
+		sm.AddSession(s)
+		sm.JoinGroup("room", s)
+		joined <- true
+	})
+	srv.Serve()
+	defer srv.Shutdown(0)
+
+	received := make(chan defs.IPacket, 3)
+	for i := 0; i < 3; i++ {
+		client := dial(t, srv.Host(), module.NewHeadCodec(), func(conn defs.IConnection, packet defs.IPacket) {
+			received <- packet
+		})
+		client.SendData([]byte("join"))
+		<-joined
+	}
+
+	packet := &defs.Packet{}
+	packet.SetId("news")
+	packet.SetData([]byte("hello"))
+	if n := sm.Broadcast("room", packet); n != 3 {
+		t.Fatalf("broadcast wrote %v", n)
+	}
+	for i := 0; i < 3; i++ {
+		p := recvPacket(t, received)
+		if p.GetId() != "news" || string(p.GetData()) != "hello" {
+			t.Fatalf("received %v %q", p.GetId(), p.GetData())
+		}
+	}
+}
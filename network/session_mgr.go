@@ -68,14 +68,19 @@ func RangeSession(f func(sId string, s defs.ISession) bool) {
 ////////////////////////////////////////////////////////////////
 
 type SessionMgr struct {
-	sessions *Map
-	connDict *Map
+	sessions      *Map
+	connDict      *Map
+	groupMux      sync.RWMutex
+	groups        map[string]*Group
+	sessionGroups map[string]map[string]struct{}
 }
 
 func NewSessionMgr() *SessionMgr {
 	return &SessionMgr{
-		sessions: &Map{},
-		connDict: &Map{},
+		sessions:      &Map{},
+		connDict:      &Map{},
+		groups:        make(map[string]*Group),
+		sessionGroups: make(map[string]map[string]struct{}),
 	}
 }
 
@@ -136,6 +141,7 @@ func (sm *SessionMgr) DelSession(sessionId string) defs.ISession {
 
 	connId := session.GetConnId()
 	sm.sessions.Del(sessionId)
+	sm.LeaveAllGroups(sessionId)
 
 	d := sm.getConnSession(connId)
 	if d != nil {
@@ -163,6 +169,7 @@ func (sm *SessionMgr) DelConnSession(connId string) []defs.ISession {
 		}
 
 		sm.sessions.Del(sessionId)
+		sm.LeaveAllGroups(sessionId)
 		delSessions = append(delSessions, session)
 		return true
 	})
@@ -170,6 +177,15 @@ func (sm *SessionMgr) DelConnSession(connId string) []defs.ISession {
 	return delSessions
 }
 
+func (sm *SessionMgr) QueueLen() int {
+	n := 0
+	sm.RangeSession(func(sessionId string, s defs.ISession) bool {
+		n += s.QueueLen()
+		return true
+	})
+	return n
+}
+
 func (sm *SessionMgr) RangeSession(f func(string, defs.ISession) bool) {
 	if f == nil {
 		return
@@ -0,0 +1,97 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/module"
+)
+
+func reply(conn defs.IConnection, packet defs.IPacket, data string) {
+	p := &defs.Packet{}
+	p.SetId(packet.GetId())
+	p.SetSequence(packet.GetSequence())
+	p.SetData([]byte(data))
+	conn.WritePacket(p)
+}
+
+//requests awaited by either side and a stream share the connection, each
+//packet reaches the call or the stream it belongs to
+func TestStreamWithAwait(t *testing.T) {
+	asked := make(chan string, 1)
+	srv := newServer(module.NewHeadCodec(), func(conn defs.IConnection, packet defs.IPacket) {
+		switch packet.GetId() {
+		case "open":
+			s, err := conn.AcceptStream(packet)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			go func() {
+				//the first sequence of the server, as the stream of the client used to be
+				answer, err := conn.WriteDataByIdAwait("ask", []byte("question"))
+				if err != nil || answer == nil {
+					asked <- ""
+				} else {
+					asked <- string(answer.GetData())
+				}
+				p := &defs.Packet{}
+				p.SetData([]byte("streamed"))
+				s.Send(p)
+			}()
+		case "echo":
+			reply(conn, packet, "echoed")
+		}
+	})
+	srv.Serve()
+	defer srv.Shutdown(0)
+
+	received := make(chan defs.IPacket, 4)
+	client := dial(t, srv.Host(), module.NewHeadCodec(), func(conn defs.IConnection, packet defs.IPacket) {
+		if packet.GetId() == "ask" {
+			reply(conn, packet, "answer")
+			return
+		}
+		received <- packet
+	})
+
+	open := &defs.Packet{}
+	open.SetId("open")
+	s, err := client.OpenStream(open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	echo, err := client.SendDataByIdAwait("echo", []byte("x"))
+	if err != nil || echo == nil || string(echo.GetData()) != "echoed" {
+		t.Fatalf("await reply %v, %v", echo, err)
+	}
+	select {
+	case answer := <-asked:
+		if answer != "answer" {
+			t.Fatalf("server await got %q", answer)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("server await not answered")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	p, err := s.RecvCtx(ctx)
+	if err != nil || string(p.GetData()) != "streamed" {
+		t.Fatalf("stream received %v, %v", p, err)
+	}
+	select {
+	case p := <-received:
+		t.Fatalf("packet %v %q delivered outside its call or stream", p.GetId(), p.GetData())
+	default:
+	}
+}
@@ -6,6 +6,8 @@
 package network
 
 import (
+	"context"
+	"crypto/tls"
 	"github.com/lightning-go/lightning/defs"
 	"net"
 	"github.com/lightning-go/lightning/logger"
@@ -14,17 +16,22 @@ import (
 )
 
 type TcpClient struct {
-	connector    *Connector
-	conn         *Connection
-	name         string
-	codec        defs.ICodec
-	ioModule     defs.IIOModule
-	connCallback defs.ConnCallback
-	msgCallback  defs.MsgCallback
-	retry        bool
-	connected    sync.WaitGroup
-	timeout      time.Duration
-	oneStep      bool
+	connector     *Connector
+	conn          *Connection
+	name          string
+	codec         defs.ICodec
+	ioModule      defs.IIOModule
+	connCallback  defs.ConnCallback
+	msgCallback   defs.MsgCallback
+	retry         bool
+	connected     sync.WaitGroup
+	timeout       time.Duration
+	oneStep       bool
+	heartbeat     time.Duration
+	idleTimeout   time.Duration
+	maxPacketSize int
+	outbox        *Outbox
+	connMux       sync.RWMutex
 }
 
 func NewTcpClient(name, addr string) *TcpClient {
@@ -48,6 +55,17 @@ func (tcpClient *TcpClient) SetOneStep(val bool) {
 }
 
 func (tcpClient *TcpClient) GetConn() defs.IConnection {
+	conn := tcpClient.getConn()
+	if conn == nil {
+		return nil
+	}
+	return conn
+}
+
+//the connection is replaced on reconnect while packets are sent
+func (tcpClient *TcpClient) getConn() *Connection {
+	tcpClient.connMux.RLock()
+	defer tcpClient.connMux.RUnlock()
 	return tcpClient.conn
 }
 
@@ -59,6 +77,41 @@ func (tcpClient *TcpClient) SetTimeout(val time.Duration) {
 	tcpClient.timeout = val
 }
 
+//dials the remote over tls when cfg is not nil
+func (tcpClient *TcpClient) SetTLSConfig(cfg *tls.Config) {
+	tcpClient.connector.SetTLSConfig(cfg)
+}
+
+//see TcpServer.SetHeartbeat
+func (tcpClient *TcpClient) SetHeartbeat(interval, idleTimeout time.Duration) {
+	tcpClient.heartbeat = interval
+	tcpClient.idleTimeout = idleTimeout
+}
+
+//SetReconnectPolicy sets the backoff between failed dials
+func (tcpClient *TcpClient) SetReconnectPolicy(policy *ReconnectPolicy) {
+	tcpClient.connector.SetReconnectPolicy(policy)
+}
+
+func (tcpClient *TcpClient) SetReconnectCallback(cb func(*ReconnectEvent)) {
+	tcpClient.connector.SetReconnectCallback(cb)
+}
+
+//SetOutbox holds the packets sent while reconnecting and replays them once
+//connected, after the connection callback. Packets the callback writes on
+//the connection itself, e.g. auth, go ahead of them
+func (tcpClient *TcpClient) SetOutbox(outbox *Outbox) {
+	tcpClient.outbox = outbox
+}
+
+func (tcpClient *TcpClient) GetOutbox() *Outbox {
+	return tcpClient.outbox
+}
+
+func (tcpClient *TcpClient) SetMaxPacketSize(size int) {
+	tcpClient.maxPacketSize = size
+}
+
 func (tcpClient *TcpClient) SetCodec(codec defs.ICodec) {
 	tcpClient.codec = codec
 }
@@ -84,41 +137,49 @@ func (tcpClient *TcpClient) SetRetry(v bool) {
 }
 
 func (tcpClient *TcpClient) connectionHandle(conn net.Conn) {
-	tcpConn, ok := conn.(*net.TCPConn)
-	if !ok {
+	c := NewConnection(conn)
+	if c == nil {
 		return
 	}
-	tcpConn.SetNoDelay(true)
-
-	tcpClient.conn = NewConnection(conn)
-	if tcpClient.conn == nil {
-		return
-	}
-	tcpClient.conn.SetCodec(tcpClient.codec)
-	tcpClient.conn.SetIOModule(tcpClient.ioModule)
-	tcpClient.conn.SetCloseCallback(tcpClient.CloseConnection)
-	tcpClient.conn.SetConnCallback(tcpClient.connCallback)
-	tcpClient.conn.SetMsgCallback(tcpClient.msgCallback)
+	c.SetCodec(tcpClient.codec)
+	c.SetHeartbeat(tcpClient.heartbeat, tcpClient.idleTimeout)
+	c.SetMaxPacketSize(tcpClient.maxPacketSize)
+	c.SetIOModule(tcpClient.ioModule)
+	c.SetCloseCallback(tcpClient.CloseConnection)
+	c.SetConnCallback(tcpClient.connCallback)
+	c.SetMsgCallback(tcpClient.msgCallback)
+	tcpClient.connMux.Lock()
+	tcpClient.conn = c
+	tcpClient.connMux.Unlock()
 
 	if !tcpClient.oneStep {
-		if !tcpClient.conn.Start() {
+		if !c.Start() {
 			return
 		}
+		tcpClient.replay()
 	}
 
 	tcpClient.connected.Done()
 }
 
 func (tcpClient *TcpClient) Start() {
-	if tcpClient.conn == nil || tcpClient.conn.IsClosed() {
+	conn := tcpClient.getConn()
+	if conn == nil || conn.IsClosed() {
 		return
 	}
-	tcpClient.conn.Start()
+	if conn.Start() {
+		tcpClient.replay()
+	}
 }
 
 func (tcpClient *TcpClient) Close() bool {
 	tcpClient.retry = false
-	return tcpClient.conn.Close()
+	tcpClient.connector.Stop()
+	conn := tcpClient.getConn()
+	if conn == nil {
+		return true
+	}
+	return conn.Close()
 }
 
 func (tcpClient *TcpClient) Cancel() {
@@ -131,38 +192,100 @@ func (tcpClient *TcpClient) CloseConnection(conn defs.IConnection) {
 		conn.OnConnection()
 	}
 	if tcpClient.retry {
+		if tcpClient.outbox != nil {
+			tcpClient.outbox.hold()
+		}
 		tcpClient.connected.Add(1)
 	}
 	tcpClient.connector.Close(tcpClient.retry)
 }
 
 func (tcpClient *TcpClient) Connect() defs.IConnection {
+	return tcpClient.ConnectCtx(context.Background())
+}
+
+//ConnectCtx returns nil when dialing gave up, ctx done stops the reconnects too
+func (tcpClient *TcpClient) ConnectCtx(ctx context.Context) defs.IConnection {
 	tcpClient.connected.Add(1)
-	go tcpClient.connector.Start(tcpClient.timeout)
+	go tcpClient.connector.StartCtx(ctx, tcpClient.timeout)
 	tcpClient.connected.Wait()
-	return tcpClient.conn
+	return tcpClient.GetConn()
+}
+
+//held puts the packet in the outbox while reconnecting, false when it is
+//for the connection
+func (tcpClient *TcpClient) held(packet defs.IPacket) bool {
+	if tcpClient.outbox == nil {
+		return false
+	}
+	return tcpClient.outbox.pushHeld(packet, tcpClient.down)
+}
+
+func (tcpClient *TcpClient) down() bool {
+	conn := tcpClient.getConn()
+	return conn == nil || conn.IsClosed()
+}
+
+func (tcpClient *TcpClient) replay() {
+	if tcpClient.outbox == nil {
+		return
+	}
+	conn := tcpClient.getConn()
+	tcpClient.outbox.Flush(func(packet defs.IPacket) bool {
+		if conn.IsClosed() {
+			return false
+		}
+		conn.WritePacket(packet)
+		return true
+	})
 }
 
 func (tcpClient *TcpClient) SendPacket(packet defs.IPacket) {
-	tcpClient.conn.WritePacket(packet)
+	if packet == nil || tcpClient.held(packet) {
+		return
+	}
+	tcpClient.getConn().WritePacket(packet)
 }
 
 func (tcpClient *TcpClient) SendData(data []byte) {
-	tcpClient.conn.WriteData(data)
+	tcpClient.SendDataById("", data)
 }
 
 func (tcpClient *TcpClient) SendDataById(id string, data []byte) {
-	tcpClient.conn.WriteDataById(id, data)
+	if tcpClient.outbox == nil {
+		tcpClient.getConn().WriteDataById(id, data)
+		return
+	}
+	if len(data) == 0 {
+		return
+	}
+	tcpClient.SendPacket(newDataPacket(id, data))
 }
 
 func (tcpClient *TcpClient) SendPacketAwait(packet defs.IPacket) (defs.IPacket, error) {
-	return tcpClient.conn.WritePacketAwait(packet)
+	return tcpClient.getConn().WritePacketAwait(packet)
 }
 
 func (tcpClient *TcpClient) SendDataAwait(data []byte) (defs.IPacket, error) {
-	return tcpClient.conn.WriteDataAwait(data)
+	return tcpClient.getConn().WriteDataAwait(data)
 }
 
 func (tcpClient *TcpClient) SendDataByIdAwait(id string, data []byte) (defs.IPacket, error) {
-	return tcpClient.conn.WriteDataByIdAwait(id, data)
+	return tcpClient.getConn().WriteDataByIdAwait(id, data)
+}
+
+func (tcpClient *TcpClient) SendPacketAwaitCtx(ctx context.Context, packet defs.IPacket) (defs.IPacket, error) {
+	return tcpClient.getConn().WritePacketAwaitCtx(ctx, packet)
+}
+
+func (tcpClient *TcpClient) SendDataAwaitCtx(ctx context.Context, data []byte) (defs.IPacket, error) {
+	return tcpClient.getConn().WriteDataAwaitCtx(ctx, data)
+}
+
+func (tcpClient *TcpClient) SendDataByIdAwaitCtx(ctx context.Context, id string, data []byte) (defs.IPacket, error) {
+	return tcpClient.getConn().WriteDataByIdAwaitCtx(ctx, id, data)
+}
+
+func (tcpClient *TcpClient) OpenStream(packet defs.IPacket) (defs.IStream, error) {
+	return tcpClient.getConn().OpenStream(packet)
 }
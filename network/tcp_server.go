@@ -6,9 +6,13 @@
 package network
 
 import (
+	"crypto/tls"
 	"net"
+	"github.com/lightning-go/lightning/conf"
 	"github.com/lightning-go/lightning/defs"
 	"github.com/lightning-go/lightning/logger"
+	"github.com/lightning-go/lightning/module"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,7 +21,7 @@ const ReAcceptDelay = 5
 type TcpServer struct {
 	listener              net.Listener
 	name                  string
-	maxConn               int
+	connLimiter           *ConnLimiter
 	connMgr               *ConnectionMgr
 	ioModuleCallback      defs.NewIOModuleCallback
 	codec                 defs.ICodec
@@ -26,14 +30,25 @@ type TcpServer struct {
 	exitCallback          defs.ExitCallback
 	authCallback          defs.AuthorizedCallback
 	writeCompleteCallback defs.WriteCompleteCallback
+	rejectCallback        defs.RejectCallback
+	rejectPacket          defs.IPacket
+	shutdownPacket        defs.IPacket
+	drainChecks           []func() bool
+	closing               int32
+	heartbeat             time.Duration
+	idleTimeout           time.Duration
+	maxPacketSize         int
+	codecErrCallback      defs.CodecErrorCallback
+	tlsConfig             *tls.Config
+	limit                 writeLimit
 }
 
 func NewTcpServer(addr, name string, maxConn int) *TcpServer {
 	return &TcpServer{
-		listener: ListenTcp(addr),
-		name:     name,
-		maxConn:  maxConn,
-		connMgr:  NewConnMgr(),
+		listener:    ListenTcp(addr),
+		name:        name,
+		connLimiter: NewConnLimiter(maxConn),
+		connMgr:     NewConnMgr(),
 	}
 }
 
@@ -73,6 +88,83 @@ func (tcpServer *TcpServer) SetWriteCompleteCallback(cb defs.WriteCompleteCallba
 	tcpServer.writeCompleteCallback = cb
 }
 
+//SetWritePolicy decides what writes do once the write queue of a connection
+//is full, timeout bounds the wait of WriteBlock
+func (tcpServer *TcpServer) SetWritePolicy(policy defs.WritePolicy, timeout time.Duration) {
+	tcpServer.limit.policy = policy
+	tcpServer.limit.timeout = timeout
+}
+
+//cb is called once the write queue of a connection reaches mark, mark <= 0 disables
+func (tcpServer *TcpServer) SetHighWatermarkCallback(cb defs.WatermarkCallback, mark int) {
+	tcpServer.limit.highCallback = cb
+	tcpServer.limit.highMark = mark
+}
+
+//cb is called once the queue falls back to mark after reaching the high watermark
+func (tcpServer *TcpServer) SetLowWatermarkCallback(cb defs.WatermarkCallback, mark int) {
+	tcpServer.limit.lowCallback = cb
+	tcpServer.limit.lowMark = mark
+}
+
+func (tcpServer *TcpServer) SetMaxConn(maxConn int) {
+	tcpServer.connLimiter.SetMaxConn(maxConn)
+}
+
+func (tcpServer *TcpServer) SetMaxConnPerIp(maxPerIp int) {
+	tcpServer.connLimiter.SetMaxConnPerIp(maxPerIp)
+}
+
+//accepted connections are served over tls when cfg is not nil
+func (tcpServer *TcpServer) SetTLSConfig(cfg *tls.Config) {
+	tcpServer.tlsConfig = cfg
+}
+
+//connections ping the peer after interval without reads and are closed
+//after idleTimeout without reads, zero disables. Only codecs implementing
+//defs.IPacketIdCodec carry the ping, over StreamCodec only the idle timeout applies
+func (tcpServer *TcpServer) SetHeartbeat(interval, idleTimeout time.Duration) {
+	tcpServer.heartbeat = interval
+	tcpServer.idleTimeout = idleTimeout
+}
+
+//size <= 0 falls back to GlobalVal.MaxPacketSize
+func (tcpServer *TcpServer) SetMaxPacketSize(size int) {
+	tcpServer.maxPacketSize = size
+}
+
+//called before a connection sending an oversized or malformed frame is closed,
+//and for a packet dropped because it could not be encoded, e.g. too large
+func (tcpServer *TcpServer) SetCodecErrorCallback(cb defs.CodecErrorCallback) {
+	tcpServer.codecErrCallback = cb
+}
+
+func (tcpServer *TcpServer) SetRejectCallback(cb defs.RejectCallback) {
+	tcpServer.rejectCallback = cb
+}
+
+//packet written to an overflow connection via the server codec before it is closed
+func (tcpServer *TcpServer) SetRejectPacket(packet defs.IPacket) {
+	tcpServer.rejectPacket = packet
+}
+
+//packet written to every connection when the server starts draining
+func (tcpServer *TcpServer) SetShutdownPacket(packet defs.IPacket) {
+	tcpServer.shutdownPacket = packet
+}
+
+//check reported as drained only when it returns true, e.g. a db queue is empty
+func (tcpServer *TcpServer) AddDrainCheck(check func() bool) {
+	if check == nil {
+		return
+	}
+	tcpServer.drainChecks = append(tcpServer.drainChecks, check)
+}
+
+func (tcpServer *TcpServer) IsClosing() bool {
+	return atomic.LoadInt32(&tcpServer.closing) > 0
+}
+
 func (tcpServer *TcpServer) Host() string {
 	return tcpServer.listener.Addr().String()
 }
@@ -113,15 +205,56 @@ func (tcpServer *TcpServer) serveTcp() {
 				time.Sleep(tmpDelay)
 				continue
 			}
+			if !tcpServer.IsClosing() {
+				logger.Errorf("%v accept error: %v", tcpServer.name, err)
+			}
+			return
 		}
 		tmpDelay = 0
 
-		onlineCount := tcpServer.connMgr.ConnCount()
-		if onlineCount >= 3000 { //todo
-			//todo conn limit
-		} else {
-			go tcpServer.connectionHandle(conn)
+		err = tcpServer.connLimiter.Acquire(conn.RemoteAddr().String())
+		if err != nil {
+			go tcpServer.reject(conn, err)
+			continue
+		}
+		go tcpServer.connectionHandle(conn)
+	}
+}
+
+func (tcpServer *TcpServer) reject(conn net.Conn, reason error) {
+	addr := conn.RemoteAddr().String()
+	logger.Warnf("%v reject connection %v: %v", tcpServer.name, addr, reason)
+
+	if tcpServer.rejectPacket != nil {
+		tcpServer.writeRejectPacket(conn)
+	}
+	conn.Close()
+
+	if tcpServer.rejectCallback != nil {
+		tcpServer.rejectCallback(addr, reason)
+	}
+}
+
+func (tcpServer *TcpServer) writeRejectPacket(conn net.Conn) {
+	if tcpServer.tlsConfig != nil {
+		tlsConn := tls.Server(conn, tcpServer.tlsConfig)
+		if tlsHandshake(tlsConn) != nil {
+			return
 		}
+		conn = tlsConn
+	}
+	conn.SetWriteDeadline(time.Now().Add(conf.GetGlobalVal().WriteWait))
+
+	codec := module.NewCodec(tcpServer.codec)
+	if codec == nil {
+		codec = module.NewStreamCodec()
+	}
+	if !codec.Init(NewConnection(conn)) {
+		return
+	}
+	err := codec.Write(tcpServer.rejectPacket)
+	if err != nil {
+		logger.Trace(err)
 	}
 }
 
@@ -130,9 +263,23 @@ func (tcpServer *TcpServer) connectionHandle(conn net.Conn) {
 		return
 	}
 
+	setNoDelay(conn)
+	if tcpServer.tlsConfig != nil {
+		tlsConn := tls.Server(conn, tcpServer.tlsConfig)
+		err := tlsHandshake(tlsConn)
+		if err != nil {
+			logger.Warnf("%v tls handshake with %v failed: %v", tcpServer.name, conn.RemoteAddr(), err)
+			tcpServer.connLimiter.Release(conn.RemoteAddr().String())
+			conn.Close()
+			return
+		}
+		conn = tlsConn
+	}
+
 	newConn := tcpServer.newConnection(conn)
 	if newConn == nil {
 		logger.Error("alloc new connection failed")
+		tcpServer.connLimiter.Release(conn.RemoteAddr().String())
 		conn.Close()
 		return
 	}
@@ -140,6 +287,7 @@ func (tcpServer *TcpServer) connectionHandle(conn net.Conn) {
 	ok := newConn.Start()
 	if !ok {
 		logger.Error("new connection start failed")
+		tcpServer.connLimiter.Release(conn.RemoteAddr().String())
 		conn.Close()
 		return
 	}
@@ -148,12 +296,6 @@ func (tcpServer *TcpServer) connectionHandle(conn net.Conn) {
 }
 
 func (tcpServer *TcpServer) newConnection(conn net.Conn) *Connection {
-	tcpConn, ok := conn.(*net.TCPConn)
-	if !ok {
-		return nil
-	}
-	tcpConn.SetNoDelay(true)
-
 	newConn := NewConnection(conn)
 	if newConn == nil {
 		return nil
@@ -162,11 +304,15 @@ func (tcpServer *TcpServer) newConnection(conn net.Conn) *Connection {
 		newConn.SetIOModule(tcpServer.ioModuleCallback(newConn))
 	}
 	newConn.SetCodec(tcpServer.codec)
+	newConn.SetHeartbeat(tcpServer.heartbeat, tcpServer.idleTimeout)
+	newConn.SetMaxPacketSize(tcpServer.maxPacketSize)
+	newConn.SetCodecErrorCallback(tcpServer.codecErrCallback)
 	newConn.SetCloseCallback(tcpServer.CloseConnection)
 	newConn.SetConnCallback(tcpServer.connCallback)
 	newConn.SetMsgCallback(tcpServer.msgCallback)
 	newConn.SetAuthorizedCallback(tcpServer.authCallback)
 	newConn.SetWriteCompleteCallback(tcpServer.writeCompleteCallback)
+	newConn.setWriteLimit(tcpServer.limit)
 	return newConn
 }
 
@@ -176,13 +322,53 @@ func (tcpServer *TcpServer) CloseConnection(conn defs.IConnection) {
 	}
 	logger.Tracef("close connection: %v", conn.GetId())
 	tcpServer.connMgr.DelConn(conn.GetId())
+	tcpServer.connLimiter.Release(conn.RemoteAddr())
 	conn.OnConnection()
 }
 
 func (tcpServer *TcpServer) Stop() {
+	tcpServer.Shutdown(conf.GetGlobalVal().DrainTimeout)
+}
+
+//Shutdown stops accepting, notifies the connected peers and waits up to
+//timeout for the pending writes and drain checks before closing everything
+func (tcpServer *TcpServer) Shutdown(timeout time.Duration) {
+	if !atomic.CompareAndSwapInt32(&tcpServer.closing, 0, 1) {
+		return
+	}
+	err := tcpServer.listener.Close()
+	if err != nil {
+		logger.Warn(err)
+	}
+	logger.Warnf("%v server draining, online %v", tcpServer.name, tcpServer.connMgr.ConnCount())
+
+	if tcpServer.shutdownPacket != nil {
+		tcpServer.connMgr.RangeConn(func(conn defs.IConnection) bool {
+			conn.WritePacket(tcpServer.shutdownPacket)
+			return true
+		})
+	}
+
+	if !waitDrain(timeout, tcpServer.isDrained) {
+		logger.Warnf("%v server drain timeout", tcpServer.name)
+	}
+	tcpServer.connMgr.Clean()
+
 	if tcpServer.exitCallback != nil {
 		tcpServer.exitCallback()
 	}
 
 	logger.Warnf("stop %v server", tcpServer.name)
 }
+
+func (tcpServer *TcpServer) isDrained() bool {
+	if tcpServer.connMgr.WriteQueueLen() > 0 {
+		return false
+	}
+	for _, check := range tcpServer.drainChecks {
+		if !check() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package network
+
+import (
+	"time"
+
+	"github.com/lightning-go/lightning/defs"
+)
+
+//writeLimit holds the write queue settings servers hand to their connections
+type writeLimit struct {
+	policy       defs.WritePolicy
+	timeout      time.Duration
+	highMark     int
+	lowMark      int
+	highCallback defs.WatermarkCallback
+	lowCallback  defs.WatermarkCallback
+}
+
+//apply is a no-op for io modules without write limits
+func (wl *writeLimit) apply(ioModule defs.IIOModule) {
+	limit, ok := ioModule.(defs.IWriteLimit)
+	if !ok {
+		return
+	}
+	limit.SetWritePolicy(wl.policy, wl.timeout)
+	limit.SetWatermark(wl.highMark, wl.lowMark)
+}
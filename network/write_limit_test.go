@@ -0,0 +1,123 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/module"
+)
+
+//slowCodec takes a while for each packet so the write queue builds up
+type slowCodec struct {
+	module.HeadCodec
+}
+
+func (sc *slowCodec) WriteBuffered(packet defs.IPacket) error {
+	time.Sleep(5 * time.Millisecond)
+	return sc.HeadCodec.WriteBuffered(packet)
+}
+
+//the server callbacks reach each connection and fire once per crossing
+func TestServerWatermark(t *testing.T) {
+	high := make(chan int, 4)
+	low := make(chan int, 4)
+	srv := newServer(&slowCodec{}, func(conn defs.IConnection, packet defs.IPacket) {
+		for i := 0; i < 10; i++ {
+			conn.WriteData([]byte("data"))
+		}
+	})
+	srv.SetHighWatermarkCallback(func(conn defs.IConnection, n int) {
+		high <- n
+	}, 5)
+	srv.SetLowWatermarkCallback(func(conn defs.IConnection, n int) {
+		low <- n
+	}, 1)
+	srv.Serve()
+	defer srv.Shutdown(0)
+
+	received := make(chan defs.IPacket, 10)
+	client := dial(t, srv.Host(), &module.HeadCodec{}, func(conn defs.IConnection, packet defs.IPacket) {
+		received <- packet
+	})
+	defer client.Close()
+	client.SendData([]byte("go"))
+
+	for i := 0; i < 10; i++ {
+		recvPacket(t, received)
+	}
+	select {
+	case n := <-high:
+		if n < 5 {
+			t.Fatalf("high watermark at %v", n)
+		}
+	default:
+		t.Fatal("high watermark not reported")
+	}
+	select {
+	case n := <-low:
+		if n > 1 {
+			t.Fatalf("low watermark at %v", n)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("low watermark not reported")
+	}
+	if len(high) != 0 || len(low) != 0 {
+		t.Fatalf("%v high and %v low reported again", len(high), len(low))
+	}
+}
+
+func slowServer(t *testing.T, policy defs.WritePolicy, count int) *TcpServer {
+	val := conf.GetGlobalVal()
+	old := val.MaxQueueSize
+	val.MaxQueueSize = 2
+	t.Cleanup(func() {
+		val.MaxQueueSize = old
+	})
+
+	srv := newServer(&slowCodec{}, func(conn defs.IConnection, packet defs.IPacket) {
+		for i := 0; i < count; i++ {
+			conn.WriteData([]byte("data"))
+		}
+	})
+	srv.SetWritePolicy(policy, 0)
+	srv.Serve()
+	t.Cleanup(func() {
+		srv.Shutdown(0)
+	})
+	return srv
+}
+
+//a full queue drops the packets and keeps the connection
+func TestServerWriteDropNewest(t *testing.T) {
+	srv := slowServer(t, defs.WriteDropNewest, 20)
+	received := make(chan defs.IPacket, 20)
+	client := dial(t, srv.Host(), &module.HeadCodec{}, func(conn defs.IConnection, packet defs.IPacket) {
+		received <- packet
+	})
+	defer client.Close()
+	client.SendData([]byte("go"))
+
+	recvPacket(t, received)
+	time.Sleep(200 * time.Millisecond)
+	if n := len(received) + 1; n >= 20 {
+		t.Fatalf("received all %v packets", n)
+	}
+	if client.GetConn().IsClosed() {
+		t.Fatal("connection closed")
+	}
+}
+
+//a full queue closes the slow consumer
+func TestServerWriteDisconnect(t *testing.T) {
+	srv := slowServer(t, defs.WriteDisconnect, 20)
+	client := dial(t, srv.Host(), &module.HeadCodec{}, nil)
+	client.SendData([]byte("go"))
+	waitClosed(t, client.GetConn())
+}
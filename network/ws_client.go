@@ -6,16 +6,18 @@
 package network
 
 import (
+	"context"
+	"crypto/tls"
 	"github.com/gorilla/websocket"
 	"github.com/lightning-go/lightning/defs"
 	"sync"
 	"github.com/lightning-go/lightning/logger"
 	"net/url"
-	"time"
 )
 
 type WSClient struct {
-	connector    *websocket.Dialer
+	connector    *Connector
+	dialer       *websocket.Dialer
 	conn         *WSConnection
 	name         string
 	addr         string
@@ -27,25 +29,77 @@ type WSClient struct {
 	msgCallback  defs.MsgCallback
 	retry        bool
 	connected    sync.WaitGroup
-	close        chan bool
+	outbox       *Outbox
+	connMux      sync.RWMutex
 }
 
 func NewWSClient(name, addr string, path ...string) *WSClient {
 	client := &WSClient{
-		connector: &websocket.Dialer{},
+		connector: NewConnector(addr),
+		dialer:    &websocket.Dialer{},
 		conn:      nil,
 		name:      name,
 		addr:      addr,
 		retry:     true,
 		msgType:   websocket.TextMessage,
-		close:     make(chan bool),
 	}
 	if len(path) > 0 {
 		client.path = path[0]
 	}
+	client.connector.SetDialCallback(client.dial)
+	client.connector.SetCancelCallback(client.Cancel)
 	return client
 }
 
+//dials wss when cfg is not nil
+func (wsclient *WSClient) SetTLSConfig(cfg *tls.Config) {
+	wsclient.dialer.TLSClientConfig = cfg
+}
+
+//offers permessage-deflate to the server
+func (wsclient *WSClient) EnableCompression(val bool) {
+	wsclient.dialer.EnableCompression = val
+}
+
+//SetReconnectPolicy sets the backoff between failed dials
+func (wsclient *WSClient) SetReconnectPolicy(policy *ReconnectPolicy) {
+	wsclient.connector.SetReconnectPolicy(policy)
+}
+
+func (wsclient *WSClient) SetReconnectCallback(cb func(*ReconnectEvent)) {
+	wsclient.connector.SetReconnectCallback(cb)
+}
+
+//SetOutbox holds the packets sent while reconnecting and replays them once
+//connected, after the connection callback. Packets the callback writes on
+//the connection itself, e.g. auth, go ahead of them
+func (wsclient *WSClient) SetOutbox(outbox *Outbox) {
+	wsclient.outbox = outbox
+}
+
+func (wsclient *WSClient) GetOutbox() *Outbox {
+	return wsclient.outbox
+}
+
+func (wsclient *WSClient) IsWorking() bool {
+	return wsclient.connector.IsWorking()
+}
+
+func (wsclient *WSClient) GetConn() defs.IConnection {
+	conn := wsclient.getConn()
+	if conn == nil {
+		return nil
+	}
+	return conn
+}
+
+//the connection is replaced on reconnect while packets are sent
+func (wsclient *WSClient) getConn() *WSConnection {
+	wsclient.connMux.RLock()
+	defer wsclient.connMux.RUnlock()
+	return wsclient.conn
+}
+
 func (wsclient *WSClient) SetMsgType(msgType int) {
 	wsclient.msgType = msgType
 }
@@ -78,30 +132,36 @@ func (wsclient *WSClient) connectionHandle(conn *websocket.Conn) {
 	if conn == nil {
 		return
 	}
-	wsclient.conn = NewWSConnection(conn)
-	if wsclient.conn == nil {
+	c := NewWSConnection(conn)
+	if c == nil {
 		return
 	}
-	wsclient.conn.SetMsgType(wsclient.msgType)
-	wsclient.conn.SetCodec(wsclient.codec)
-	wsclient.conn.SetIOModule(wsclient.ioModule)
-	wsclient.conn.SetCloseCallback(wsclient.CloseConnection)
-	wsclient.conn.SetConnCallback(wsclient.connCallback)
-	wsclient.conn.SetMsgCallback(wsclient.msgCallback)
+	c.SetMsgType(wsclient.msgType)
+	c.SetCodec(wsclient.codec)
+	c.SetIOModule(wsclient.ioModule)
+	c.SetCloseCallback(wsclient.CloseConnection)
+	c.SetConnCallback(wsclient.connCallback)
+	c.SetMsgCallback(wsclient.msgCallback)
+	wsclient.connMux.Lock()
+	wsclient.conn = c
+	wsclient.connMux.Unlock()
 
-	if !wsclient.conn.Start() {
+	if !c.Start() {
 		return
 	}
+	wsclient.replay()
 
 	wsclient.connected.Done()
 }
 
 func (wsclient *WSClient) Close() bool {
 	wsclient.retry = false
-	if wsclient.conn == nil {
+	wsclient.connector.Stop()
+	conn := wsclient.getConn()
+	if conn == nil {
 		return true
 	}
-	return wsclient.conn.Close()
+	return conn.Close()
 }
 
 func (wsclient *WSClient) CloseConnection(conn defs.IConnection) {
@@ -110,63 +170,122 @@ func (wsclient *WSClient) CloseConnection(conn defs.IConnection) {
 		conn.OnConnection()
 	}
 	if wsclient.retry {
+		if wsclient.outbox != nil {
+			wsclient.outbox.hold()
+		}
 		wsclient.connected.Add(1)
 	}
-	wsclient.close <- wsclient.retry
+	wsclient.connector.Close(wsclient.retry)
+}
+
+func (wsclient *WSClient) Cancel() {
+	wsclient.connected.Done()
 }
 
 func (wsclient *WSClient) Connect() defs.IConnection {
+	return wsclient.ConnectCtx(context.Background())
+}
+
+//ConnectCtx returns nil when dialing gave up, ctx done stops the reconnects too
+func (wsclient *WSClient) ConnectCtx(ctx context.Context) defs.IConnection {
 	wsclient.connected.Add(1)
-	go wsclient.connect()
+	go wsclient.connector.StartCtx(ctx, 0)
 	wsclient.connected.Wait()
-	return wsclient.conn
+	return wsclient.GetConn()
 }
 
-func (wsclient *WSClient) connect() {
+func (wsclient *WSClient) dial(ctx context.Context) error {
+	scheme := "ws"
+	if wsclient.dialer.TLSClientConfig != nil {
+		scheme = "wss"
+	}
 	u := url.URL{
-		Scheme: "ws",
+		Scheme: scheme,
 		Host:   wsclient.addr,
 		Path:   wsclient.path,
 	}
-	addr := u.String()
-
-	var tmpDelay time.Duration
-	maxDelay := 3 * time.Second
-
-	for {
-		conn, _, err := wsclient.connector.Dial(addr, nil)
-		if err != nil {
-			if tmpDelay == 0 {
-				tmpDelay = time.Second
-			} else {
-				tmpDelay += time.Second
-			}
-			if tmpDelay > maxDelay {
-				tmpDelay = maxDelay
-			}
-			logger.Warnf("connecting to %v error, retrying in %v second", addr, tmpDelay.Seconds())
-			time.Sleep(tmpDelay)
-			continue
-		}
+	conn, _, err := wsclient.dialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	go wsclient.connectionHandle(conn)
+	return nil
+}
 
-		go wsclient.connectionHandle(conn)
+//held puts the packet in the outbox while reconnecting, false when it is
+//for the connection
+func (wsclient *WSClient) held(packet defs.IPacket) bool {
+	if wsclient.outbox == nil {
+		return false
+	}
+	return wsclient.outbox.pushHeld(packet, wsclient.down)
+}
 
-		retry := <-wsclient.close
-		if !retry {
-			break
-		}
+func (wsclient *WSClient) down() bool {
+	conn := wsclient.getConn()
+	return conn == nil || conn.IsClosed()
+}
 
-		tmpDelay = 0
-		logger.Warnf("reconnecting to %v", addr)
+func (wsclient *WSClient) replay() {
+	if wsclient.outbox == nil {
+		return
 	}
-
-	logger.Warn("connection disconnected")
+	conn := wsclient.getConn()
+	wsclient.outbox.Flush(func(packet defs.IPacket) bool {
+		if conn.IsClosed() {
+			return false
+		}
+		conn.WritePacket(packet)
+		return true
+	})
 }
 
 func (wsclient *WSClient) SendPacket(packet defs.IPacket) {
-	wsclient.conn.WritePacket(packet)
+	if packet == nil || wsclient.held(packet) {
+		return
+	}
+	wsclient.getConn().WritePacket(packet)
 }
 
 func (wsclient *WSClient) SendData(data []byte) {
-	wsclient.conn.WriteData(data)
+	wsclient.SendDataById("", data)
+}
+
+func (wsclient *WSClient) SendDataById(id string, data []byte) {
+	if wsclient.outbox == nil {
+		wsclient.getConn().WriteDataById(id, data)
+		return
+	}
+	if len(data) == 0 {
+		return
+	}
+	wsclient.SendPacket(newDataPacket(id, data))
+}
+
+func (wsclient *WSClient) SendPacketAwait(packet defs.IPacket) (defs.IPacket, error) {
+	return wsclient.getConn().WritePacketAwait(packet)
+}
+
+func (wsclient *WSClient) SendDataAwait(data []byte) (defs.IPacket, error) {
+	return wsclient.getConn().WriteDataAwait(data)
+}
+
+func (wsclient *WSClient) SendDataByIdAwait(id string, data []byte) (defs.IPacket, error) {
+	return wsclient.getConn().WriteDataByIdAwait(id, data)
+}
+
+func (wsclient *WSClient) SendPacketAwaitCtx(ctx context.Context, packet defs.IPacket) (defs.IPacket, error) {
+	return wsclient.getConn().WritePacketAwaitCtx(ctx, packet)
+}
+
+func (wsclient *WSClient) SendDataAwaitCtx(ctx context.Context, data []byte) (defs.IPacket, error) {
+	return wsclient.getConn().WriteDataAwaitCtx(ctx, data)
+}
+
+func (wsclient *WSClient) SendDataByIdAwaitCtx(ctx context.Context, id string, data []byte) (defs.IPacket, error) {
+	return wsclient.getConn().WriteDataByIdAwaitCtx(ctx, id, data)
+}
+
+func (wsclient *WSClient) OpenStream(packet defs.IPacket) (defs.IStream, error) {
+	return wsclient.getConn().OpenStream(packet)
 }
@@ -8,6 +8,7 @@ package network
 import (
 	"context"
 	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/lightning-go/lightning/defs"
@@ -31,6 +32,7 @@ type WSConnection struct {
 	isAuthorized  bool
 	ctx           context.Context
 	msgType       int
+	limit         writeLimit
 }
 
 func NewWSConnection(conn *websocket.Conn) *WSConnection {
@@ -94,6 +96,44 @@ func (wsc *WSConnection) SetWriteCompleteCallback(cb defs.WriteCompleteCallback)
 	wsc.writeComplete = cb
 }
 
+//SetWritePolicy decides what writes do once the write queue is full,
+//timeout bounds the wait of WriteBlock
+func (wsc *WSConnection) SetWritePolicy(policy defs.WritePolicy, timeout time.Duration) {
+	wsc.limit.policy = policy
+	wsc.limit.timeout = timeout
+	if wsc.ioModule != nil {
+		wsc.limit.apply(wsc.ioModule)
+	}
+}
+
+//cb is called once the write queue length reaches mark, mark <= 0 disables
+func (wsc *WSConnection) SetHighWatermarkCallback(cb defs.WatermarkCallback, mark int) {
+	wsc.limit.highCallback = cb
+	wsc.limit.highMark = mark
+}
+
+//cb is called once the queue falls back to mark after reaching the high watermark
+func (wsc *WSConnection) SetLowWatermarkCallback(cb defs.WatermarkCallback, mark int) {
+	wsc.limit.lowCallback = cb
+	wsc.limit.lowMark = mark
+}
+
+func (wsc *WSConnection) setWriteLimit(limit writeLimit) {
+	wsc.limit = limit
+}
+
+func (wsc *WSConnection) OnHighWatermark(n int) {
+	if wsc.limit.highCallback != nil {
+		wsc.limit.highCallback(wsc, n)
+	}
+}
+
+func (wsc *WSConnection) OnLowWatermark(n int) {
+	if wsc.limit.lowCallback != nil {
+		wsc.limit.lowCallback(wsc, n)
+	}
+}
+
 func (wsc *WSConnection) SetContext(key, value interface{}) {
 	utils.SetMapContext(wsc.ctx, key, value)
 }
@@ -144,6 +184,7 @@ func (wsc *WSConnection) Start() bool {
 			return false
 		}
 	}
+	wsc.limit.apply(wsc.ioModule)
 	ok := wsc.ioModule.Codec(wsc.codec)
 	if !ok {
 		logger.Error("io module codec error")
@@ -177,13 +218,20 @@ func (wsc *WSConnection) Close() bool {
 	return true
 }
 
+func (wsc *WSConnection) WriteQueueLen() int {
+	if wsc.ioModule == nil {
+		return 0
+	}
+	return wsc.ioModule.WriteQueueLen()
+}
+
 func (wsc *WSConnection) WriteComplete() {
 	if wsc.writeComplete != nil {
 		wsc.writeComplete(wsc)
 	}
 }
 
-func (wsc *WSConnection) write(packet defs.IPacket, await bool) (defs.IPacket, error) {
+func (wsc *WSConnection) write(ctx context.Context, packet defs.IPacket, await bool) (defs.IPacket, error) {
 	if wsc.IsClosed() {
 		return nil, nil
 	}
@@ -191,44 +239,70 @@ func (wsc *WSConnection) write(packet defs.IPacket, await bool) (defs.IPacket, e
 		return nil, nil
 	}
 	if await {
-		return wsc.ioModule.WriteAwait(packet)
+		return wsc.ioModule.WriteAwaitCtx(ctx, packet)
 	}
 	wsc.ioModule.Write(packet)
 	return nil, nil
 }
 
-func (wsc *WSConnection) writeData(id string, data []byte, await bool) (defs.IPacket, error) {
+func (wsc *WSConnection) writeData(ctx context.Context, id string, data []byte, await bool) (defs.IPacket, error) {
 	if data == nil || len(data) == 0 {
 		return nil, nil
 	}
 	p := &defs.Packet{}
 	p.SetId(id)
 	p.SetData(data)
-	return wsc.write(p, await)
+	return wsc.write(ctx, p, await)
 }
 
 func (wsc *WSConnection) WriteData(data []byte) {
-	wsc.writeData("", data, false)
+	wsc.writeData(context.Background(), "", data, false)
 }
 
 func (wsc *WSConnection) WriteDataById(id string, data []byte) {
-	wsc.writeData(id, data, false)
+	wsc.writeData(context.Background(), id, data, false)
 }
 
 func (wsc *WSConnection) WritePacket(packet defs.IPacket) {
-	wsc.write(packet, false)
+	wsc.write(context.Background(), packet, false)
 }
 
 func (wsc *WSConnection) WriteDataAwait(data []byte) (defs.IPacket, error) {
-	return wsc.writeData("", data, true)
+	return wsc.writeData(context.Background(), "", data, true)
 }
 
 func (wsc *WSConnection) WriteDataByIdAwait(id string, data []byte) (defs.IPacket, error) {
-	return wsc.writeData(id, data, true)
+	return wsc.writeData(context.Background(), id, data, true)
 }
 
 func (wsc *WSConnection) WritePacketAwait(packet defs.IPacket) (defs.IPacket, error) {
-	return wsc.write(packet, true)
+	return wsc.write(context.Background(), packet, true)
+}
+
+func (wsc *WSConnection) WriteDataAwaitCtx(ctx context.Context, data []byte) (defs.IPacket, error) {
+	return wsc.writeData(ctx, "", data, true)
+}
+
+func (wsc *WSConnection) WriteDataByIdAwaitCtx(ctx context.Context, id string, data []byte) (defs.IPacket, error) {
+	return wsc.writeData(ctx, id, data, true)
+}
+
+func (wsc *WSConnection) WritePacketAwaitCtx(ctx context.Context, packet defs.IPacket) (defs.IPacket, error) {
+	return wsc.write(ctx, packet, true)
+}
+
+func (wsc *WSConnection) OpenStream(packet defs.IPacket) (defs.IStream, error) {
+	if wsc.IsClosed() || wsc.ioModule == nil {
+		return nil, module.ErrConnClosed
+	}
+	return wsc.ioModule.OpenStream(packet)
+}
+
+func (wsc *WSConnection) AcceptStream(packet defs.IPacket) (defs.IStream, error) {
+	if wsc.IsClosed() || wsc.ioModule == nil {
+		return nil, module.ErrConnClosed
+	}
+	return wsc.ioModule.AcceptStream(packet)
 }
 
 func (wsc *WSConnection) ReadPacket(packet defs.IPacket) {
@@ -6,12 +6,14 @@
 package network
 
 import (
+	"crypto/tls"
 	"net"
 	"net/http"
 	"github.com/gorilla/websocket"
 	"github.com/lightning-go/lightning/defs"
 	"github.com/lightning-go/lightning/conf"
 	"github.com/lightning-go/lightning/logger"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,7 +22,7 @@ type WSServer struct {
 	addr             string
 	path             string
 	name             string
-	maxConn          int
+	connLimiter      *ConnLimiter
 	msgType          int
 	enablePong       bool
 	connMgr          *ConnectionMgr
@@ -33,18 +35,28 @@ type WSServer struct {
 	exitCallback     defs.ExitCallback
 	authCallback     defs.AuthorizedCallback
 	writeComplete    defs.WriteCompleteCallback
+	rejectCallback   defs.RejectCallback
+	rejectPacket     defs.IPacket
+	shutdownPacket   defs.IPacket
+	drainChecks      []func() bool
+	closing          int32
+	maxPacketSize    int
+	tlsConfig        *tls.Config
+	compress         bool
+	compressLevel    int
+	limit            writeLimit
 }
 
 func NewWSServer(name, addr string, maxConn int, path ...string) *WSServer {
 	wss := &WSServer{
-		listener:   ListenTcp(addr),
-		name:       name,
-		addr:       addr,
-		path:       "/",
-		maxConn:    maxConn,
-		msgType:    websocket.TextMessage,
-		enablePong: false,
-		connMgr:    NewConnMgr(),
+		listener:    ListenTcp(addr),
+		name:        name,
+		addr:        addr,
+		path:        "/",
+		connLimiter: NewConnLimiter(maxConn),
+		msgType:     websocket.TextMessage,
+		enablePong:  false,
+		connMgr:     NewConnMgr(),
 	}
 	if len(path) > 0 && len(path[0]) > 0 {
 		wss.path = path[0]
@@ -56,6 +68,16 @@ func (ws *WSServer) EnablePong(val bool) {
 	ws.enablePong = val
 }
 
+//negotiates permessage-deflate with clients offering it
+func (ws *WSServer) EnableCompression(val bool) {
+	ws.compress = val
+}
+
+//flate level of the compressed writes, zero keeps the default
+func (ws *WSServer) SetCompressionLevel(level int) {
+	ws.compressLevel = level
+}
+
 func (ws *WSServer) SetMsgType(msgType int) {
 	ws.msgType = msgType
 }
@@ -88,6 +110,68 @@ func (ws *WSServer) SetWriteCompleteCallback(cb defs.WriteCompleteCallback) {
 	ws.writeComplete = cb
 }
 
+//SetWritePolicy decides what writes do once the write queue of a connection
+//is full, timeout bounds the wait of WriteBlock
+func (ws *WSServer) SetWritePolicy(policy defs.WritePolicy, timeout time.Duration) {
+	ws.limit.policy = policy
+	ws.limit.timeout = timeout
+}
+
+//cb is called once the write queue of a connection reaches mark, mark <= 0 disables
+func (ws *WSServer) SetHighWatermarkCallback(cb defs.WatermarkCallback, mark int) {
+	ws.limit.highCallback = cb
+	ws.limit.highMark = mark
+}
+
+//cb is called once the queue falls back to mark after reaching the high watermark
+func (ws *WSServer) SetLowWatermarkCallback(cb defs.WatermarkCallback, mark int) {
+	ws.limit.lowCallback = cb
+	ws.limit.lowMark = mark
+}
+
+func (ws *WSServer) SetMaxConn(maxConn int) {
+	ws.connLimiter.SetMaxConn(maxConn)
+}
+
+func (ws *WSServer) SetMaxConnPerIp(maxPerIp int) {
+	ws.connLimiter.SetMaxConnPerIp(maxPerIp)
+}
+
+//serves wss when cfg is not nil
+func (ws *WSServer) SetTLSConfig(cfg *tls.Config) {
+	ws.tlsConfig = cfg
+}
+
+//size <= 0 falls back to GlobalVal.MaxPacketSize
+func (ws *WSServer) SetMaxPacketSize(size int) {
+	ws.maxPacketSize = size
+}
+
+func (ws *WSServer) SetRejectCallback(cb defs.RejectCallback) {
+	ws.rejectCallback = cb
+}
+
+//packet sent as a message to an overflow connection before it is closed
+func (ws *WSServer) SetRejectPacket(packet defs.IPacket) {
+	ws.rejectPacket = packet
+}
+
+//packet written to every connection when the server starts draining
+func (ws *WSServer) SetShutdownPacket(packet defs.IPacket) {
+	ws.shutdownPacket = packet
+}
+
+func (ws *WSServer) AddDrainCheck(check func() bool) {
+	if check == nil {
+		return
+	}
+	ws.drainChecks = append(ws.drainChecks, check)
+}
+
+func (ws *WSServer) IsClosing() bool {
+	return atomic.LoadInt32(&ws.closing) > 0
+}
+
 func (ws *WSServer) Host() string {
 	return ws.listener.Addr().String()
 }
@@ -100,7 +184,8 @@ func (ws *WSServer) Serve() {
 	timeout := conf.GetGlobalVal().HttpTimeout
 
 	ws.upgrader = &websocket.Upgrader{
-		HandshakeTimeout: timeout,
+		HandshakeTimeout:  timeout,
+		EnableCompression: ws.compress,
 		CheckOrigin: func(r *http.Request) bool {
 			return true
 		},
@@ -117,8 +202,13 @@ func (ws *WSServer) Serve() {
 		MaxHeaderBytes: 1024,
 	}
 
+	listener := ws.listener
+	if ws.tlsConfig != nil {
+		listener = tls.NewListener(listener, ws.tlsConfig)
+	}
+
 	logger.Infof("%v server start, listen %v", ws.name, ws.listener.Addr().String())
-	go ws.httpSrv.Serve(ws.listener)
+	go ws.httpSrv.Serve(listener)
 
 	GetSrvMgr().AddServer(ws)
 }
@@ -128,17 +218,22 @@ func (ws *WSServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if ws.IsClosing() {
+		http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
+		return
+	}
 
-	onlineCount := ws.connMgr.ConnCount()
-	if onlineCount >= ws.maxConn {
-		http.Error(w, "Too many connections", http.StatusInternalServerError)
+	err := ws.connLimiter.Acquire(r.RemoteAddr)
+	if err != nil {
+		ws.reject(w, r, err)
 		return
 	}
 
 	conn, err := ws.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		logger.Error(err)
-		http.Error(w, "Method upgrade failed", http.StatusInternalServerError)
+		ws.connLimiter.Release(r.RemoteAddr)
+		//the upgrader has already replied with an http error
 		return
 	}
 
@@ -146,12 +241,42 @@ func (ws *WSServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 }
 
+func (ws *WSServer) reject(w http.ResponseWriter, r *http.Request, reason error) {
+	logger.Warnf("%v reject connection %v: %v", ws.name, r.RemoteAddr, reason)
+
+	if ws.rejectPacket == nil {
+		http.Error(w, "Too many connections", http.StatusServiceUnavailable)
+	} else {
+		conn, err := ws.upgrader.Upgrade(w, r, nil)
+		if err == nil {
+			conn.SetWriteDeadline(time.Now().Add(conf.GetGlobalVal().WriteWait))
+			conn.WriteMessage(ws.msgType, ws.rejectPacket.GetData())
+			conn.Close()
+		}
+	}
+
+	if ws.rejectCallback != nil {
+		ws.rejectCallback(r.RemoteAddr, reason)
+	}
+}
+
 func (ws *WSServer) connectionHandle(conn *websocket.Conn) {
 	if conn == nil {
 		return
 	}
 
-	conn.SetReadLimit(int64(conf.GetGlobalVal().MaxPacketSize))
+	readLimit := int64(conf.GetGlobalVal().MaxPacketSize)
+	if ws.maxPacketSize > 0 {
+		readLimit = int64(ws.maxPacketSize)
+	}
+	conn.SetReadLimit(readLimit)
+
+	if ws.compress && ws.compressLevel != 0 {
+		err := conn.SetCompressionLevel(ws.compressLevel)
+		if err != nil {
+			logger.Warn(err)
+		}
+	}
 
 	if ws.enablePong {
 		conn.SetReadDeadline(time.Now().Add(conf.GetGlobalVal().PongWait))
@@ -164,12 +289,15 @@ func (ws *WSServer) connectionHandle(conn *websocket.Conn) {
 	wsConn := ws.newConnection(conn)
 	if wsConn == nil {
 		logger.Error("alloc new ws connection failed")
+		ws.connLimiter.Release(conn.RemoteAddr().String())
 		conn.Close()
+		return
 	}
 
 	ok := wsConn.Start()
 	if !ok {
 		logger.Error("new ws connection start failed")
+		ws.connLimiter.Release(conn.RemoteAddr().String())
 		conn.Close()
 		return
 	}
@@ -192,6 +320,7 @@ func (ws *WSServer) newConnection(conn *websocket.Conn) *WSConnection {
 	wsConn.SetMsgCallback(ws.msgCallback)
 	wsConn.SetAuthorizedCallback(ws.authCallback)
 	wsConn.SetWriteCompleteCallback(ws.writeComplete)
+	wsConn.setWriteLimit(ws.limit)
 	return wsConn
 }
 
@@ -201,13 +330,58 @@ func (ws *WSServer) closeConnection(conn defs.IConnection) {
 	}
 	logger.Tracef("CloseConnection: %v", conn.GetId())
 	ws.connMgr.DelConn(conn.GetId())
+	ws.connLimiter.Release(conn.RemoteAddr())
 	conn.OnConnection()
 }
 
 func (ws *WSServer) Stop() {
+	ws.Shutdown(conf.GetGlobalVal().DrainTimeout)
+}
+
+//Shutdown stops accepting, notifies the connected peers and waits up to
+//timeout for the pending writes and drain checks before closing everything
+func (ws *WSServer) Shutdown(timeout time.Duration) {
+	if !atomic.CompareAndSwapInt32(&ws.closing, 0, 1) {
+		return
+	}
+	if ws.httpSrv != nil {
+		//upgraded connections are hijacked and not closed by the http server
+		err := ws.httpSrv.Close()
+		if err != nil {
+			logger.Warn(err)
+		}
+	} else {
+		ws.listener.Close()
+	}
+	logger.Warnf("%v server draining, online %v", ws.name, ws.connMgr.ConnCount())
+
+	if ws.shutdownPacket != nil {
+		ws.connMgr.RangeConn(func(conn defs.IConnection) bool {
+			conn.WritePacket(ws.shutdownPacket)
+			return true
+		})
+	}
+
+	if !waitDrain(timeout, ws.isDrained) {
+		logger.Warnf("%v server drain timeout", ws.name)
+	}
+	ws.connMgr.Clean()
+
 	if ws.exitCallback != nil {
 		ws.exitCallback()
 	}
 
 	logger.Warnf("stop %v server", ws.name)
 }
+
+func (ws *WSServer) isDrained() bool {
+	if ws.connMgr.WriteQueueLen() > 0 {
+		return false
+	}
+	for _, check := range ws.drainChecks {
+		if !check() {
+			return false
+		}
+	}
+	return true
+}
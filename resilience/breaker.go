@@ -0,0 +1,320 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+//Package resilience keeps a sick backend from taking down its callers:
+//circuit breakers fail calls fast once the error or timeout rate of a
+//backend is too high, and idempotent calls are retried with backoff.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lightning-go/lightning/module"
+)
+
+var ErrOpen = errors.New("circuit open")
+
+const (
+	DefaultWindow           = time.Second * 10
+	DefaultMinRequests      = 20
+	DefaultErrorRate        = 0.5
+	DefaultTimeoutRate      = 0.5
+	DefaultOpenTimeout      = time.Second * 5
+	DefaultHalfOpenRequests = 3
+
+	windowBuckets = 10
+)
+
+type State int32
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type result int
+
+const (
+	resultSuccess result = iota
+	resultFailure
+	resultTimeout
+	resultIgnore
+)
+
+//timeouts are counted apart from the other errors, a call canceled by its
+//caller says nothing of the backend
+func classify(err error) result {
+	switch {
+	case err == nil:
+		return resultSuccess
+	case errors.Is(err, module.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return resultTimeout
+	case errors.Is(err, context.Canceled), errors.Is(err, ErrOpen):
+		return resultIgnore
+	}
+	return resultFailure
+}
+
+type bucket struct {
+	slot     int64
+	total    int
+	failures int
+	timeouts int
+}
+
+//Breaker is closed while the calls go well, it opens once the error or
+//timeout rate of the window is reached and lets a few probes through
+//after the open timeout, their success closes it again
+type Breaker struct {
+	name             string
+	mux              sync.Mutex
+	state            State
+	buckets          [windowBuckets]bucket
+	window           time.Duration
+	minRequests      int
+	errorRate        float64
+	timeoutRate      float64
+	openTimeout      time.Duration
+	halfOpenRequests int
+	openedAt         time.Time
+	probes           int
+	passed           int
+	stateCallback    func(name string, from, to State)
+}
+
+func NewBreaker(name string) *Breaker {
+	return &Breaker{
+		name:             name,
+		window:           DefaultWindow,
+		minRequests:      DefaultMinRequests,
+		errorRate:        DefaultErrorRate,
+		timeoutRate:      DefaultTimeoutRate,
+		openTimeout:      DefaultOpenTimeout,
+		halfOpenRequests: DefaultHalfOpenRequests,
+	}
+}
+
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+//SetWindow sets the duration the rates are taken over
+func (b *Breaker) SetWindow(window time.Duration) {
+	if window < windowBuckets {
+		return
+	}
+	b.mux.Lock()
+	b.window = window
+	b.buckets = [windowBuckets]bucket{}
+	b.mux.Unlock()
+}
+
+//SetMinRequests is the number of calls in the window before the rates count
+func (b *Breaker) SetMinRequests(n int) {
+	b.mux.Lock()
+	b.minRequests = n
+	b.mux.Unlock()
+}
+
+//SetErrorRate opens the circuit at this rate of failed calls, 0 disables it
+func (b *Breaker) SetErrorRate(rate float64) {
+	b.mux.Lock()
+	b.errorRate = rate
+	b.mux.Unlock()
+}
+
+//SetTimeoutRate opens the circuit at this rate of timed out calls, 0 disables it
+func (b *Breaker) SetTimeoutRate(rate float64) {
+	b.mux.Lock()
+	b.timeoutRate = rate
+	b.mux.Unlock()
+}
+
+//SetOpenTimeout is how long the circuit stays open before the probes
+func (b *Breaker) SetOpenTimeout(timeout time.Duration) {
+	b.mux.Lock()
+	b.openTimeout = timeout
+	b.mux.Unlock()
+}
+
+//SetHalfOpenRequests is the number of probes to pass before closing
+func (b *Breaker) SetHalfOpenRequests(n int) {
+	if n < 1 {
+		n = 1
+	}
+	b.mux.Lock()
+	b.halfOpenRequests = n
+	b.mux.Unlock()
+}
+
+func (b *Breaker) SetStateCallback(cb func(name string, from, to State)) {
+	b.mux.Lock()
+	b.stateCallback = cb
+	b.mux.Unlock()
+}
+
+func (b *Breaker) State() State {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.state
+}
+
+//Ready reports whether a call would be let through, without taking a probe
+func (b *Breaker) Ready() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	switch b.state {
+	case StateOpen:
+		return time.Since(b.openedAt) >= b.openTimeout
+	case StateHalfOpen:
+		return b.probes < b.halfOpenRequests
+	}
+	return true
+}
+
+//Allow returns ErrOpen when the call should fail fast, calls let through
+//must be reported with Report
+func (b *Breaker) Allow() error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return ErrOpen
+		}
+		b.setState(StateHalfOpen)
+		fallthrough
+	case StateHalfOpen:
+		if b.probes >= b.halfOpenRequests {
+			return ErrOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+//Report records the result of a call let through by Allow
+func (b *Breaker) Report(err error) {
+	r := classify(err)
+	if r == resultIgnore {
+		b.mux.Lock()
+		if b.state == StateHalfOpen && b.probes > 0 {
+			b.probes--
+		}
+		b.mux.Unlock()
+		return
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	switch b.state {
+	case StateClosed:
+		b.add(r, time.Now())
+		if b.tripped() {
+			b.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		if r != resultSuccess {
+			b.setState(StateOpen)
+			return
+		}
+		b.passed++
+		if b.passed >= b.halfOpenRequests {
+			b.setState(StateClosed)
+		}
+	}
+}
+
+//Do calls f unless the circuit is open and reports its error
+func (b *Breaker) Do(f func() error) error {
+	err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = f()
+	b.Report(err)
+	return err
+}
+
+//Reset closes the circuit and clears the window
+func (b *Breaker) Reset() {
+	b.mux.Lock()
+	b.setState(StateClosed)
+	b.mux.Unlock()
+}
+
+func (b *Breaker) setState(state State) {
+	from := b.state
+	b.state = state
+	b.probes = 0
+	b.passed = 0
+	switch state {
+	case StateOpen:
+		b.openedAt = time.Now()
+	case StateClosed:
+		b.buckets = [windowBuckets]bucket{}
+	}
+	if from != state && b.stateCallback != nil {
+		cb := b.stateCallback
+		name := b.name
+		//outside the lock, the callback may read the breaker
+		go cb(name, from, state)
+	}
+}
+
+func (b *Breaker) slot(now time.Time) int64 {
+	return now.UnixNano() / int64(b.window/windowBuckets)
+}
+
+func (b *Breaker) add(r result, now time.Time) {
+	slot := b.slot(now)
+	bk := &b.buckets[slot%windowBuckets]
+	if bk.slot != slot {
+		*bk = bucket{slot: slot}
+	}
+	bk.total++
+	switch r {
+	case resultFailure:
+		bk.failures++
+	case resultTimeout:
+		bk.timeouts++
+	}
+}
+
+func (b *Breaker) tripped() bool {
+	slot := b.slot(time.Now())
+	total, failures, timeouts := 0, 0, 0
+	for _, bk := range b.buckets {
+		if slot-bk.slot >= windowBuckets {
+			continue
+		}
+		total += bk.total
+		failures += bk.failures
+		timeouts += bk.timeouts
+	}
+	if total == 0 || total < b.minRequests {
+		return false
+	}
+	if b.errorRate > 0 && float64(failures)/float64(total) >= b.errorRate {
+		return true
+	}
+	return b.timeoutRate > 0 && float64(timeouts)/float64(total) >= b.timeoutRate
+}
@@ -0,0 +1,76 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lightning-go/lightning/module"
+)
+
+func TestBreaker(t *testing.T) {
+	b := NewBreaker("logic1")
+	b.SetMinRequests(4)
+	b.SetOpenTimeout(time.Millisecond * 50)
+	b.SetHalfOpenRequests(2)
+
+	fail := errors.New("fail")
+	for i := 0; i < 2; i++ {
+		b.Do(func() error { return nil })
+		b.Do(func() error { return module.ErrTimeout })
+	}
+	if b.State() != StateOpen {
+		t.Fatal("timeouts did not open", b.State())
+	}
+	if err := b.Do(func() error { return nil }); err != ErrOpen {
+		t.Fatal("open circuit let a call through")
+	}
+
+	//a failed probe opens it again
+	time.Sleep(time.Millisecond * 60)
+	b.Do(func() error { return fail })
+	if b.State() != StateOpen {
+		t.Fatal("failed probe", b.State())
+	}
+
+	time.Sleep(time.Millisecond * 60)
+	if !b.Ready() {
+		t.Fatal("not ready after the open timeout")
+	}
+	b.Do(func() error { return nil })
+	if b.State() != StateHalfOpen {
+		t.Fatal("probe", b.State())
+	}
+	b.Do(func() error { return nil })
+	if b.State() != StateClosed {
+		t.Fatal("passed probes did not close", b.State())
+	}
+}
+
+func TestRetry(t *testing.T) {
+	r := NewRetry(3)
+	r.SetBackoff(time.Millisecond, time.Millisecond*5)
+	n := 0
+	err := r.Do(context.Background(), func(attempt int) error {
+		n++
+		return module.ErrTimeout
+	})
+	if err != module.ErrTimeout || n != 3 {
+		t.Fatal(err, n)
+	}
+
+	n = 0
+	err = r.Do(context.Background(), func(attempt int) error {
+		n++
+		return ErrOpen
+	})
+	if err != ErrOpen || n != 1 {
+		t.Fatal("retried an open circuit", n)
+	}
+}
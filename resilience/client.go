@@ -0,0 +1,183 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package resilience
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/logger"
+	"github.com/lightning-go/lightning/module"
+)
+
+//Client guards the calls of a client with a breaker, awaited and forwarded
+//calls are reported to it and fail fast with ErrOpen while it is open,
+//packets sent without reply are dropped
+type Client struct {
+	defs.IClient
+	breaker *Breaker
+	retry   *Retry
+	timeout time.Duration
+}
+
+//NewClient uses a breaker of its own when breaker is nil
+func NewClient(client defs.IClient, breaker *Breaker) *Client {
+	if client == nil {
+		return nil
+	}
+	if breaker == nil {
+		breaker = NewBreaker(client.Name())
+	}
+	return &Client{
+		IClient: client,
+		breaker: breaker,
+		retry:   NewRetry(),
+		timeout: conf.GetGlobalVal().RpcTimeout,
+	}
+}
+
+func (c *Client) Breaker() *Breaker {
+	return c.breaker
+}
+
+//SetRetryPolicy is used by SendPacketAwaitRetry
+func (c *Client) SetRetryPolicy(retry *Retry) {
+	if retry != nil {
+		c.retry = retry
+	}
+}
+
+//SetCallTimeout limits the awaited calls without a context deadline, so a
+//stuck backend counts as timeouts, zero waits forever
+func (c *Client) SetCallTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+func (c *Client) ready() bool {
+	if c.breaker.Ready() {
+		return true
+	}
+	logger.Warnf("circuit of %v open, packet dropped", c.breaker.Name())
+	return false
+}
+
+func (c *Client) SendPacket(packet defs.IPacket) {
+	if c.ready() {
+		c.IClient.SendPacket(packet)
+	}
+}
+
+func (c *Client) SendData(data []byte) {
+	if c.ready() {
+		c.IClient.SendData(data)
+	}
+}
+
+func (c *Client) SendDataById(id string, data []byte) {
+	if c.ready() {
+		c.IClient.SendDataById(id, data)
+	}
+}
+
+//Forward sends packet through the breaker without awaiting the reply. track
+//gets the report of the outcome before the write, to be called once with nil
+//on the reply or with module.ErrTimeout, nil track reports the write. A client
+//not connected fails with module.ErrConnClosed, reported as a failure
+func (c *Client) Forward(packet defs.IPacket, track func(report func(error))) error {
+	err := c.breaker.Allow()
+	if err != nil {
+		return err
+	}
+	var once sync.Once
+	report := func(err error) {
+		once.Do(func() {
+			c.breaker.Report(err)
+		})
+	}
+	conn := c.GetConn()
+	if conn == nil || conn.IsClosed() {
+		report(module.ErrConnClosed)
+		return module.ErrConnClosed
+	}
+	if track == nil {
+		c.IClient.SendPacket(packet)
+		report(nil)
+		return nil
+	}
+	track(report)
+	c.IClient.SendPacket(packet)
+	return nil
+}
+
+func (c *Client) call(ctx context.Context, f func(context.Context) (defs.IPacket, error)) (defs.IPacket, error) {
+	err := c.breaker.Allow()
+	if err != nil {
+		return nil, err
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	reply, err := f(ctx)
+	//the connection was lost before the reply
+	if err == nil && reply == nil {
+		err = module.ErrConnClosed
+	}
+	c.breaker.Report(err)
+	return reply, err
+}
+
+func (c *Client) SendPacketAwait(packet defs.IPacket) (defs.IPacket, error) {
+	return c.SendPacketAwaitCtx(context.Background(), packet)
+}
+
+func (c *Client) SendDataAwait(data []byte) (defs.IPacket, error) {
+	return c.SendDataAwaitCtx(context.Background(), data)
+}
+
+func (c *Client) SendDataByIdAwait(id string, data []byte) (defs.IPacket, error) {
+	return c.SendDataByIdAwaitCtx(context.Background(), id, data)
+}
+
+func (c *Client) SendPacketAwaitCtx(ctx context.Context, packet defs.IPacket) (defs.IPacket, error) {
+	return c.call(ctx, func(ctx context.Context) (defs.IPacket, error) {
+		return c.IClient.SendPacketAwaitCtx(ctx, packet)
+	})
+}
+
+func (c *Client) SendDataAwaitCtx(ctx context.Context, data []byte) (defs.IPacket, error) {
+	return c.call(ctx, func(ctx context.Context) (defs.IPacket, error) {
+		return c.IClient.SendDataAwaitCtx(ctx, data)
+	})
+}
+
+func (c *Client) SendDataByIdAwaitCtx(ctx context.Context, id string, data []byte) (defs.IPacket, error) {
+	return c.call(ctx, func(ctx context.Context) (defs.IPacket, error) {
+		return c.IClient.SendDataByIdAwaitCtx(ctx, id, data)
+	})
+}
+
+//SendPacketAwaitRetry sends an idempotent request again on failure, each
+//attempt gets the call timeout and ctx bounds them all
+func (c *Client) SendPacketAwaitRetry(ctx context.Context, packet defs.IPacket) (defs.IPacket, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var reply defs.IPacket
+	err := c.retry.Do(ctx, func(attempt int) error {
+		var err error
+		reply, err = c.SendPacketAwaitCtx(ctx, packet)
+		return err
+	})
+	return reply, err
+}
@@ -0,0 +1,67 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package resilience
+
+import (
+	"sync"
+
+	"github.com/lightning-go/lightning/selector"
+)
+
+//Group keeps a breaker per backend name
+type Group struct {
+	mux             sync.Mutex
+	breakers        map[string]*Breaker
+	breakerCallback func(*Breaker)
+}
+
+func NewGroup() *Group {
+	return &Group{
+		breakers: make(map[string]*Breaker),
+	}
+}
+
+//SetBreakerCallback configures the breakers the group creates
+func (g *Group) SetBreakerCallback(cb func(*Breaker)) {
+	g.mux.Lock()
+	g.breakerCallback = cb
+	g.mux.Unlock()
+}
+
+//Get returns the breaker of the backend, created on first use
+func (g *Group) Get(name string) *Breaker {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	b, ok := g.breakers[name]
+	if ok {
+		return b
+	}
+	b = NewBreaker(name)
+	if g.breakerCallback != nil {
+		g.breakerCallback(b)
+	}
+	g.breakers[name] = b
+	return b
+}
+
+func (g *Group) Del(name string) {
+	g.mux.Lock()
+	delete(g.breakers, name)
+	g.mux.Unlock()
+}
+
+//Ready reports whether calls to the backend are let through
+func (g *Group) Ready(name string) bool {
+	g.mux.Lock()
+	b, ok := g.breakers[name]
+	g.mux.Unlock()
+	return !ok || b.Ready()
+}
+
+//Filter is the filter callback of the selectors, open circuits are skipped
+func (g *Group) Filter(sd *selector.SessionData) bool {
+	return g.Ready(sd.Name)
+}
@@ -0,0 +1,110 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+const (
+	DefaultRetryAttempts = 3
+	DefaultRetryBase     = time.Millisecond * 50
+	DefaultRetryMax      = time.Second
+)
+
+//Retry calls again after a failure, waiting a jittered backoff that doubles
+//each attempt, only idempotent calls should be retried
+type Retry struct {
+	attempts      int
+	base          time.Duration
+	max           time.Duration
+	retryCallback func(error) bool
+}
+
+func NewRetry(attempts ...int) *Retry {
+	r := &Retry{
+		attempts: DefaultRetryAttempts,
+		base:     DefaultRetryBase,
+		max:      DefaultRetryMax,
+	}
+	if len(attempts) > 0 && attempts[0] > 0 {
+		r.attempts = attempts[0]
+	}
+	return r
+}
+
+//SetAttempts is the number of calls at most, the first included
+func (r *Retry) SetAttempts(attempts int) {
+	if attempts > 0 {
+		r.attempts = attempts
+	}
+}
+
+func (r *Retry) SetBackoff(base, max time.Duration) {
+	r.base = base
+	r.max = max
+}
+
+//SetRetryCallback decides which errors are retried, by default failures and
+//timeouts but not an open circuit or a canceled context
+func (r *Retry) SetRetryCallback(cb func(error) bool) {
+	r.retryCallback = cb
+}
+
+func (r *Retry) retryable(err error) bool {
+	if r.retryCallback != nil {
+		return r.retryCallback(err)
+	}
+	if errors.Is(err, ErrOpen) {
+		return false
+	}
+	res := classify(err)
+	return res == resultFailure || res == resultTimeout
+}
+
+//Backoff is the full jitter wait before the attempt, counted from 1
+func (r *Retry) Backoff(attempt int) time.Duration {
+	d := r.base
+	for i := 1; i < attempt && d < r.max; i++ {
+		d *= 2
+	}
+	if d > r.max {
+		d = r.max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+//Do calls f until it succeeds, its error is not retryable, the attempts are
+//spent or ctx is done, the last error is returned
+func (r *Retry) Do(ctx context.Context, f func(attempt int) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var err error
+	for attempt := 0; attempt < r.attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(r.Backoff(attempt)):
+			}
+		}
+		err = f(attempt)
+		if err == nil || !r.retryable(err) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
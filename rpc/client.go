@@ -0,0 +1,201 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/json-iterator/go"
+	"github.com/lightning-go/lightning/conf"
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/module"
+	"github.com/lightning-go/lightning/utils"
+)
+
+var (
+	ErrSerialize = errors.New("rpc serialize request failed")
+	ErrParse     = errors.New("rpc parse response failed")
+)
+
+//StatusError is returned for replies with a non-zero status, the reply is still decoded
+type StatusError struct {
+	Method string
+	Status int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("rpc %v status %v", e.Method, e.Status)
+}
+
+//Status returns the reply status carried by err, zero when err is not a StatusError
+func Status(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Status
+	}
+	return 0
+}
+
+//Caller is implemented by TcpClient and WSClient
+type Caller interface {
+	SendPacket(defs.IPacket)
+	SendPacketAwaitCtx(context.Context, defs.IPacket) (defs.IPacket, error)
+}
+
+//Client calls ServiceFactory methods by name, the request is serialized and
+//the reply parsed with the same callbacks the service side is configured with
+type Client struct {
+	caller                Caller
+	timeout               time.Duration
+	parseDataCallback     defs.ParseDataCallback
+	serializeDataCallback defs.SerializeDataCallback
+	sendMsg               bool
+}
+
+func NewClient(caller Caller) *Client {
+	return &Client{
+		caller:  caller,
+		timeout: conf.GetGlobalVal().RpcTimeout,
+	}
+}
+
+//calls without a context deadline give up after timeout, zero waits forever
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+func (c *Client) SetParseDataCallback(cb defs.ParseDataCallback) {
+	c.parseDataCallback = cb
+}
+
+func (c *Client) SetSerializeDataCallback(cb defs.SerializeDataCallback) {
+	c.serializeDataCallback = cb
+}
+
+//SetSendMsg sends the requests as registered proto messages instead of
+//serialized data, for callers using ProtoCodec
+func (c *Client) SetSendMsg(v bool) {
+	c.sendMsg = v
+}
+
+func (c *Client) Call(method string, req, resp interface{}) error {
+	return c.CallCtx(context.Background(), method, req, resp)
+}
+
+//CallCtx sends req to method and decodes the reply into resp, resp may be nil
+func (c *Client) CallCtx(ctx context.Context, method string, req, resp interface{}) error {
+	packet, err := c.newPacket(method, req)
+	if err != nil {
+		return err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	reply, err := c.caller.SendPacketAwaitCtx(ctx, packet)
+	if err != nil {
+		return err
+	}
+	//the connection was lost before the reply
+	if reply == nil {
+		return module.ErrConnClosed
+	}
+
+	if resp != nil && !c.parse(reply, resp) {
+		return ErrParse
+	}
+	if reply.GetStatus() != 0 {
+		return &StatusError{Method: method, Status: reply.GetStatus()}
+	}
+	return nil
+}
+
+//Notify sends req to a method without reply
+func (c *Client) Notify(method string, req interface{}) error {
+	packet, err := c.newPacket(method, req)
+	if err != nil {
+		return err
+	}
+	c.caller.SendPacket(packet)
+	return nil
+}
+
+//ProtoCodec dispatches the message by its registered type, other codecs send
+//the serialized data under the method name
+func (c *Client) newPacket(method string, req interface{}) (defs.IPacket, error) {
+	if c.sendMsg {
+		_, ok := module.GetMsgRegistry().GetMsgId(req)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T is not a registered message", ErrSerialize, req)
+		}
+		p := &defs.MsgPacket{}
+		p.SetMsg(req)
+		return p, nil
+	}
+
+	p := &defs.Packet{}
+	p.SetId(method)
+	if req == nil {
+		return p, nil
+	}
+	var data []byte
+	if c.serializeDataCallback == nil {
+		var err error
+		data, err = jsoniter.Marshal(req)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSerialize, err)
+		}
+	} else {
+		//the callbacks return nil on failure
+		data = c.serializeDataCallback(req)
+		if data == nil {
+			return nil, ErrSerialize
+		}
+	}
+	p.SetData(data)
+	return p, nil
+}
+
+func (c *Client) parse(reply defs.IPacket, resp interface{}) bool {
+	msgPacket, ok := reply.(defs.IMsgPacket)
+	if ok && msgPacket.GetMsg() != nil {
+		src, srcOk := msgPacket.GetMsg().(proto.Message)
+		dst, dstOk := resp.(proto.Message)
+		if srcOk && dstOk && reflect.TypeOf(src) == reflect.TypeOf(dst) {
+			proto.Merge(dst, src)
+			return true
+		}
+	}
+
+	data := reply.GetData()
+	if len(data) == 0 {
+		return true
+	}
+	if c.parseDataCallback == nil {
+		return utils.ParseDataByJson(data, resp)
+	}
+	return c.parseDataCallback(data, resp)
+}
+
+//Call uses json with the default timeout
+func Call(caller Caller, method string, req, resp interface{}) error {
+	return NewClient(caller).Call(method, req, resp)
+}
+
+func CallCtx(ctx context.Context, caller Caller, method string, req, resp interface{}) error {
+	return NewClient(caller).CallCtx(ctx, method, req, resp)
+}
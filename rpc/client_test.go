@@ -0,0 +1,99 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package rpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/lightning-go/lightning/defs"
+	"github.com/lightning-go/lightning/logger"
+	"github.com/lightning-go/lightning/module"
+	"github.com/lightning-go/lightning/network"
+	"github.com/lightning-go/lightning/utils"
+)
+
+func init() {
+	module.RegisterMsg(1, &wrappers.StringValue{})
+	module.RegisterMsg(2, &wrappers.Int64Value{})
+}
+
+//Len replies the length of the string
+var lenMethod = &utils.MethodDesc{
+	Name: "Len",
+	NewReq: func() interface{} {
+		return &wrappers.StringValue{}
+	},
+	NewReply: func() interface{} {
+		return &wrappers.Int64Value{}
+	},
+	Invoke: func(session defs.ISession, req, reply interface{}) int {
+		reply.(*wrappers.Int64Value).Value = int64(len(req.(*wrappers.StringValue).Value))
+		return 0
+	},
+}
+
+func dialService(t *testing.T, codec func() defs.ICodec) *network.TcpClient {
+	logger.SetLevel(logger.FATAL)
+	sf := utils.NewServiceFactory()
+	sf.RegisterMethods(lenMethod)
+
+	srv := network.NewTcpServer("127.0.0.1:0", "rpc", 0)
+	srv.SetCodec(codec())
+	srv.SetMsgCallback(func(conn defs.IConnection, packet defs.IPacket) {
+		sf.OnServiceHandle(network.NewSession(conn, packet.GetSessionId(), sf.OnServiceHandle), packet)
+	})
+	srv.Serve()
+	t.Cleanup(func() {
+		srv.Shutdown(0)
+	})
+
+	client := network.NewTcpClient("rpc_cli", srv.Host())
+	client.SetRetry(false)
+	client.SetCodec(codec())
+	if client.Connect() == nil {
+		t.Fatal("connect failed")
+	}
+	return client
+}
+
+func TestCallProtoCodec(t *testing.T) {
+	c := NewClient(dialService(t, func() defs.ICodec { return module.NewProtoCodec() }))
+	c.SetSendMsg(true)
+
+	resp := &wrappers.Int64Value{}
+	err := c.Call("Len", &wrappers.StringValue{Value: "hello"}, resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Value != 5 {
+		t.Fatalf("reply %v", resp.Value)
+	}
+
+	err = c.Call("Len", &wrappers.BoolValue{}, resp)
+	if !errors.Is(err, ErrSerialize) {
+		t.Fatalf("unregistered message: %v", err)
+	}
+}
+
+func TestCallHeadCodec(t *testing.T) {
+	c := NewClient(dialService(t, func() defs.ICodec { return module.NewHeadCodec() }))
+
+	resp := &wrappers.Int64Value{}
+	err := c.Call("Len", &wrappers.StringValue{Value: "hello world"}, resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Value != 11 {
+		t.Fatalf("reply %v", resp.Value)
+	}
+
+	err = c.Call("Len", make(chan int), resp)
+	if !errors.Is(err, ErrSerialize) {
+		t.Fatalf("unserializable request: %v", err)
+	}
+}
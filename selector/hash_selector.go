@@ -0,0 +1,102 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package selector
+
+import (
+	"hash/crc32"
+	"sort"
+	"sync"
+
+	"github.com/lightning-go/lightning/utils"
+)
+
+//HashSelector keeps a key on the same session while the sessions are
+//unchanged, adding or removing one moves few keys. Keys of a filtered
+//session are spread over the others until it passes again
+type HashSelector struct {
+	mux            sync.RWMutex
+	hash           *utils.ConsistentHash
+	sessions       map[string]*SessionData
+	filterCallback func(*SessionData) bool
+}
+
+func NewHashSelector(replicas ...int) *HashSelector {
+	return &HashSelector{
+		hash:     utils.NewConsistentHash(replicas...),
+		sessions: make(map[string]*SessionData),
+	}
+}
+
+func (hs *HashSelector) Set(data *SessionData) {
+	if data == nil {
+		return
+	}
+	hs.mux.Lock()
+	defer hs.mux.Unlock()
+	sd, ok := hs.sessions[data.Name]
+	if ok {
+		sd.Host = data.Host
+		sd.Type = data.Type
+		sd.Weight = data.Weight
+		sd.setLoad(data.Load)
+		return
+	}
+	data.setLoad(data.Load)
+	hs.sessions[data.Name] = data
+	hs.hash.Add(data.Name)
+}
+
+func (hs *HashSelector) Del(name string) {
+	hs.mux.Lock()
+	_, ok := hs.sessions[name]
+	if ok {
+		delete(hs.sessions, name)
+		hs.hash.Remove(name)
+	}
+	hs.mux.Unlock()
+}
+
+func (hs *HashSelector) Get(name string) *SessionData {
+	hs.mux.RLock()
+	defer hs.mux.RUnlock()
+	return hs.sessions[name]
+}
+
+//SetFilterCallback is checked on the hashed session
+func (hs *HashSelector) SetFilterCallback(cb func(*SessionData) bool) {
+	hs.mux.Lock()
+	hs.filterCallback = cb
+	hs.mux.Unlock()
+}
+
+func (hs *HashSelector) Select(key string) *SessionData {
+	hs.mux.RLock()
+	defer hs.mux.RUnlock()
+	n := len(hs.sessions)
+	if n == 0 {
+		return nil
+	}
+	sd := hs.sessions[hs.hash.GetNode(key)]
+	if hs.filterCallback == nil {
+		return sd
+	}
+	if sd != nil && hs.filterCallback(sd) {
+		return sd
+	}
+
+	//the keys of a filtered session are spread over the others
+	names := make([]string, 0, n)
+	for name, sd := range hs.sessions {
+		if hs.filterCallback(sd) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return hs.sessions[names[crc32.ChecksumIEEE([]byte(key))%uint32(len(names))]]
+}
@@ -0,0 +1,130 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package selector
+
+import (
+	"time"
+)
+
+//DefaultLoadTTL is how long a load report is used, stale reports fall back to the weight
+const DefaultLoadTTL = time.Second * 10
+
+//Load is the live load a backend reports
+type Load struct {
+	Sessions int     `json:"sessions"`
+	Queue    int     `json:"queue"`
+	CPU      float64 `json:"cpu"`                //percent of one core
+	Capacity int     `json:"capacity,omitempty"` //sessions at most, 0 is unlimited
+	Time     int64   `json:"time"`               //unix ms of the report
+}
+
+//Saturated reports whether the backend takes no more sessions
+func (l *Load) Saturated() bool {
+	return l.Capacity > 0 && l.Sessions >= l.Capacity
+}
+
+//DefaultLoadScore counts the sessions and queued packets, a busy cpu
+//doubles it at most
+func DefaultLoadScore(l *Load) int {
+	score := l.Sessions + l.Queue
+	cpu := l.CPU
+	if cpu > 100 {
+		cpu = 100
+	}
+	if cpu > 0 {
+		score += int(float64(score) * cpu / 100)
+	}
+	return score
+}
+
+//SetLoadTTL sets how long reports are used, 0 keeps them until replaced
+func (selector *WeightSelector) SetLoadTTL(ttl time.Duration) {
+	selector.mux.Lock()
+	selector.loadTTL = ttl
+	selector.mux.Unlock()
+}
+
+//SetScoreCallback turns a load report into the weight, DefaultLoadScore by default
+func (selector *WeightSelector) SetScoreCallback(cb func(*Load) int) {
+	if cb == nil {
+		return
+	}
+	selector.mux.Lock()
+	selector.scoreCallback = cb
+	selector.mux.Unlock()
+}
+
+//UpdateLoad replaces the load report of the session
+func (selector *WeightSelector) UpdateLoad(key string, load *Load) {
+	selector.mux.Lock()
+	for _, sd := range selector.sessions {
+		if sd == nil {
+			continue
+		}
+		if sd.Name == key {
+			sd.setLoad(load)
+			selector.mux.Unlock()
+			return
+		}
+	}
+	selector.mux.Unlock()
+}
+
+//Set updates the session of the same name in place, or adds it
+func (selector *WeightSelector) Set(data *SessionData) {
+	if data == nil {
+		return
+	}
+	selector.mux.Lock()
+	defer selector.mux.Unlock()
+
+	for _, sd := range selector.sessions {
+		if sd == nil || sd.Name != data.Name {
+			continue
+		}
+		sd.Host = data.Host
+		sd.Type = data.Type
+		sd.Weight = data.Weight
+		sd.setLoad(data.Load)
+		return
+	}
+	data.setLoad(data.Load)
+	selector.sessions = append(selector.sessions, data)
+}
+
+func (selector *WeightSelector) weight(sd *SessionData, now time.Time) int {
+	return loadOf(sd, selector.loadTTL, selector.scoreCallback, now)
+}
+
+func (selector *WeightSelector) available(sd *SessionData, now time.Time) bool {
+	return isAvailable(sd, selector.loadTTL, now) && selector.pass(sd)
+}
+
+func isFresh(sd *SessionData, ttl time.Duration, now time.Time) bool {
+	if sd.Load == nil {
+		return false
+	}
+	return ttl <= 0 || now.Sub(sd.loadTime) <= ttl
+}
+
+//loadOf is the score of a fresh report, the weight otherwise
+func loadOf(sd *SessionData, ttl time.Duration, score func(*Load) int, now time.Time) int {
+	if !isFresh(sd, ttl, now) {
+		return sd.Weight
+	}
+	if score != nil {
+		return score(sd.Load)
+	}
+	return DefaultLoadScore(sd.Load)
+}
+
+//saturated sessions are skipped while their report is fresh
+func isAvailable(sd *SessionData, ttl time.Duration, now time.Time) bool {
+	if sd == nil {
+		return false
+	}
+	return !isFresh(sd, ttl, now) || !sd.Load.Saturated()
+}
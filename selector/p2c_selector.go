@@ -0,0 +1,79 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package selector
+
+import (
+	"math/rand"
+	"time"
+)
+
+//P2CSelector picks two sessions at random and takes the one of less load,
+//it avoids the herd of every gate picking the same least loaded session
+type P2CSelector struct {
+	sessionList
+	scoreCallback func(*Load) int
+	buf           []*SessionData
+}
+
+func NewP2CSelector() *P2CSelector {
+	return &P2CSelector{
+		sessionList: newSessionList(),
+	}
+}
+
+//SetScoreCallback turns a load report into the load, DefaultLoadScore by default
+func (ps *P2CSelector) SetScoreCallback(cb func(*Load) int) {
+	ps.mux.Lock()
+	ps.scoreCallback = cb
+	ps.mux.Unlock()
+}
+
+func (ps *P2CSelector) Set(data *SessionData) {
+	if data == nil {
+		return
+	}
+	ps.mux.Lock()
+	ps.set(data)
+	ps.mux.Unlock()
+}
+
+func (ps *P2CSelector) Del(name string) {
+	ps.mux.Lock()
+	ps.del(name)
+	ps.mux.Unlock()
+}
+
+func (ps *P2CSelector) Select(key string) *SessionData {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	now := time.Now()
+	ps.buf = ps.available(ps.buf[:0], now)
+	list := ps.buf
+	defer func() {
+		for i := range list {
+			list[i] = nil
+		}
+	}()
+
+	n := len(list)
+	switch n {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	}
+	i := rand.Intn(n)
+	j := rand.Intn(n - 1)
+	if j >= i {
+		j++
+	}
+	a, b := list[i], list[j]
+	if loadOf(b, ps.loadTTL, ps.scoreCallback, now) < loadOf(a, ps.loadTTL, ps.scoreCallback, now) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,67 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package selector
+
+import (
+	"math/rand"
+	"time"
+)
+
+//RandomSelector picks sessions at random in proportion to their weight
+type RandomSelector struct {
+	sessionList
+	buf []*SessionData
+}
+
+func NewRandomSelector() *RandomSelector {
+	return &RandomSelector{
+		sessionList: newSessionList(),
+	}
+}
+
+func (rs *RandomSelector) Set(data *SessionData) {
+	if data == nil {
+		return
+	}
+	rs.mux.Lock()
+	rs.set(data)
+	rs.mux.Unlock()
+}
+
+func (rs *RandomSelector) Del(name string) {
+	rs.mux.Lock()
+	rs.del(name)
+	rs.mux.Unlock()
+}
+
+func (rs *RandomSelector) Select(key string) *SessionData {
+	rs.mux.Lock()
+	defer rs.mux.Unlock()
+
+	rs.buf = rs.available(rs.buf[:0], time.Now())
+	list := rs.buf
+	defer func() {
+		for i := range list {
+			list[i] = nil
+		}
+	}()
+
+	total := 0
+	for _, sd := range list {
+		total += share(sd)
+	}
+	if total == 0 {
+		return nil
+	}
+	n := rand.Intn(total)
+	for _, sd := range list {
+		n -= share(sd)
+		if n < 0 {
+			return sd
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package selector
+
+import (
+	"sync"
+	"time"
+)
+
+//Selector picks a session for a key, e.g. the account of the client session,
+//hash and sticky selection need a key stable across reconnects
+type Selector interface {
+	//Set adds the session or updates the one of its name
+	Set(data *SessionData)
+	Del(name string)
+	Get(name string) *SessionData
+	//Select returns nil when no session is available
+	Select(key string) *SessionData
+}
+
+//Filterable selectors skip the sessions the callback rejects, e.g. those of
+//an open circuit
+type Filterable interface {
+	SetFilterCallback(cb func(*SessionData) bool)
+}
+
+var (
+	_ Filterable = (*WeightSelector)(nil)
+	_ Filterable = (*SmoothSelector)(nil)
+	_ Filterable = (*P2CSelector)(nil)
+	_ Filterable = (*HashSelector)(nil)
+	_ Filterable = (*RandomSelector)(nil)
+	_ Filterable = (*StickySelector)(nil)
+
+	_ Selector = (*WeightSelector)(nil)
+	_ Selector = (*SmoothSelector)(nil)
+	_ Selector = (*P2CSelector)(nil)
+	_ Selector = (*HashSelector)(nil)
+	_ Selector = (*RandomSelector)(nil)
+	_ Selector = (*StickySelector)(nil)
+)
+
+//sessionList keeps the sessions of a selector by name, the owner locks it
+type sessionList struct {
+	mux            sync.Mutex
+	sessions       []*SessionData
+	loadTTL        time.Duration
+	filterCallback func(*SessionData) bool
+}
+
+func newSessionList() sessionList {
+	return sessionList{
+		sessions: make([]*SessionData, 0),
+		loadTTL:  DefaultLoadTTL,
+	}
+}
+
+//SetLoadTTL sets how long load reports are used, 0 keeps them until replaced
+func (sl *sessionList) SetLoadTTL(ttl time.Duration) {
+	sl.mux.Lock()
+	sl.loadTTL = ttl
+	sl.mux.Unlock()
+}
+
+func (sl *sessionList) SetFilterCallback(cb func(*SessionData) bool) {
+	sl.mux.Lock()
+	sl.filterCallback = cb
+	sl.mux.Unlock()
+}
+
+func (sl *sessionList) usable(sd *SessionData, now time.Time) bool {
+	if !isAvailable(sd, sl.loadTTL, now) {
+		return false
+	}
+	return sl.filterCallback == nil || sl.filterCallback(sd)
+}
+
+func (sl *sessionList) GetSessions() []*SessionData {
+	sl.mux.Lock()
+	defer sl.mux.Unlock()
+	return append([]*SessionData(nil), sl.sessions...)
+}
+
+func (sl *sessionList) Get(name string) *SessionData {
+	sl.mux.Lock()
+	defer sl.mux.Unlock()
+	return sl.get(name)
+}
+
+func (sl *sessionList) get(name string) *SessionData {
+	for _, sd := range sl.sessions {
+		if sd.Name == name {
+			return sd
+		}
+	}
+	return nil
+}
+
+//set returns false when the session was updated in place
+func (sl *sessionList) set(data *SessionData) bool {
+	sd := sl.get(data.Name)
+	if sd != nil {
+		sd.Host = data.Host
+		sd.Type = data.Type
+		sd.Weight = data.Weight
+		sd.setLoad(data.Load)
+		return false
+	}
+	data.setLoad(data.Load)
+	sl.sessions = append(sl.sessions, data)
+	return true
+}
+
+func (sl *sessionList) del(name string) bool {
+	for i, sd := range sl.sessions {
+		if sd.Name == name {
+			sl.sessions = append(sl.sessions[:i:i], sl.sessions[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+//available appends the sessions not saturated nor filtered to buf
+func (sl *sessionList) available(buf []*SessionData, now time.Time) []*SessionData {
+	for _, sd := range sl.sessions {
+		if sl.usable(sd, now) {
+			buf = append(buf, sd)
+		}
+	}
+	return buf
+}
+
+//share is the weight as a share, at least 1
+func share(sd *SessionData) int {
+	if sd.Weight < 1 {
+		return 1
+	}
+	return sd.Weight
+}
@@ -8,6 +8,7 @@ package selector
 import (
 	"testing"
 	"fmt"
+	"time"
 )
 
 func TestSelector(t *testing.T) {
@@ -48,3 +49,72 @@ func genSessionData() *WeightSelector {
 
 	return s
 }
+
+func TestSelectorLoad(t *testing.T) {
+	s := NewWeightSelector()
+	s.SetLoadTTL(time.Millisecond * 50)
+	s.Add(&SessionData{Name: "a", Weight: 1, Load: &Load{Sessions: 10, Capacity: 10}})
+	s.Add(&SessionData{Name: "b", Weight: 5, Load: &Load{Sessions: 3}})
+
+	//a is saturated
+	for i := 0; i < 3; i++ {
+		if sd := s.SelectWeightLeast(); sd == nil || sd.Name != "b" {
+			t.Fatal("saturated selected", sd)
+		}
+	}
+	s.UpdateLoad("b", &Load{Sessions: 20, Capacity: 20})
+	if sd := s.SelectRoundWeightLeast(); sd != nil {
+		t.Fatal("all saturated", sd.Name)
+	}
+
+	//stale reports fall back to the weight
+	time.Sleep(time.Millisecond * 100)
+	if sd := s.SelectWeightLeast(); sd == nil || sd.Name != "a" {
+		t.Fatal("stale load used", sd)
+	}
+}
+
+func TestSmoothSelector(t *testing.T) {
+	s := NewSmoothSelector()
+	s.Set(&SessionData{Name: "a", Weight: 5})
+	s.Set(&SessionData{Name: "b", Weight: 1})
+	s.Set(&SessionData{Name: "c", Weight: 1})
+
+	//nginx order for 5,1,1
+	order := ""
+	for i := 0; i < 7; i++ {
+		order += s.Select("").Name
+	}
+	if order != "aabacaa" {
+		t.Fatal(order)
+	}
+}
+
+func TestStickySelector(t *testing.T) {
+	s := NewStickySelector(NewRandomSelector())
+	for i := 1; i <= 5; i++ {
+		s.Set(&SessionData{Name: fmt.Sprintf("conn%v", i), Weight: 1})
+	}
+	first := s.Select("player").Name
+	for i := 0; i < 20; i++ {
+		if sd := s.Select("player"); sd.Name != first {
+			t.Fatal("moved to", sd.Name)
+		}
+	}
+	s.Del(first)
+	if sd := s.Select("player"); sd == nil || sd.Name == first {
+		t.Fatal("kept a deleted session")
+	}
+}
+
+func TestP2CSelector(t *testing.T) {
+	s := NewP2CSelector()
+	s.Set(&SessionData{Name: "a", Weight: 0})
+	s.Set(&SessionData{Name: "b", Weight: 100})
+	s.Set(&SessionData{Name: "c", Load: &Load{Sessions: 1, Capacity: 1}})
+	for i := 0; i < 20; i++ {
+		if sd := s.Select(""); sd.Name != "a" {
+			t.Fatal(sd.Name)
+		}
+	}
+}
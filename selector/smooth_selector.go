@@ -0,0 +1,65 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package selector
+
+import (
+	"time"
+)
+
+//SmoothSelector is the smooth weighted round robin of nginx, sessions are
+//picked in proportion to their weight, spread evenly over the turns
+type SmoothSelector struct {
+	sessionList
+	current map[string]int
+}
+
+func NewSmoothSelector() *SmoothSelector {
+	return &SmoothSelector{
+		sessionList: newSessionList(),
+		current:     make(map[string]int),
+	}
+}
+
+func (ss *SmoothSelector) Set(data *SessionData) {
+	if data == nil {
+		return
+	}
+	ss.mux.Lock()
+	ss.set(data)
+	ss.mux.Unlock()
+}
+
+func (ss *SmoothSelector) Del(name string) {
+	ss.mux.Lock()
+	if ss.del(name) {
+		delete(ss.current, name)
+	}
+	ss.mux.Unlock()
+}
+
+func (ss *SmoothSelector) Select(key string) *SessionData {
+	ss.mux.Lock()
+	defer ss.mux.Unlock()
+
+	now := time.Now()
+	total := 0
+	var best *SessionData
+	for _, sd := range ss.sessions {
+		if !ss.usable(sd, now) {
+			continue
+		}
+		w := share(sd)
+		ss.current[sd.Name] += w
+		total += w
+		if best == nil || ss.current[sd.Name] > ss.current[best.Name] {
+			best = sd
+		}
+	}
+	if best != nil {
+		ss.current[best.Name] -= total
+	}
+	return best
+}
@@ -0,0 +1,100 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package selector
+
+import (
+	"sync"
+	"time"
+)
+
+const DefaultStickyTTL = time.Minute * 10
+
+type binding struct {
+	name string
+	last time.Time
+}
+
+//StickySelector keeps a key on the session first selected for it while the
+//session is there, keys unused for the ttl are forgotten
+type StickySelector struct {
+	Selector
+	mux            sync.Mutex
+	ttl            time.Duration
+	bindings       map[string]*binding
+	lastSweep      time.Time
+	filterCallback func(*SessionData) bool
+}
+
+//NewStickySelector picks the first session of a key with s
+func NewStickySelector(s Selector, ttl ...time.Duration) *StickySelector {
+	if s == nil {
+		return nil
+	}
+	ss := &StickySelector{
+		Selector:  s,
+		ttl:       DefaultStickyTTL,
+		bindings:  make(map[string]*binding),
+		lastSweep: time.Now(),
+	}
+	if len(ttl) > 0 && ttl[0] > 0 {
+		ss.ttl = ttl[0]
+	}
+	return ss
+}
+
+func (ss *StickySelector) Select(key string) *SessionData {
+	ss.mux.Lock()
+	defer ss.mux.Unlock()
+
+	now := time.Now()
+	ss.sweep(now)
+	b, ok := ss.bindings[key]
+	if ok {
+		sd := ss.Get(b.name)
+		if sd != nil && (ss.filterCallback == nil || ss.filterCallback(sd)) {
+			b.last = now
+			return sd
+		}
+	}
+	sd := ss.Selector.Select(key)
+	if sd == nil {
+		delete(ss.bindings, key)
+		return nil
+	}
+	ss.bindings[key] = &binding{name: sd.Name, last: now}
+	return sd
+}
+
+//SetFilterCallback moves the keys of a filtered session, the inner selector
+//gets the callback when it is Filterable
+func (ss *StickySelector) SetFilterCallback(cb func(*SessionData) bool) {
+	ss.mux.Lock()
+	ss.filterCallback = cb
+	ss.mux.Unlock()
+	f, ok := ss.Selector.(Filterable)
+	if ok {
+		f.SetFilterCallback(cb)
+	}
+}
+
+//Forget drops the session of the key, e.g. once the client logged out
+func (ss *StickySelector) Forget(key string) {
+	ss.mux.Lock()
+	delete(ss.bindings, key)
+	ss.mux.Unlock()
+}
+
+func (ss *StickySelector) sweep(now time.Time) {
+	if now.Sub(ss.lastSweep) < ss.ttl {
+		return
+	}
+	ss.lastSweep = now
+	for key, b := range ss.bindings {
+		if now.Sub(b.last) >= ss.ttl {
+			delete(ss.bindings, key)
+		}
+	}
+}
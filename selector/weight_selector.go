@@ -7,6 +7,7 @@ package selector
 
 import (
 	"sync"
+	"time"
 )
 
 type SessionData struct {
@@ -15,17 +16,31 @@ type SessionData struct {
 	Name   string           `json:"name"`
 	Type   int              `json:"type"`
 	Weight int              `json:"weight"`
+	Load   *Load            `json:"load,omitempty"`
+
+	loadTime time.Time
+}
+
+func (sd *SessionData) setLoad(load *Load) {
+	sd.Load = load
+	if load != nil {
+		sd.loadTime = time.Now()
+	}
 }
 
 type WeightSelector struct {
-	mux      sync.RWMutex
-	sessions []*SessionData
-	lastIdx  int
+	mux            sync.RWMutex
+	sessions       []*SessionData
+	lastIdx        int
+	loadTTL        time.Duration
+	scoreCallback  func(*Load) int
+	filterCallback func(*SessionData) bool
 }
 
 func NewWeightSelector() *WeightSelector {
 	return &WeightSelector{
 		sessions: make([]*SessionData, 0),
+		loadTTL:  DefaultLoadTTL,
 	}
 }
 
@@ -49,6 +64,7 @@ func (selector *WeightSelector) IsNew(data *SessionData) (new bool, changed bool
 			if sd.Host == data.Host {
 				sd.Type = data.Type
 				sd.Weight = data.Weight
+				sd.setLoad(data.Load)
 				return false, false
 			} else {
 				selector.Del(sd.Name)
@@ -65,6 +81,7 @@ func (selector *WeightSelector) Add(data *SessionData) {
 		return
 	}
 	selector.mux.Lock()
+	data.setLoad(data.Load)
 	selector.sessions = append(selector.sessions, data)
 	selector.mux.Unlock()
 	return
@@ -86,6 +103,7 @@ func (selector *WeightSelector) AddCheck(data *SessionData) (new bool, changed b
 			if sd.Host == data.Host {
 				sd.Type = data.Type
 				sd.Weight = data.Weight
+				sd.setLoad(data.Load)
 				return false, false
 			} else {
 				selector.Del(sd.Name)
@@ -94,6 +112,7 @@ func (selector *WeightSelector) AddCheck(data *SessionData) (new bool, changed b
 		}
 	}
 
+	data.setLoad(data.Load)
 	selector.sessions = append(selector.sessions, data)
 	return true, false
 }
@@ -187,6 +206,7 @@ func (selector *WeightSelector) SubWeight(key string, weight int) {
 	selector.mux.Unlock()
 }
 
+//SelectWeightLeast skips saturated sessions, nil when every one is
 func (selector *WeightSelector) SelectWeightLeast() *SessionData {
 	selector.mux.Lock()
 	defer selector.mux.Unlock()
@@ -195,23 +215,22 @@ func (selector *WeightSelector) SelectWeightLeast() *SessionData {
 	sessionCount := len(sessionList)
 	if sessionCount == 0 {
 		return nil
-	} else if sessionCount == 1 {
-		return sessionList[0]
 	}
 
+	now := time.Now()
 	start := (selector.lastIdx + 1) % sessionCount
-	minLoadIdx := start
-	minLoad := sessionList[start].Weight
+	minLoadIdx := -1
+	minLoad := 0
 
 	for i := 0; i < sessionCount; i++ {
 		idx := (start + i) % sessionCount
 		s := sessionList[idx]
-		if s == nil {
+		if !selector.available(s, now) {
 			continue
 		}
 
-		load := sessionList[idx].Weight
-		if load < minLoad {
+		load := selector.weight(s, now)
+		if minLoadIdx == -1 || load < minLoad {
 			minLoad = load
 			minLoadIdx = idx
 			if minLoad == 0 {
@@ -219,12 +238,16 @@ func (selector *WeightSelector) SelectWeightLeast() *SessionData {
 			}
 		}
 	}
+	if minLoadIdx == -1 {
+		return nil
+	}
 
 	selector.lastIdx = minLoadIdx
 	session := sessionList[minLoadIdx]
 	return session
 }
 
+//SelectRoundWeightLeast starts after the last pick, so equal weights take turns
 func (selector *WeightSelector) SelectRoundWeightLeast() *SessionData {
 	selector.mux.Lock()
 	defer selector.mux.Unlock()
@@ -233,32 +256,64 @@ func (selector *WeightSelector) SelectRoundWeightLeast() *SessionData {
 	sessionCount := len(sessionList)
 	if sessionCount == 0 {
 		return nil
-	} else if sessionCount == 1 {
-		return sessionList[0]
 	}
 
+	now := time.Now()
 	start := (selector.lastIdx + 1) % sessionCount
-	minLoadIdx := start
-
-	minLoad := sessionList[start].Weight
+	minLoadIdx := -1
+	minLoad := 0
+	if selector.available(sessionList[start], now) {
+		minLoadIdx = start
+		minLoad = selector.weight(sessionList[start], now)
+	}
 
-	for i := 0; i < sessionCount; i++ {
-		s := sessionList[i]
-		if s == nil {
+	for i := 1; i < sessionCount; i++ {
+		idx := (start + i) % sessionCount
+		s := sessionList[idx]
+		if !selector.available(s, now) {
 			continue
 		}
 
-		if s.Weight < minLoad {
-			minLoad = s.Weight
-			minLoadIdx = i
+		load := selector.weight(s, now)
+		if minLoadIdx == -1 || load < minLoad {
+			minLoad = load
+			minLoadIdx = idx
 			if minLoad == 0 {
 				break
 			}
 		}
 	}
+	if minLoadIdx == -1 {
+		return nil
+	}
 
 	selector.lastIdx = minLoadIdx
 	session := sessionList[minLoadIdx]
 	return session
 }
 
+func (selector *WeightSelector) Get(name string) *SessionData {
+	selector.mux.RLock()
+	defer selector.mux.RUnlock()
+	for _, sd := range selector.sessions {
+		if sd != nil && sd.Name == name {
+			return sd
+		}
+	}
+	return nil
+}
+
+//Select picks the session of the least weight in turn, the key is unused
+func (selector *WeightSelector) Select(key string) *SessionData {
+	return selector.SelectRoundWeightLeast()
+}
+
+func (selector *WeightSelector) SetFilterCallback(cb func(*SessionData) bool) {
+	selector.mux.Lock()
+	selector.filterCallback = cb
+	selector.mux.Unlock()
+}
+
+func (selector *WeightSelector) pass(sd *SessionData) bool {
+	return selector.filterCallback == nil || selector.filterCallback(sd)
+}
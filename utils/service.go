@@ -24,6 +24,9 @@ import (
 var theServiceFactory *ServiceFactory
 var theServiceOnce sync.Once
 
+//requests of this type are also dispatched by the type of a decoded message
+var protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
 func GetMsgFactory() *ServiceFactory {
 	theServiceOnce.Do(func() {
 		theServiceFactory = NewServiceFactory()
@@ -31,16 +34,65 @@ func GetMsgFactory() *ServiceFactory {
 	return theServiceFactory
 }
 
+//ServiceCall is one method invocation, middlewares may replace Req and fill Reply
+type ServiceCall struct {
+	Session defs.ISession
+	Packet  defs.IPacket
+	Method  string
+	Req     interface{}
+	Reply   interface{}
+	method  *MethodDesc
+}
+
+//MethodDesc is a service method, either found by Register or emitted by
+//lightning-gen for RegisterMethods, NewReply is nil for methods without reply.
+//Release is optional and gets back the objects of NewReq and NewReply once
+//the reply is serialized. ReqType is optional too, the type NewReq returns,
+//without it NewReq is called once at registration to find it
+type MethodDesc struct {
+	Name     string
+	NewReq   func() interface{}
+	NewReply func() interface{}
+	Invoke   func(session defs.ISession, req, reply interface{}) int
+	Release  func(req, reply interface{})
+	ReqType  reflect.Type
+}
+
+//Handler invokes a service method and returns the status written back with the reply
+type Handler func(call *ServiceCall) int
+
+type Middleware func(next Handler) Handler
+
 type ServiceFactory struct {
 	msgRCVR                 reflect.Value
 	msgHandle               sync.Map
+	msgTypeHandle           sync.Map
+	middlewares             []Middleware
+	handler                 Handler
 	ParseMethodNameCallback defs.ParseMethodNameCallback
 	ParseDataCallback       defs.ParseDataCallback
 	serializeDataCallback   defs.SerializeDataCallback
 }
 
 func NewServiceFactory() *ServiceFactory {
-	return &ServiceFactory{}
+	sf := &ServiceFactory{}
+	sf.handler = sf.invoke
+	return sf
+}
+
+//Use wraps every method invocation, the first middleware added runs outermost.
+//the chain is not guarded, so add them before serving
+func (sf *ServiceFactory) Use(mw ...Middleware) {
+	sf.middlewares = append(sf.middlewares, mw...)
+	handler := sf.invoke
+	for i := len(sf.middlewares) - 1; i >= 0; i-- {
+		handler = sf.middlewares[i](handler)
+	}
+	sf.handler = handler
+}
+
+func (sf *ServiceFactory) invoke(call *ServiceCall) int {
+	return call.method.Invoke(call.Session, call.Req, call.Reply)
 }
 
 func (sf *ServiceFactory) SetParseMethodNameCallback(cb defs.ParseMethodNameCallback) {
@@ -55,14 +107,52 @@ func (sf *ServiceFactory) SetSerializeDataCallback(cb defs.SerializeDataCallback
 	sf.serializeDataCallback = cb
 }
 
-func (sf *ServiceFactory) get(key interface{}) *defs.MethodType {
+func (sf *ServiceFactory) get(key interface{}) *MethodDesc {
 	cb, ok := sf.msgHandle.Load(key)
 	if ok {
-		return cb.(*defs.MethodType)
+		return cb.(*MethodDesc)
 	}
 	return nil
 }
 
+func (sf *ServiceFactory) getByType(typ reflect.Type) *MethodDesc {
+	cb, ok := sf.msgTypeHandle.Load(typ)
+	if ok {
+		return cb.(*MethodDesc)
+	}
+	return nil
+}
+
+//RegisterMethods adds statically typed methods, dispatched without reflection
+func (sf *ServiceFactory) RegisterMethods(descs ...*MethodDesc) {
+	for _, desc := range descs {
+		if desc == nil || desc.NewReq == nil || desc.Invoke == nil {
+			continue
+		}
+		sf.addMethod(&sf.msgHandle, desc)
+	}
+}
+
+func (sf *ServiceFactory) addMethod(serviceMap *sync.Map, desc *MethodDesc) {
+	serviceMap.Store(desc.Name, desc)
+
+	typ := desc.ReqType
+	if typ == nil {
+		req := desc.NewReq()
+		typ = reflect.TypeOf(req)
+		//the probe goes back to a pooled NewReq
+		if desc.Release != nil {
+			desc.Release(req, nil)
+		}
+	}
+	if typ != nil && typ.Implements(protoMessageType) {
+		_, loaded := sf.msgTypeHandle.LoadOrStore(typ, desc)
+		if loaded {
+			logger.Debugf("function: %v, message %v already handled", desc.Name, typ)
+		}
+	}
+}
+
 func (sf *ServiceFactory) Register(rcvr interface{}, cb ...defs.ParseMethodNameCallback) {
 	if len(cb) > 0 {
 		sf.ParseMethodNameCallback = cb[0]
@@ -88,29 +178,44 @@ func (sf *ServiceFactory) OnServiceHandle(session defs.ISession, packet defs.IPa
 		return false
 	}
 
+	//decoded messages are dispatched by type
+	var msg interface{}
+	msgPacket, isMsgPacket := packet.(defs.IMsgPacket)
+	if isMsgPacket {
+		msg = msgPacket.GetMsg()
+	}
+
 	key := packet.GetId()
-	typ := sf.get(key)
-	if typ == nil {
+	var desc *MethodDesc
+	if msg != nil {
+		desc = sf.getByType(reflect.TypeOf(msg))
+	} else {
+		desc = sf.get(key)
+	}
+	if desc == nil {
 		logger.Trace("callback for service is nil ", logger.Fields{"type": key})
 		return false
 	}
 
-	//
-	if typ.ArgType.Kind() != reflect.Ptr {
-		logger.Error("req type error")
-		return false
+	var req, reply interface{}
+	//decoded messages and their replies are owned by the codec
+	if msg == nil && desc.Release != nil {
+		defer func() {
+			desc.Release(req, reply)
+		}()
 	}
-	req := reflect.New(typ.ArgType.Elem())
 
 	data := packet.GetData()
-	if data != nil && len(data) > 0 {
+	if msg != nil {
+		req = msg
+	} else if req = desc.NewReq(); data != nil && len(data) > 0 {
 		if sf.ParseDataCallback == nil {
-			if !ParseDataByJson(data, req.Interface()) {
+			if !ParseDataByJson(data, req) {
 				logger.Trace("parse request data failed")
 				return false
 			}
 		} else {
-			if !sf.ParseDataCallback(data, req.Interface()) {
+			if !sf.ParseDataCallback(data, req) {
 				logger.Trace("parse request data failed")
 				return false
 			}
@@ -119,44 +224,70 @@ func (sf *ServiceFactory) OnServiceHandle(session defs.ISession, packet defs.IPa
 
 	session.SetPacket(packet)
 	defer session.SetPacket(nil)
-	function := typ.Method.Func
 
-	if typ.ReplyType == nil {
-		function.Call([]reflect.Value{sf.msgRCVR, reflect.ValueOf(session), req})
+	call := &ServiceCall{
+		Session: session,
+		Packet:  packet,
+		Method:  desc.Name,
+		Req:     req,
+		method:  desc,
+	}
+	if desc.NewReply != nil {
+		reply = desc.NewReply()
+		call.Reply = reply
+	}
+	handler := sf.handler
+	if handler == nil {
+		handler = sf.invoke
+	}
+	errno := handler(call)
+
+	if desc.NewReply == nil {
 		return true
 	}
 
-	ack := reflect.New(typ.ReplyType.Elem())
-	result := function.Call([]reflect.Value{sf.msgRCVR, reflect.ValueOf(session), req, ack})
-	if result != nil && len(result) > 0 {
-		iErrno := result[0].Interface()
-		switch iErrno.(type) {
-		case int:
-			errno, ok := iErrno.(int)
-			if ok {
-				var data []byte
-				d := ack.Interface()
-				d = d
-				if sf.serializeDataCallback == nil {
-					data = SerializeDataByJson(ack.Interface())
-				} else {
-					data = sf.serializeDataCallback(ack.Interface())
-				}
-				p := &defs.Packet{}
-				p.SetSessionId(packet.GetSessionId())
-				p.SetId(packet.GetId())
-				p.SetStatus(errno)
-				p.SetData(data)
-				p.SetSequence(packet.GetSequence())
-				session.WritePacket(p)
-			}
-		default:
-			logger.Warn("unexpected type")
+	if msg != nil {
+		p := &defs.MsgPacket{}
+		p.SetMsg(call.Reply)
+		p.SetSessionId(packet.GetSessionId())
+		p.SetStatus(errno)
+		p.SetSequence(packet.GetSequence())
+		session.WritePacket(p)
+	} else {
+		var data []byte
+		if sf.serializeDataCallback == nil {
+			data = SerializeDataByJson(call.Reply)
+		} else {
+			data = sf.serializeDataCallback(call.Reply)
 		}
+		p := &defs.Packet{}
+		p.SetSessionId(packet.GetSessionId())
+		p.SetId(packet.GetId())
+		p.SetStatus(errno)
+		p.SetData(data)
+		p.SetSequence(packet.GetSequence())
+		session.WritePacket(p)
 	}
 	return true
 }
 
+//RecoverMiddleware replies errno instead of dropping the request when a method panics
+func RecoverMiddleware(errno int) Middleware {
+	return func(next Handler) Handler {
+		return func(call *ServiceCall) (status int) {
+			defer func() {
+				if err := recover(); err != nil {
+					logger.Errorf("%v: %v", call.Method, err)
+					trackBack := string(debug.Stack())
+					logger.Errorf("%v", trackBack)
+					status = errno
+				}
+			}()
+			return next(call)
+		}
+	}
+}
+
 func (sf *ServiceFactory) IsExported(name string) bool {
 	runeName, _ := utf8.DecodeRuneInString(name)
 	return unicode.IsUpper(runeName)
@@ -220,22 +351,52 @@ func (sf *ServiceFactory) suitableMethods(rcvr interface{}, rcvr2 *reflect.Value
 			continue
 		}
 
-		serviceMap.Store(
-			nameVal,
-			&defs.MethodType{
-				Method:    method,
-				ArgType:   argType,
-				ReplyType: replyType,
-			})
+		if argType.Kind() != reflect.Ptr {
+			logger.Debug("method arg type not a pointer")
+			continue
+		}
+
+		desc := newMethodDesc(*rcvr2, method, argType, replyType)
+		desc.Name = nameVal
+		sf.addMethod(serviceMap, desc)
 	}
 
 	return nil
 }
 
+//each method keeps its own receiver, so several services can be registered
+func newMethodDesc(rcvr reflect.Value, method reflect.Method, argType, replyType reflect.Type) *MethodDesc {
+	function := method.Func
+	desc := &MethodDesc{
+		Name:    method.Name,
+		ReqType: argType,
+		NewReq: func() interface{} {
+			return reflect.New(argType.Elem()).Interface()
+		},
+		Invoke: func(session defs.ISession, req, reply interface{}) int {
+			args := []reflect.Value{rcvr, reflect.ValueOf(session), reflect.ValueOf(req)}
+			if replyType != nil {
+				args = append(args, reflect.ValueOf(reply))
+			}
+			return int(function.Call(args)[0].Int())
+		},
+	}
+	if replyType != nil {
+		desc.NewReply = func() interface{} {
+			return reflect.New(replyType.Elem()).Interface()
+		}
+	}
+	return desc
+}
+
 func RegisterService(rcvr interface{}) {
 	GetMsgFactory().Register(rcvr)
 }
 
+func UseService(mw ...Middleware) {
+	GetMsgFactory().Use(mw...)
+}
+
 func OnServiceHandle(session defs.ISession, packet defs.IPacket) bool {
 	return GetMsgFactory().OnServiceHandle(session, packet)
 }
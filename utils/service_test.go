@@ -0,0 +1,224 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lightning-go/lightning/defs"
+)
+
+type BenchReq struct {
+	Account string
+	Id      int
+}
+
+type BenchResp struct {
+	Token string
+	Code  int
+}
+
+type benchService struct{}
+
+func (bs *benchService) Login(session defs.ISession, req *BenchReq, resp *BenchResp) int {
+	resp.Code = req.Id
+	return 0
+}
+
+//only the methods used by OnServiceHandle are implemented
+type benchSession struct {
+	defs.ISession
+	packet  defs.IPacket
+	written int
+	last    defs.IPacket
+}
+
+func (s *benchSession) SetPacket(packet defs.IPacket) {
+	s.packet = packet
+}
+
+func (s *benchSession) WritePacket(packet defs.IPacket) {
+	s.written++
+	s.last = packet
+}
+
+func newBenchFactory(typed bool) *ServiceFactory {
+	sf := NewServiceFactory()
+	//dispatch only, without serialization
+	sf.SetParseDataCallback(func(data []byte, v interface{}) bool {
+		return true
+	})
+	sf.SetSerializeDataCallback(func(v interface{}, param ...interface{}) []byte {
+		return nil
+	})
+	if typed {
+		Handle(sf, "Login", (&benchService{}).Login)
+	} else {
+		sf.Register(&benchService{})
+	}
+	return sf
+}
+
+func benchmarkDispatch(b *testing.B, typed bool) {
+	sf := newBenchFactory(typed)
+	session := &benchSession{}
+	packet := &defs.Packet{}
+	packet.SetId("Login")
+	packet.SetData([]byte("{}"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sf.OnServiceHandle(session, packet)
+	}
+	if session.written != b.N {
+		b.Fatalf("written %v, want %v", session.written, b.N)
+	}
+}
+
+func BenchmarkDispatchReflect(b *testing.B) {
+	benchmarkDispatch(b, false)
+}
+
+func BenchmarkDispatchTyped(b *testing.B) {
+	benchmarkDispatch(b, true)
+}
+
+func TestHandleReleasesPooled(t *testing.T) {
+	sf := newBenchFactory(true)
+	var last *BenchReq
+	Handle(sf, "Check", func(session defs.ISession, req *BenchReq, resp *BenchResp) int {
+		if req.Id != 0 || resp.Code != 0 {
+			t.Errorf("pooled objects not reset: %v %v", req, resp)
+		}
+		req.Id = 1
+		resp.Code = 1
+		last = req
+		return 0
+	})
+
+	session := &benchSession{}
+	packet := &defs.Packet{}
+	packet.SetId("Check")
+	for i := 0; i < 3; i++ {
+		if !sf.OnServiceHandle(session, packet) {
+			t.Fatal("dispatch failed")
+		}
+	}
+	if last == nil || session.written != 3 {
+		t.Fatalf("written %v", session.written)
+	}
+}
+
+//registration does not take objects from a pooled NewReq for good
+func TestRegisterReturnsProbe(t *testing.T) {
+	sf := NewServiceFactory()
+	gets, puts := 0, 0
+	sf.RegisterMethods(&MethodDesc{
+		Name: "Probe",
+		NewReq: func() interface{} {
+			gets++
+			return &BenchReq{}
+		},
+		Invoke: func(session defs.ISession, req, reply interface{}) int {
+			return 0
+		},
+		Release: func(req, reply interface{}) {
+			puts++
+		},
+	})
+	if gets != puts {
+		t.Fatalf("%v requests taken, %v put back", gets, puts)
+	}
+
+	Handle(sf, "Typed", func(session defs.ISession, req *BenchReq, resp *BenchResp) int {
+		return 0
+	})
+	if desc := sf.get("Typed"); desc == nil || desc.ReqType != reflect.TypeOf(&BenchReq{}) {
+		t.Fatal("typed method registered without its request type")
+	}
+}
+
+func serviceCall(t *testing.T, sf *ServiceFactory, method string) *benchSession {
+	session := &benchSession{}
+	packet := &defs.Packet{}
+	packet.SetId(method)
+	if !sf.OnServiceHandle(session, packet) {
+		t.Fatal("dispatch failed")
+	}
+	return session
+}
+
+//the first middleware added runs outermost
+func TestMiddlewareOrder(t *testing.T) {
+	sf := newBenchFactory(true)
+	var trace []string
+	mark := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(call *ServiceCall) int {
+				trace = append(trace, name+">")
+				status := next(call)
+				trace = append(trace, "<"+name)
+				return status
+			}
+		}
+	}
+	sf.Use(mark("a"))
+	sf.Use(mark("b"), mark("c"))
+	Handle(sf, "Trace", func(session defs.ISession, req *BenchReq, resp *BenchResp) int {
+		trace = append(trace, "call")
+		return 0
+	})
+
+	serviceCall(t, sf, "Trace")
+	if got := strings.Join(trace, " "); got != "a> b> c> call <c <b <a" {
+		t.Fatalf("called in order %v", got)
+	}
+}
+
+//a middleware not calling next answers in place of the method
+func TestMiddlewareShortCircuit(t *testing.T) {
+	sf := newBenchFactory(true)
+	called := false
+	sf.Use(func(next Handler) Handler {
+		return func(call *ServiceCall) int {
+			if call.Method == "Denied" {
+				return 403
+			}
+			return next(call)
+		}
+	})
+	Handle(sf, "Denied", func(session defs.ISession, req *BenchReq, resp *BenchResp) int {
+		called = true
+		return 0
+	})
+
+	session := serviceCall(t, sf, "Denied")
+	if called {
+		t.Fatal("method called past the middleware")
+	}
+	if session.written != 1 || session.last.GetStatus() != 403 {
+		t.Fatalf("written %v", session.written)
+	}
+	if session = serviceCall(t, sf, "Login"); session.last.GetStatus() != 0 {
+		t.Fatalf("status %v of an allowed method", session.last.GetStatus())
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	sf := newBenchFactory(true)
+	sf.Use(RecoverMiddleware(500))
+	Handle(sf, "Panic", func(session defs.ISession, req *BenchReq, resp *BenchResp) int {
+		panic("boom")
+	})
+
+	session := serviceCall(t, sf, "Panic")
+	if session.written != 1 || session.last.GetStatus() != 500 {
+		t.Fatalf("written %v after a panic", session.written)
+	}
+}
@@ -0,0 +1,81 @@
+/**
+ * Created: 2026/10/18
+ * @author: Jason
+ */
+
+package utils
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/lightning-go/lightning/defs"
+)
+
+//Handle registers fn as the method name, called without reflection.
+//the request and reply are pooled and reset once the reply is serialized,
+//so neither fn nor the middlewares may keep them after returning
+func Handle[Req any, Resp any](sf *ServiceFactory, name string, fn func(defs.ISession, *Req, *Resp) int) {
+	reqPool := newTypedPool[Req]()
+	replyPool := newTypedPool[Resp]()
+	sf.RegisterMethods(&MethodDesc{
+		Name:     name,
+		NewReq:   reqPool.get,
+		NewReply: replyPool.get,
+		ReqType:  reflect.TypeOf((*Req)(nil)),
+		Invoke: func(session defs.ISession, req, reply interface{}) int {
+			return fn(session, req.(*Req), reply.(*Resp))
+		},
+		Release: func(req, reply interface{}) {
+			reqPool.put(req)
+			replyPool.put(reply)
+		},
+	})
+}
+
+//HandleNotify registers fn as the method name without reply, see Handle
+func HandleNotify[Req any](sf *ServiceFactory, name string, fn func(defs.ISession, *Req) int) {
+	reqPool := newTypedPool[Req]()
+	sf.RegisterMethods(&MethodDesc{
+		Name:    name,
+		NewReq:  reqPool.get,
+		ReqType: reflect.TypeOf((*Req)(nil)),
+		Invoke: func(session defs.ISession, req, reply interface{}) int {
+			return fn(session, req.(*Req))
+		},
+		Release: func(req, reply interface{}) {
+			reqPool.put(req)
+		},
+	})
+}
+
+type typedPool[T any] struct {
+	pool sync.Pool
+}
+
+func newTypedPool[T any]() *typedPool[T] {
+	p := &typedPool[T]{}
+	p.pool.New = func() interface{} {
+		return new(T)
+	}
+	return p
+}
+
+func (p *typedPool[T]) get() interface{} {
+	return p.pool.Get()
+}
+
+func (p *typedPool[T]) put(v interface{}) {
+	obj, ok := v.(*T)
+	if !ok || obj == nil {
+		return
+	}
+	//generated protobuf messages reset their internal state as well
+	if r, ok := v.(interface{ Reset() }); ok {
+		r.Reset()
+	} else {
+		var zero T
+		*obj = zero
+	}
+	p.pool.Put(obj)
+}